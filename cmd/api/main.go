@@ -6,6 +6,7 @@ import (
 	"github.com/acidsoft/gorestteach/internal/config"
 	"github.com/acidsoft/gorestteach/internal/database"
 	"github.com/acidsoft/gorestteach/internal/server"
+	"github.com/acidsoft/gorestteach/internal/storage"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -30,8 +31,15 @@ func main() {
 	}
 	log.Info().Msg("Database connected and migrated")
 
+	// ─── Image storage ────────────────────────────────────────────────────────
+	blobs, err := storage.Open(&cfg.Storage, db)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to open image storage")
+	}
+	log.Info().Str("backend", cfg.Storage.Backend).Msg("Image storage ready")
+
 	// ─── Server ───────────────────────────────────────────────────────────────
-	srv := server.New(cfg, db)
+	srv := server.New(cfg, db, blobs)
 	if err := srv.Start(); err != nil {
 		log.Fatal().Err(err).Msg("server stopped with error")
 	}
@@ -0,0 +1,72 @@
+// Command imagegc deletes the images that no avatar or post references any
+// more, the same way the API's background collector does:
+//
+//	go run ./cmd/imagegc -dry-run
+//	go run ./cmd/imagegc -grace 1h
+//
+// Only images unreferenced for longer than the grace period are deleted, so
+// an image uploaded moments ago and not yet attached is left alone. Their
+// bytes are removed from storage later by the API's blob sweeper. The command
+// can be interrupted and re-run at any time.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/database"
+	"github.com/acidsoft/gorestteach/internal/jobs"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/internal/storage"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "15:04:05"})
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load configuration")
+	}
+
+	dryRun := flag.Bool("dry-run", false, "only count the images that would be deleted")
+	grace := flag.Duration("grace", cfg.ImageGC.GracePeriod, "how long an image must have been unreferenced")
+	flag.Parse()
+	if *grace <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg.ImageGC.GracePeriod = *grace
+
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to database")
+	}
+	blobs, err := storage.Open(&cfg.Storage, db)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to open image storage")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	collector := jobs.NewImageCollector(repository.NewImageRepository(db, blobs), &cfg.ImageGC)
+	total, err := collector.CollectOnce(ctx, *dryRun)
+	if ctx.Err() != nil {
+		log.Warn().Int64("images", total.Images).Msg("interrupted; re-run to continue")
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Int64("images", total.Images).Msg("failed to collect images")
+	}
+	log.Info().
+		Bool("dry_run", *dryRun).
+		Int64("images", total.Images).
+		Int64("bytes", total.Bytes).
+		Msg("Collection complete")
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -10,15 +11,24 @@ import (
 
 // Config holds all application configuration loaded from environment variables.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Upload   UploadConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Upload     UploadConfig
+	Trash      TrashConfig
+	Moderation ModerationConfig
+	Storage    StorageConfig
+	ImageGC    ImageGCConfig
+	ImageURL   ImageURLConfig
 }
 
+// ServerConfig configures the HTTP listeners. DebugAddr is where internal
+// endpoints such as /debug/vars are served, apart from the API; empty
+// disables them.
 type ServerConfig struct {
-	Port int
-	Mode string
+	Port      int
+	Mode      string
+	DebugAddr string
 }
 
 type DatabaseConfig struct {
@@ -35,10 +45,83 @@ type JWTConfig struct {
 	RefreshSecret          string
 	AccessExpiresDuration  time.Duration
 	RefreshExpiresDuration time.Duration
+	// StatusCacheDuration is how long an account's status is cached when
+	// checking access tokens, i.e. how long a suspension or ban made on
+	// another instance can take to lock out tokens already issued.
+	StatusCacheDuration time.Duration
 }
 
+// UploadConfig limits uploaded files. QuotaMB caps the images each user
+// may have stored in total; 0 means no cap. Resumable uploads that are not
+// complete ResumableExpiry after they were started are deleted.
 type UploadConfig struct {
-	MaxSizeMB int64
+	MaxSizeMB       int64
+	QuotaMB         int64
+	ResumableExpiry time.Duration
+}
+
+// TrashConfig controls how long soft-deleted posts stay restorable and how
+// often the background purger looks for expired ones.
+type TrashConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// ModerationConfig controls automatic moderation. Content is hidden pending
+// review once AutoHideThreshold distinct users have reported it; 0 disables
+// auto-hiding.
+type ModerationConfig struct {
+	AutoHideThreshold int
+}
+
+// StorageConfig selects where image bytes are kept. Backend receives new
+// uploads; every other configured backend stays readable, so images can be
+// moved between backends while the API keeps serving them. Postgres is always
+// available, the filesystem when FSRoot is set, S3 when a bucket is set.
+type StorageConfig struct {
+	Backend       string // postgres | filesystem | s3
+	FSRoot        string
+	S3            S3Config
+	SweepInterval time.Duration // how often blobs of deleted images are removed
+}
+
+// ImageGCConfig controls the collection of images nothing references any
+// more, such as replaced avatars. GracePeriod leaves time for a fresh upload
+// to be attached to a profile or post before it counts as unreferenced.
+type ImageGCConfig struct {
+	GracePeriod time.Duration
+	Interval    time.Duration
+}
+
+// ImageURLConfig controls the signed URLs the API hands out for images.
+// The first of Keys signs them; the others only verify, so that URLs signed
+// before a key rotation keep working until they expire. A signed URL is
+// valid for between TTL/2 and TTL. With PublicAvatars, avatars get plain
+// URLs instead and are served to anyone.
+type ImageURLConfig struct {
+	Keys          []SigningKey
+	TTL           time.Duration
+	PublicAvatars bool
+}
+
+// SigningKey is a secret for signing image URLs. ID is sent along with the
+// signature to tell which key made it.
+type SigningKey struct {
+	ID     string
+	Secret []byte
+}
+
+// S3Config points at an S3-compatible object store (AWS S3, MinIO, ...).
+// PathStyle addresses the bucket as endpoint/bucket rather than as a
+// subdomain, which most self-hosted stores need.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string
+	PathStyle bool
 }
 
 // Load reads configuration from environment variables (and optionally from .env file via viper).
@@ -55,18 +138,39 @@ func Load() (*Config, error) {
 	// Render.com injects PORT; fall back to SERVER_PORT for local dev
 	viper.SetDefault("SERVER_PORT", 8080)
 	viper.SetDefault("SERVER_MODE", "debug")
+	viper.SetDefault("DEBUG_ADDR", "127.0.0.1:6060")
 	if port := viper.GetInt("PORT"); port != 0 && !viper.IsSet("SERVER_PORT") {
 		viper.Set("SERVER_PORT", port)
 	}
 	viper.SetDefault("DB_SSLMODE", "disable")
 	viper.SetDefault("JWT_ACCESS_EXPIRES_MINUTES", 15)
 	viper.SetDefault("JWT_REFRESH_EXPIRES_DAYS", 7)
+	viper.SetDefault("JWT_STATUS_CACHE_SECONDS", 30)
 	viper.SetDefault("MAX_UPLOAD_SIZE_MB", 5)
+	viper.SetDefault("UPLOAD_QUOTA_MB", 500)
+	viper.SetDefault("UPLOAD_EXPIRY_HOURS", 24)
+	viper.SetDefault("TRASH_RETENTION_DAYS", 30)
+	viper.SetDefault("TRASH_PURGE_INTERVAL_MINUTES", 60)
+	viper.SetDefault("MODERATION_AUTO_HIDE_THRESHOLD", 3)
+	viper.SetDefault("STORAGE_BACKEND", "postgres")
+	viper.SetDefault("STORAGE_S3_REGION", "us-east-1")
+	viper.SetDefault("STORAGE_S3_PATH_STYLE", true)
+	viper.SetDefault("STORAGE_SWEEP_INTERVAL_MINUTES", 10)
+	viper.SetDefault("IMAGE_GC_GRACE_HOURS", 24)
+	viper.SetDefault("IMAGE_GC_INTERVAL_MINUTES", 60)
+	viper.SetDefault("IMAGE_URL_TTL_MINUTES", 60)
+	viper.SetDefault("IMAGE_URL_PUBLIC_AVATARS", true)
+
+	signingKeys, err := parseSigningKeys(viper.GetString("IMAGE_URL_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
 
 	cfg := &Config{
 		Server: ServerConfig{
-			Port: viper.GetInt("SERVER_PORT"),
-			Mode: viper.GetString("SERVER_MODE"),
+			Port:      viper.GetInt("SERVER_PORT"),
+			Mode:      viper.GetString("SERVER_MODE"),
+			DebugAddr: viper.GetString("DEBUG_ADDR"),
 		},
 		Database: DatabaseConfig{
 			Host:     viper.GetString("DB_HOST"),
@@ -81,9 +185,42 @@ func Load() (*Config, error) {
 			RefreshSecret:          viper.GetString("JWT_REFRESH_SECRET"),
 			AccessExpiresDuration:  time.Duration(viper.GetInt("JWT_ACCESS_EXPIRES_MINUTES")) * time.Minute,
 			RefreshExpiresDuration: time.Duration(viper.GetInt("JWT_REFRESH_EXPIRES_DAYS")) * 24 * time.Hour,
+			StatusCacheDuration:    time.Duration(viper.GetInt("JWT_STATUS_CACHE_SECONDS")) * time.Second,
 		},
 		Upload: UploadConfig{
-			MaxSizeMB: viper.GetInt64("MAX_UPLOAD_SIZE_MB"),
+			MaxSizeMB:       viper.GetInt64("MAX_UPLOAD_SIZE_MB"),
+			QuotaMB:         viper.GetInt64("UPLOAD_QUOTA_MB"),
+			ResumableExpiry: time.Duration(viper.GetInt("UPLOAD_EXPIRY_HOURS")) * time.Hour,
+		},
+		Trash: TrashConfig{
+			Retention:     time.Duration(viper.GetInt("TRASH_RETENTION_DAYS")) * 24 * time.Hour,
+			PurgeInterval: time.Duration(viper.GetInt("TRASH_PURGE_INTERVAL_MINUTES")) * time.Minute,
+		},
+		Moderation: ModerationConfig{
+			AutoHideThreshold: viper.GetInt("MODERATION_AUTO_HIDE_THRESHOLD"),
+		},
+		Storage: StorageConfig{
+			Backend: viper.GetString("STORAGE_BACKEND"),
+			FSRoot:  viper.GetString("STORAGE_FS_ROOT"),
+			S3: S3Config{
+				Endpoint:  viper.GetString("STORAGE_S3_ENDPOINT"),
+				Region:    viper.GetString("STORAGE_S3_REGION"),
+				Bucket:    viper.GetString("STORAGE_S3_BUCKET"),
+				AccessKey: viper.GetString("STORAGE_S3_ACCESS_KEY"),
+				SecretKey: viper.GetString("STORAGE_S3_SECRET_KEY"),
+				Prefix:    viper.GetString("STORAGE_S3_PREFIX"),
+				PathStyle: viper.GetBool("STORAGE_S3_PATH_STYLE"),
+			},
+			SweepInterval: time.Duration(viper.GetInt("STORAGE_SWEEP_INTERVAL_MINUTES")) * time.Minute,
+		},
+		ImageGC: ImageGCConfig{
+			GracePeriod: time.Duration(viper.GetInt("IMAGE_GC_GRACE_HOURS")) * time.Hour,
+			Interval:    time.Duration(viper.GetInt("IMAGE_GC_INTERVAL_MINUTES")) * time.Minute,
+		},
+		ImageURL: ImageURLConfig{
+			Keys:          signingKeys,
+			TTL:           time.Duration(viper.GetInt("IMAGE_URL_TTL_MINUTES")) * time.Minute,
+			PublicAvatars: viper.GetBool("IMAGE_URL_PUBLIC_AVATARS"),
 		},
 	}
 
@@ -104,9 +241,80 @@ func (c *Config) validate() error {
 	if c.JWT.RefreshSecret == "" {
 		return fmt.Errorf("JWT_REFRESH_SECRET is required")
 	}
+	if c.JWT.StatusCacheDuration < 0 {
+		return fmt.Errorf("JWT_STATUS_CACHE_SECONDS must not be negative")
+	}
+	if c.Trash.Retention <= 0 {
+		return fmt.Errorf("TRASH_RETENTION_DAYS must be positive")
+	}
+	if c.Trash.PurgeInterval <= 0 {
+		return fmt.Errorf("TRASH_PURGE_INTERVAL_MINUTES must be positive")
+	}
+	if c.Moderation.AutoHideThreshold < 0 {
+		return fmt.Errorf("MODERATION_AUTO_HIDE_THRESHOLD must not be negative")
+	}
+	switch c.Storage.Backend {
+	case "postgres":
+	case "filesystem":
+		if c.Storage.FSRoot == "" {
+			return fmt.Errorf("STORAGE_FS_ROOT is required for the filesystem backend")
+		}
+	case "s3":
+		if c.Storage.S3.Bucket == "" || c.Storage.S3.Endpoint == "" {
+			return fmt.Errorf("STORAGE_S3_ENDPOINT and STORAGE_S3_BUCKET are required for the s3 backend")
+		}
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be postgres, filesystem or s3")
+	}
+	if c.Storage.SweepInterval <= 0 {
+		return fmt.Errorf("STORAGE_SWEEP_INTERVAL_MINUTES must be positive")
+	}
+	if c.Upload.QuotaMB < 0 {
+		return fmt.Errorf("UPLOAD_QUOTA_MB must not be negative")
+	}
+	if c.Upload.ResumableExpiry <= 0 {
+		return fmt.Errorf("UPLOAD_EXPIRY_HOURS must be positive")
+	}
+	if c.ImageGC.GracePeriod <= 0 {
+		return fmt.Errorf("IMAGE_GC_GRACE_HOURS must be positive")
+	}
+	if c.ImageGC.Interval <= 0 {
+		return fmt.Errorf("IMAGE_GC_INTERVAL_MINUTES must be positive")
+	}
+	if len(c.ImageURL.Keys) == 0 {
+		return fmt.Errorf("IMAGE_URL_KEYS is required")
+	}
+	if c.ImageURL.TTL < 2*time.Minute {
+		return fmt.Errorf("IMAGE_URL_TTL_MINUTES must be at least 2")
+	}
 	return nil
 }
 
+// parseSigningKeys parses a comma-separated list of id:secret pairs.
+func parseSigningKeys(s string) ([]SigningKey, error) {
+	var keys []SigningKey
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("IMAGE_URL_KEYS must be a comma-separated list of id:secret pairs")
+		}
+		if url.QueryEscape(id) != id {
+			return nil, fmt.Errorf("IMAGE_URL_KEYS key ID %q must not need escaping in a URL", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("IMAGE_URL_KEYS has two keys with ID %q", id)
+		}
+		seen[id] = true
+		keys = append(keys, SigningKey{ID: id, Secret: []byte(secret)})
+	}
+	return keys, nil
+}
+
 // DSN returns the PostgreSQL connection string.
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf(
@@ -0,0 +1,345 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/acidsoft/gorestteach/pkg/markdown"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// migration is a named, hand-written schema change applied after AutoMigrate.
+// AutoMigrate only knows what the struct tags describe — compound indexes with
+// explicit ordering, partial indexes and data backfills live here instead.
+// Backfills that need Go code (e.g. rendering) use Run, which runs after SQL
+// in the same transaction.
+type migration struct {
+	ID  string
+	SQL string
+	Run func(tx *gorm.DB) error
+}
+
+// migrations are applied in order, exactly once each. Never edit or reorder an
+// entry that has shipped — append a new one instead.
+var migrations = []migration{
+	{
+		ID: "0001_posts_list_indexes",
+		SQL: `
+			CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts (created_at DESC, id DESC);
+			CREATE INDEX IF NOT EXISTS idx_posts_updated_at_id ON posts (updated_at DESC, id DESC);
+			CREATE INDEX IF NOT EXISTS idx_posts_user_created_at ON posts (user_id, created_at DESC);
+			CREATE INDEX IF NOT EXISTS idx_posts_user_updated_at ON posts (user_id, updated_at DESC);
+			CREATE INDEX IF NOT EXISTS idx_posts_title ON posts (title);
+			CREATE INDEX IF NOT EXISTS idx_posts_with_image_created_at ON posts (created_at DESC) WHERE image_id IS NOT NULL;
+		`,
+	},
+	{
+		ID: "0002_tags_indexes",
+		SQL: `
+			CREATE INDEX IF NOT EXISTS idx_post_tags_tag_post ON post_tags (tag_id, post_id);
+			CREATE INDEX IF NOT EXISTS idx_tags_slug_prefix ON tags (slug text_pattern_ops);
+		`,
+	},
+	{
+		ID: "0003_comments_constraints",
+		SQL: `
+			ALTER TABLE comments
+				ADD CONSTRAINT fk_comments_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+				ADD CONSTRAINT fk_comments_parent FOREIGN KEY (parent_id) REFERENCES comments (id) ON DELETE CASCADE;
+			CREATE INDEX IF NOT EXISTS idx_comments_post_parent_created ON comments (post_id, parent_id, created_at, id);
+			CREATE INDEX IF NOT EXISTS idx_comments_parent ON comments (parent_id);
+			UPDATE posts SET comment_count = (SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id);
+		`,
+	},
+	{
+		ID: "0004_post_reactions_constraints",
+		SQL: `
+			ALTER TABLE post_reactions
+				ADD CONSTRAINT fk_post_reactions_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE;
+			ALTER TABLE post_reaction_counts
+				ADD CONSTRAINT fk_post_reaction_counts_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE;
+		`,
+	},
+	{
+		ID: "0005_follows_constraints_and_feed_index",
+		SQL: `
+			ALTER TABLE follows
+				ADD CONSTRAINT fk_follows_follower FOREIGN KEY (follower_id) REFERENCES users (id) ON DELETE CASCADE,
+				ADD CONSTRAINT fk_follows_followee FOREIGN KEY (followee_id) REFERENCES users (id) ON DELETE CASCADE,
+				ADD CONSTRAINT chk_follows_not_self CHECK (follower_id <> followee_id);
+			CREATE INDEX IF NOT EXISTS idx_follows_followee_created ON follows (followee_id, created_at DESC);
+			CREATE INDEX IF NOT EXISTS idx_follows_follower_created ON follows (follower_id, created_at DESC);
+			CREATE INDEX IF NOT EXISTS idx_posts_user_created_id ON posts (user_id, created_at DESC, id DESC);
+		`,
+	},
+	{
+		ID: "0006_bookmarks_constraints",
+		SQL: `
+			ALTER TABLE bookmark_collections
+				ADD CONSTRAINT fk_bookmark_collections_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+			ALTER TABLE bookmarks
+				ADD CONSTRAINT fk_bookmarks_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				ADD CONSTRAINT fk_bookmarks_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+				ADD CONSTRAINT fk_bookmarks_collection FOREIGN KEY (collection_id) REFERENCES bookmark_collections (id) ON DELETE SET NULL;
+			CREATE INDEX IF NOT EXISTS idx_bookmarks_user_created ON bookmarks (user_id, created_at DESC);
+		`,
+	},
+	{
+		// Existing posts get their current content as revision 1 so the first
+		// edit after the upgrade still has something to diff against.
+		ID: "0007_post_revisions",
+		SQL: `
+			ALTER TABLE post_revisions
+				ADD CONSTRAINT fk_post_revisions_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE;
+			INSERT INTO post_revisions (post_id, revision, editor_id, title, body, created_at)
+			SELECT p.id, 1, p.user_id, p.title, p.body, p.updated_at
+			FROM posts p
+			WHERE NOT EXISTS (SELECT 1 FROM post_revisions r WHERE r.post_id = p.id);
+		`,
+	},
+	{
+		ID:  "0008_posts_body_html",
+		Run: backfillPostBodyHTML,
+	},
+	{
+		// Posts with an image get it as the cover of a one-image gallery.
+		ID: "0009_post_images",
+		SQL: `
+			ALTER TABLE post_images
+				ADD CONSTRAINT fk_post_images_image FOREIGN KEY (image_id) REFERENCES images (id);
+			INSERT INTO post_images (post_id, image_id, position, caption, alt, created_at)
+			SELECT id, image_id, 0, '', '', updated_at
+			FROM posts
+			WHERE image_id IS NOT NULL
+			ON CONFLICT DO NOTHING;
+		`,
+	},
+	{
+		ID: "0010_moderation",
+		SQL: `
+			ALTER TABLE reports
+				ADD CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE;
+			ALTER TABLE notifications
+				ADD CONSTRAINT fk_notifications_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+			CREATE INDEX IF NOT EXISTS idx_reports_status_created ON reports (status, created_at);
+			CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications (user_id, created_at DESC);
+		`,
+	},
+	{
+		// Suspensions used to be recorded by suspended_until alone.
+		ID: "0011_account_status",
+		SQL: `
+			UPDATE users SET status = 'suspended'
+			WHERE status = 'active' AND suspended_until > now();
+		`,
+	},
+	{
+		// Images kept in another storage backend have no bytes in the row.
+		ID: "0012_image_backends",
+		SQL: `
+			ALTER TABLE images ALTER COLUMN data DROP NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_images_backend_id ON images (backend, id);
+		`,
+	},
+	{
+		ID: "0013_image_variants",
+		SQL: `
+			ALTER TABLE image_variants
+				ADD CONSTRAINT fk_image_variants_image FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE;
+			CREATE INDEX IF NOT EXISTS idx_image_variants_backend ON image_variants (backend);
+		`,
+	},
+	{
+		// Images kept in other backends get their hash when next served.
+		ID: "0014_image_hashes",
+		SQL: `
+			UPDATE images SET sha256 = encode(sha256(data), 'hex')
+			WHERE sha256 = '' AND data IS NOT NULL;
+			UPDATE image_variants SET sha256 = encode(sha256(data), 'hex')
+			WHERE sha256 = '' AND data IS NOT NULL;
+		`,
+	},
+	{
+		// Copies of the same bytes are merged into the oldest one before the
+		// hash becomes unique. A gallery that held several copies keeps the
+		// first. Reference counts are then maintained by triggers, so that
+		// no code path that adds or drops an avatar or gallery entry —
+		// cascades included — can forget them.
+		ID: "0015_image_dedup",
+		SQL: `
+			CREATE TEMP TABLE image_copies ON COMMIT DROP AS
+			SELECT id, keep FROM (
+				SELECT id, first_value(id) OVER (PARTITION BY sha256 ORDER BY created_at, id) AS keep
+				FROM images WHERE sha256 <> ''
+			) c WHERE id <> keep;
+
+			DELETE FROM post_images p
+			WHERE EXISTS (
+				SELECT 1 FROM post_images q
+				LEFT JOIN image_copies qc ON qc.id = q.image_id
+				LEFT JOIN image_copies pc ON pc.id = p.image_id
+				WHERE q.post_id = p.post_id AND q.position < p.position
+				  AND COALESCE(qc.keep, q.image_id) = COALESCE(pc.keep, p.image_id)
+			);
+			UPDATE post_images p SET position = r.position
+			FROM (
+				SELECT post_id, image_id, row_number() OVER (PARTITION BY post_id ORDER BY position) - 1 AS position
+				FROM post_images
+			) r
+			WHERE p.post_id = r.post_id AND p.image_id = r.image_id AND p.position <> r.position;
+			UPDATE post_images SET image_id = c.keep FROM image_copies c WHERE post_images.image_id = c.id;
+			UPDATE posts SET image_id = c.keep, version = version + 1 FROM image_copies c WHERE posts.image_id = c.id;
+			UPDATE users SET avatar_id = c.keep FROM image_copies c WHERE users.avatar_id = c.id;
+
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT i.backend, i.id::text, now()
+			FROM images i JOIN image_copies c ON c.id = i.id
+			WHERE i.backend <> 'postgres';
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT v.backend, v.image_id::text || '_' || v.name, now()
+			FROM image_variants v JOIN image_copies c ON c.id = v.image_id
+			WHERE v.backend <> 'postgres';
+			DELETE FROM images WHERE id IN (SELECT id FROM image_copies);
+
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_images_sha256 ON images (sha256) WHERE sha256 <> '';
+
+			UPDATE images SET ref_count =
+				(SELECT count(*) FROM post_images WHERE post_images.image_id = images.id) +
+				(SELECT count(*) FROM users WHERE users.avatar_id = images.id);
+
+			CREATE OR REPLACE FUNCTION count_image_refs() RETURNS trigger AS $$
+			DECLARE
+				old_id uuid;
+				new_id uuid;
+			BEGIN
+				IF TG_OP <> 'INSERT' THEN
+					old_id := (to_jsonb(OLD) ->> TG_ARGV[0])::uuid;
+				END IF;
+				IF TG_OP <> 'DELETE' THEN
+					new_id := (to_jsonb(NEW) ->> TG_ARGV[0])::uuid;
+				END IF;
+				IF old_id IS DISTINCT FROM new_id THEN
+					UPDATE images SET ref_count = ref_count - 1 WHERE id = old_id;
+					UPDATE images SET ref_count = ref_count + 1 WHERE id = new_id;
+				END IF;
+				RETURN NULL;
+			END
+			$$ LANGUAGE plpgsql;
+			CREATE TRIGGER post_images_count_image_refs
+				AFTER INSERT OR DELETE OR UPDATE OF image_id ON post_images
+				FOR EACH ROW EXECUTE FUNCTION count_image_refs('image_id');
+			CREATE TRIGGER users_count_image_refs
+				AFTER INSERT OR DELETE OR UPDATE OF avatar_id ON users
+				FOR EACH ROW EXECUTE FUNCTION count_image_refs('avatar_id');
+		`,
+	},
+	{
+		ID: "0016_image_orphans",
+		SQL: `
+			UPDATE images SET saved_at = created_at;
+			CREATE INDEX IF NOT EXISTS idx_images_orphans ON images (saved_at) WHERE ref_count = 0;
+		`,
+	},
+	{
+		// Neither key cascades: uploads are deleted through UploadRepository,
+		// which queues the bytes of their chunks for the blob sweeper.
+		ID: "0017_uploads",
+		SQL: `
+			ALTER TABLE uploads
+				ADD CONSTRAINT fk_uploads_user FOREIGN KEY (user_id) REFERENCES users (id);
+			ALTER TABLE upload_chunks
+				ADD CONSTRAINT fk_upload_chunks_upload FOREIGN KEY (upload_id) REFERENCES uploads (id);
+		`,
+	},
+	{
+		// Uploaders were not recorded before; charge existing images to the
+		// users whose avatar or posts they are, avatars first.
+		ID: "0018_image_owner",
+		SQL: `
+			ALTER TABLE images
+				ADD CONSTRAINT fk_images_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+			UPDATE images SET user_id = users.id
+			FROM users WHERE users.avatar_id = images.id AND images.user_id IS NULL;
+			UPDATE images SET user_id = posts.user_id
+			FROM post_images JOIN posts ON posts.id = post_images.post_id
+			WHERE post_images.image_id = images.id AND images.user_id IS NULL;
+		`,
+	},
+}
+
+// backfillPostBodyHTML renders the cached HTML of posts written before body
+// formats existed. They are all plain text.
+func backfillPostBodyHTML(tx *gorm.DB) error {
+	type row struct {
+		ID   uuid.UUID
+		Body string
+	}
+
+	after := uuid.Nil
+	for {
+		var rows []row
+		if err := tx.Table("posts").
+			Select("id", "body").
+			Where("body_html = '' AND id > ?", after).
+			Order("id").
+			Limit(500).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		for _, r := range rows {
+			if err := tx.Table("posts").
+				Where("id = ?", r.ID).
+				Update("body_html", markdown.PlainToHTML(r.Body)).Error; err != nil {
+				return err
+			}
+		}
+		after = rows[len(rows)-1].ID
+	}
+}
+
+// schemaMigration records which migrations have already been applied.
+type schemaMigration struct {
+	ID string `gorm:"type:varchar(100);primaryKey"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// runMigrations applies every pending migration inside its own transaction.
+func runMigrations(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	for _, m := range migrations {
+		var count int64
+		if err := db.Model(&schemaMigration{}).Where("id = ?", m.ID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", m.ID, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if m.SQL != "" {
+				if err := tx.Exec(m.SQL).Error; err != nil {
+					return err
+				}
+			}
+			if m.Run != nil {
+				if err := m.Run(tx); err != nil {
+					return err
+				}
+			}
+			return tx.Create(&schemaMigration{ID: m.ID}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.ID, err)
+		}
+	}
+
+	return nil
+}
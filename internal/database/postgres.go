@@ -24,11 +24,31 @@ func Connect(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	if err := db.AutoMigrate(
 		&domain.User{},
 		&domain.Post{},
+		&domain.Tag{},
+		&domain.Comment{},
+		&domain.PostReaction{},
+		&domain.PostReactionCount{},
+		&domain.Follow{},
+		&domain.BookmarkCollection{},
+		&domain.Bookmark{},
+		&domain.PostRevision{},
+		&domain.PostImage{},
 		&domain.Image{},
+		&domain.ImageVariant{},
+		&domain.BlobTombstone{},
+		&domain.Upload{},
+		&domain.UploadChunk{},
 		&domain.RefreshToken{},
+		&domain.Report{},
+		&domain.AuditEntry{},
+		&domain.Notification{},
 	); err != nil {
 		return nil, fmt.Errorf("auto migration failed: %w", err)
 	}
 
+	if err := runMigrations(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction names a moderation action recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditAutoHide      AuditAction = "auto_hide"
+	AuditDismiss       AuditAction = "dismiss"
+	AuditRemoveContent AuditAction = "remove_content"
+	AuditSuspendAuthor AuditAction = "suspend_author"
+	AuditSuspendUser   AuditAction = "suspend_user"
+	AuditBanUser       AuditAction = "ban_user"
+	AuditReinstateUser AuditAction = "reinstate_user"
+)
+
+// AuditTargetUser is the target type of entries about accounts rather than
+// reported content.
+const AuditTargetUser ReportTargetType = "user"
+
+// AuditEntry records a moderation action. Entries are append-only and are
+// written in the same transaction as the action itself. ActorID is nil for
+// actions the system took on its own.
+type AuditEntry struct {
+	ID         uuid.UUID        `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ActorID    *uuid.UUID       `gorm:"type:uuid"                                      json:"actor_id"`
+	Action     AuditAction      `gorm:"type:varchar(32);not null"                      json:"action"`
+	TargetType ReportTargetType `gorm:"type:varchar(16);not null"                      json:"target_type"`
+	TargetID   uuid.UUID        `gorm:"type:uuid;not null;index"                       json:"target_id"`
+	ReportID   *uuid.UUID       `gorm:"type:uuid"                                      json:"report_id,omitempty"`
+	Note       string           `gorm:"type:varchar(1000);not null;default:''"         json:"note"`
+	CreatedAt  time.Time        `gorm:"index"                                          json:"created_at"`
+}
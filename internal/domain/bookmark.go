@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookmarkCollection is a user-named folder of saved posts.
+type BookmarkCollection struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"                     json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_bookmark_collection_name"         json:"-"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_bookmark_collection_name" json:"name"`
+	CreatedAt time.Time `                                                                           json:"created_at"`
+	UpdatedAt time.Time `                                                                           json:"updated_at"`
+}
+
+// Bookmark is a post saved by a user, optionally filed into a collection.
+// A post is saved at most once per user; re-saving moves it between collections.
+type Bookmark struct {
+	UserID       uuid.UUID  `gorm:"type:uuid;primaryKey"       json:"-"`
+	PostID       uuid.UUID  `gorm:"type:uuid;primaryKey;index" json:"post_id"`
+	CollectionID *uuid.UUID `gorm:"type:uuid;index"            json:"collection_id"`
+	CreatedAt    time.Time  `                                  json:"created_at"`
+}
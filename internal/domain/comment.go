@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxCommentDepth is the deepest reply level allowed; top-level comments are depth 0.
+const MaxCommentDepth = 4
+
+// Comment is a (possibly nested) discussion entry under a Post.
+//
+// Hidden and removed comments stay in the thread so their replies keep their
+// place, but their body is blanked for everyone who may not read it.
+type Comment struct {
+	ID         uuid.UUID        `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	PostID     uuid.UUID        `gorm:"type:uuid;not null"                             json:"post_id"`
+	UserID     uuid.UUID        `gorm:"type:uuid;not null;index"                       json:"user_id"`
+	ParentID   *uuid.UUID       `gorm:"type:uuid"                                      json:"parent_id,omitempty"`
+	Depth      int              `gorm:"not null;default:0"                             json:"depth"`
+	Body       string           `gorm:"type:text;not null"                             json:"body"`
+	Moderation ModerationStatus `gorm:"type:varchar(16);not null;default:visible"      json:"moderation_status"`
+	ReplyCount int64            `gorm:"->;-:migration"                                 json:"reply_count"` // computed on read
+	User       *User            `gorm:"foreignKey:UserID"                              json:"author,omitempty"`
+	CreatedAt  time.Time        `                                                      json:"created_at"`
+	UpdatedAt  time.Time        `                                                      json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Follow is a directed edge of the social graph: FollowerID follows FolloweeID.
+// The composite primary key (follower first) also serves "who do I follow"
+// lookups; the extra index on FolloweeID serves "who follows me".
+type Follow struct {
+	FollowerID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	FolloweeID uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+	CreatedAt  time.Time
+}
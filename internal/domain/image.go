@@ -1,17 +1,90 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Image stores binary file data directly in PostgreSQL as bytea.
-// Using a separate table keeps the User/Post rows lean.
+// Image is an uploaded file. The row holds its metadata; the bytes live in
+// the storage backend named by Backend — for "postgres", in Data itself.
+// Width and Height are the displayed size in pixels, 0 for images uploaded
+// before it was recorded. Using a separate table keeps the User/Post rows
+// lean.
+//
+// Images are content-addressed: SHA256 identifies the bytes and is unique,
+// so uploading the same file again yields the existing image. It is empty
+// for old images until they are next read in full. RefCount is the number
+// of avatars and gallery entries using the image, kept up to date by
+// database triggers. SavedAt is when the bytes were last uploaded: images
+// nothing references are collected a grace period after it.
+//
+// UserID is the user who first uploaded the bytes, whose storage quota they
+// count against; uploading them again costs nothing, as nothing new is
+// stored. It is nil for images whose uploader left no trace.
 type Image struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Data        []byte    `gorm:"type:bytea;not null"                            json:"-"`
-	ContentType string    `gorm:"type:varchar(50);not null"                      json:"content_type"`
-	Size        int64     `gorm:"not null"                                       json:"size"`
-	CreatedAt   time.Time `                                                      json:"created_at"`
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"  json:"id"`
+	UserID      *uuid.UUID `gorm:"type:uuid;index"                                 json:"-"`
+	Data        []byte     `gorm:"type:bytea"                                      json:"-"`
+	Backend     string     `gorm:"type:varchar(16);not null;default:postgres"      json:"-"`
+	ContentType string     `gorm:"type:varchar(50);not null"                       json:"content_type"`
+	Size        int64      `gorm:"not null"                                        json:"size"`
+	Width       int        `gorm:"not null;default:0"                              json:"width"`
+	Height      int        `gorm:"not null;default:0"                              json:"height"`
+	SHA256      string     `gorm:"column:sha256;type:char(64);not null;default:''" json:"-"`
+	RefCount    int        `gorm:"not null;default:0"                              json:"-"`
+	SavedAt     time.Time  `gorm:"not null;default:now()"                          json:"-"`
+	CreatedAt   time.Time  `                                                       json:"created_at"`
+
+	// Public is false when only some viewers may see the image: it belongs
+	// to a post's gallery. Set by ImageRepository.GetByID.
+	Public bool `gorm:"->;-:migration" json:"-"`
+	// Body streams the bytes of a new image to store instead of Data; Size
+	// and SHA256 are computed as it is read.
+	Body io.Reader `gorm:"-" json:"-"`
+	// Quota, when set on a new image with a UserID, is the most bytes of
+	// images the uploader may have once it is saved; saving it fails if it
+	// would take them past that.
+	Quota int64 `gorm:"-" json:"-"`
+}
+
+// ContentHash returns the hex SHA-256 digest of data, as recorded in
+// Image.SHA256 and ImageVariant.SHA256.
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// BlobTombstone marks a blob whose image or upload was deleted. Rows are deleted
+// inside database transactions, which cannot reach external storage; the
+// blob sweeper deletes the bytes later.
+type BlobTombstone struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Backend   string    `gorm:"type:varchar(16);not null"`
+	Key       string    `gorm:"type:varchar(255);not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// ImageVariant is a resized rendition of an image, generated on first
+// request and kept until the image is deleted. Like Image, its bytes live in
+// Backend — for "postgres", in Data itself.
+type ImageVariant struct {
+	ImageID     uuid.UUID `gorm:"type:uuid;primaryKey"                            json:"-"`
+	Name        string    `gorm:"type:varchar(64);primaryKey"                     json:"-"`
+	Data        []byte    `gorm:"type:bytea"                                      json:"-"`
+	Backend     string    `gorm:"type:varchar(16);not null;default:postgres"      json:"-"`
+	ContentType string    `gorm:"type:varchar(50);not null"                       json:"content_type"`
+	Size        int64     `gorm:"not null"                                        json:"size"`
+	Width       int       `gorm:"not null"                                        json:"width"`
+	Height      int       `gorm:"not null"                                        json:"height"`
+	SHA256      string    `gorm:"column:sha256;type:char(64);not null;default:''" json:"-"`
+	CreatedAt   time.Time `                                                       json:"created_at"`
+}
+
+// BlobKey is the key of the variant's bytes in an external backend.
+func (v *ImageVariant) BlobKey() string {
+	return v.ImageID.String() + "_" + v.Name
 }
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationKind says what a notification is about.
+type NotificationKind string
+
+const (
+	// NotifyReportResolved tells a reporter their report was reviewed.
+	NotifyReportResolved NotificationKind = "report_resolved"
+	// NotifyContentHidden tells an author their content was hidden pending review.
+	NotifyContentHidden NotificationKind = "content_hidden"
+	// NotifyContentRestored tells an author hidden content is visible again.
+	NotifyContentRestored NotificationKind = "content_restored"
+	// NotifyContentRemoved tells an author a moderator removed their content.
+	NotifyContentRemoved NotificationKind = "content_removed"
+	// NotifyAccountSuspended tells a user their account was suspended.
+	NotifyAccountSuspended NotificationKind = "account_suspended"
+	// NotifyAccountReinstated tells a user an admin lifted their suspension.
+	NotifyAccountReinstated NotificationKind = "account_reinstated"
+)
+
+// Notification is an entry in a user's notification stream. SubjectType and
+// SubjectID point at what it is about, when there is such a thing.
+type Notification struct {
+	ID          uuid.UUID        `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID        `gorm:"type:uuid;not null"                             json:"-"`
+	Kind        NotificationKind `gorm:"type:varchar(32);not null"                      json:"kind"`
+	SubjectType string           `gorm:"type:varchar(16);not null;default:''"           json:"subject_type,omitempty"`
+	SubjectID   *uuid.UUID       `gorm:"type:uuid"                                      json:"subject_id,omitempty"`
+	Message     string           `gorm:"type:varchar(500);not null"                     json:"message"`
+	ReadAt      *time.Time       `                                                      json:"read_at"`
+	CreatedAt   time.Time        `                                                      json:"created_at"`
+}
@@ -4,16 +4,75 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BodyFormat is the markup language of a post body.
+type BodyFormat string
+
+const (
+	BodyFormatPlain    BodyFormat = "plain"
+	BodyFormatMarkdown BodyFormat = "markdown"
+)
+
+// Visibility controls who can see a post.
+type Visibility string
+
+const (
+	// VisibilityPublic posts are shown to everyone, in listings too.
+	VisibilityPublic Visibility = "public"
+	// VisibilityFollowers posts are shown only to the author's followers.
+	VisibilityFollowers Visibility = "followers"
+	// VisibilityUnlisted posts open for anyone with the link but stay out
+	// of listings, search and feeds.
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPrivate posts are shown to the author only.
+	VisibilityPrivate Visibility = "private"
 )
 
 // Post is an article/post entity owned by a User.
+//
+// ImageID is derived: it always mirrors the first image of the Images gallery
+// (nil when the gallery is empty).
+//
+// Body is the source as written in BodyFormat; BodyHTML caches its sanitized
+// HTML rendering and is refreshed by every write that changes the body.
+//
+// CommentCount is denormalized: the comment repository adjusts it in the same
+// transaction as every comment insert/delete, so post saves must never write it.
+// ImageURL, Reactions, MyReaction and IsBookmarked are not columns — they are
+// filled per viewer by the use case after loading.
+//
+// Visibility is enforced by the repository: every post query takes the viewer
+// and only returns what they may see. The author always sees their own posts,
+// unless a moderator removed them; hidden posts are seen by the author only.
+//
+// Version is bumped by every content write and is exposed as the ETag; writes
+// are conditional on it (optimistic concurrency control).
+//
+// Posts are soft-deleted: DeletedAt moves them to the owner's trash, and the
+// trash purger removes them for good once the retention window has passed.
+// It serializes as null for live posts.
 type Post struct {
-	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"                       json:"user_id"`
-	Title     string     `gorm:"type:varchar(255);not null"                     json:"title"`
-	Body      string     `gorm:"type:text;not null"                             json:"body"`
-	ImageID   *uuid.UUID `gorm:"type:uuid"                                      json:"image_id,omitempty"`
-	User      *User      `gorm:"foreignKey:UserID"                              json:"author,omitempty"`
-	CreatedAt time.Time  `                                                      json:"created_at"`
-	UpdatedAt time.Time  `                                                      json:"updated_at"`
+	ID           uuid.UUID              `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"  json:"id"`
+	UserID       uuid.UUID              `gorm:"type:uuid;not null;index"                        json:"user_id"`
+	Title        string                 `gorm:"type:varchar(255);not null"                      json:"title"`
+	Body         string                 `gorm:"type:text;not null"                              json:"body"`
+	BodyFormat   BodyFormat             `gorm:"type:varchar(16);not null;default:plain"         json:"body_format"`
+	BodyHTML     string                 `gorm:"type:text;not null;default:''"                   json:"body_html"`
+	Visibility   Visibility             `gorm:"type:varchar(16);not null;default:public"        json:"visibility"`
+	Moderation   ModerationStatus       `gorm:"type:varchar(16);not null;default:visible"       json:"moderation_status"`
+	ImageID      *uuid.UUID             `gorm:"type:uuid"                                       json:"image_id,omitempty"`
+	ImageURL     string                 `gorm:"-"                                               json:"image_url,omitempty"`
+	Images       []PostImage            `gorm:"constraint:OnDelete:CASCADE"                     json:"images"`
+	Version      int64                  `gorm:"not null;default:1"                              json:"version"`
+	CommentCount int64                  `gorm:"not null;default:0"                              json:"comment_count"`
+	Reactions    map[ReactionKind]int64 `gorm:"-"                                               json:"reactions"`
+	MyReaction   *ReactionKind          `gorm:"-"                                               json:"my_reaction"`
+	IsBookmarked bool                   `gorm:"-"                                               json:"is_bookmarked"`
+	User         *User                  `gorm:"foreignKey:UserID"                               json:"author,omitempty"`
+	Tags         []Tag                  `gorm:"many2many:post_tags;constraint:OnDelete:CASCADE" json:"tags"`
+	CreatedAt    time.Time              `                                                       json:"created_at"`
+	UpdatedAt    time.Time              `                                                       json:"updated_at"`
+	DeletedAt    gorm.DeletedAt         `gorm:"index"                                           json:"deleted_at"`
 }
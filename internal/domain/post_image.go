@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxPostImages caps the size of a post's gallery.
+const MaxPostImages = 20
+
+// PostImage places an image in a post's gallery. Positions are contiguous from
+// 0; the image at position 0 is the cover, mirrored into Post.ImageID for
+// clients that predate galleries.
+type PostImage struct {
+	PostID    uuid.UUID `gorm:"type:uuid;primaryKey"                  json:"-"`
+	ImageID   uuid.UUID `gorm:"type:uuid;primaryKey"                  json:"image_id"`
+	ImageURL  string    `gorm:"-"                                     json:"image_url"`
+	Position  int       `gorm:"not null"                              json:"position"`
+	Caption   string    `gorm:"type:varchar(300);not null;default:''" json:"caption"`
+	Alt       string    `gorm:"type:varchar(300);not null;default:''" json:"alt"`
+	CreatedAt time.Time `                                             json:"created_at"`
+
+	// Image is an upload to store along with the entry when it is added.
+	Image *Image `gorm:"-" json:"-"`
+}
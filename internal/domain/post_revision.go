@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostRevision is an immutable snapshot of a post's title and body. Revision
+// numbers start at 1 per post and increase by one with every edit; the highest
+// revision always matches the live post.
+type PostRevision struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"     json:"id"`
+	PostID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_post_revision"   json:"post_id"`
+	Revision  int       `gorm:"not null;uniqueIndex:idx_post_revision"             json:"revision"`
+	EditorID  uuid.UUID `gorm:"type:uuid;not null"                                 json:"editor_id"`
+	Title     string    `gorm:"type:varchar(255);not null"                         json:"title"`
+	Body      string    `gorm:"type:text;not null"                                 json:"body,omitempty"`
+	CreatedAt time.Time `                                                          json:"created_at"`
+}
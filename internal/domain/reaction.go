@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReactionKind is one of a fixed set of reactions a user can leave on a post.
+type ReactionKind string
+
+const (
+	ReactionLike  ReactionKind = "like"
+	ReactionLove  ReactionKind = "love"
+	ReactionLaugh ReactionKind = "laugh"
+	ReactionWow   ReactionKind = "wow"
+	ReactionSad   ReactionKind = "sad"
+	ReactionAngry ReactionKind = "angry"
+)
+
+// PostReaction is a single user's reaction to a post. The composite primary key
+// enforces one reaction per user per post; changing it overwrites Kind.
+type PostReaction struct {
+	PostID    uuid.UUID    `gorm:"type:uuid;primaryKey"            json:"post_id"`
+	UserID    uuid.UUID    `gorm:"type:uuid;primaryKey;index"      json:"user_id"`
+	Kind      ReactionKind `gorm:"type:varchar(20);not null"       json:"kind"`
+	CreatedAt time.Time    `                                       json:"created_at"`
+	UpdatedAt time.Time    `                                       json:"updated_at"`
+}
+
+// PostReactionCount is the denormalized per-post, per-kind counter kept in
+// step with post_reactions inside the same transaction.
+type PostReactionCount struct {
+	PostID uuid.UUID    `gorm:"type:uuid;primaryKey"`
+	Kind   ReactionKind `gorm:"type:varchar(20);primaryKey"`
+	Count  int64        `gorm:"not null;default:0"`
+}
+
+// ReactionSummary is the aggregated view of a post's reactions for one viewer.
+type ReactionSummary struct {
+	Counts map[ReactionKind]int64 `json:"reactions"`
+	Mine   *ReactionKind          `json:"my_reaction"`
+}
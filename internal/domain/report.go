@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ModerationStatus is the moderation state of a post or comment.
+type ModerationStatus string
+
+const (
+	ModerationVisible ModerationStatus = "visible"
+	// ModerationHidden content was hidden automatically after enough reports
+	// and waits for review; only its author still sees it.
+	ModerationHidden ModerationStatus = "hidden"
+	// ModerationRemoved content was taken down by a moderator; nobody sees it.
+	ModerationRemoved ModerationStatus = "removed"
+)
+
+// ReportTargetType is the kind of content a report is about.
+type ReportTargetType string
+
+const (
+	ReportTargetPost    ReportTargetType = "post"
+	ReportTargetComment ReportTargetType = "comment"
+)
+
+// ReportReason is the reason code a reporter picks.
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "spam"
+	ReportReasonHarassment     ReportReason = "harassment"
+	ReportReasonHate           ReportReason = "hate"
+	ReportReasonViolence       ReportReason = "violence"
+	ReportReasonSexual         ReportReason = "sexual"
+	ReportReasonMisinformation ReportReason = "misinformation"
+	ReportReasonOther          ReportReason = "other"
+)
+
+// ReportStatus tracks a report through the moderation queue.
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportDismissed ReportStatus = "dismissed"
+	ReportActioned  ReportStatus = "actioned"
+)
+
+// Report is one user's complaint about a post or comment. A user can report
+// the same content only once. Resolving a report resolves every open report
+// on the same content.
+//
+// TargetAuthorID and Snapshot are captured when the report is filed, so the
+// queue still shows what was reported after the content is edited or gone.
+type Report struct {
+	ID             uuid.UUID        `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"                     json:"id"`
+	TargetType     ReportTargetType `gorm:"type:varchar(16);not null;uniqueIndex:idx_reports_target_reporter" json:"target_type"`
+	TargetID       uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_reports_target_reporter"        json:"target_id"`
+	ReporterID     uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_reports_target_reporter"        json:"reporter_id"`
+	TargetAuthorID uuid.UUID        `gorm:"type:uuid;not null;index"                                           json:"target_author_id"`
+	Reason         ReportReason     `gorm:"type:varchar(32);not null"                                          json:"reason"`
+	Details        string           `gorm:"type:varchar(1000);not null;default:''"                             json:"details"`
+	Snapshot       string           `gorm:"type:text;not null"                                                 json:"snapshot"`
+	Status         ReportStatus     `gorm:"type:varchar(16);not null;default:open"                             json:"status"`
+	ResolvedBy     *uuid.UUID       `gorm:"type:uuid"                                                          json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time       `                                                                          json:"resolved_at,omitempty"`
+	CreatedAt      time.Time        `                                                                          json:"created_at"`
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// MaxTagSlugLength caps the normalized slug length.
+const MaxTagSlugLength = 50
+
+// Tag is a topic label shared between posts (many-to-many via post_tags).
+// Slug is the canonical, URL-safe identity; Name keeps the first spelling seen.
+type Tag struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"-"`
+	Slug      string    `gorm:"type:varchar(50);uniqueIndex;not null"          json:"slug"`
+	Name      string    `gorm:"type:varchar(50);not null"                      json:"name"`
+	CreatedAt time.Time `                                                      json:"-"`
+}
+
+// TagWithCount is a tag together with the number of posts using it.
+type TagWithCount struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	PostCount int64  `json:"post_count"`
+}
+
+// NormalizeTagSlug lowercases the input and collapses every run of characters
+// other than letters and digits into a single dash: "  Go Lang!! " → "go-lang".
+// The result may be empty if the input had no letters or digits.
+func NormalizeTagSlug(raw string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(raw)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingDash = true
+	}
+
+	slug := b.String()
+	if len(slug) > MaxTagSlugLength {
+		// Trim on a rune boundary, then drop any trailing dash.
+		runes := []rune(slug)
+		for len(string(runes)) > MaxTagSlugLength {
+			runes = runes[:len(runes)-1]
+		}
+		slug = strings.TrimRight(string(runes), "-")
+	}
+	return slug
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxUploadChunks caps how many chunks an upload may be sent in, and so how
+// many rows and blobs one upload can take up.
+const MaxUploadChunks = 1000
+
+// Upload is a resumable upload (tus protocol): a file of Length bytes sent in
+// chunks, of which the first Offset have been received. A complete upload is
+// turned into an image when it is attached to a profile or post, and is
+// deleted then; an incomplete one is deleted at ExpiresAt. Metadata is the
+// client's Upload-Metadata header, kept as sent.
+type Upload struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"                       json:"-"`
+	Length    int64     `gorm:"not null"                                       json:"length"`
+	Offset    int64     `gorm:"column:upload_offset;not null;default:0"        json:"offset"`
+	Chunks    int       `gorm:"not null;default:0"                             json:"-"`
+	Metadata  string    `gorm:"type:varchar(1024);not null;default:''"         json:"-"`
+	ExpiresAt time.Time `gorm:"not null;index"                                 json:"expires_at"`
+	CreatedAt time.Time `                                                      json:"created_at"`
+	UpdatedAt time.Time `                                                      json:"updated_at"`
+}
+
+// Complete reports whether every byte of the upload has been received.
+func (u *Upload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// UploadChunk is a run of an upload's bytes starting at Offset. Like an
+// image's, its bytes live in Backend — for "postgres", in Data itself.
+type UploadChunk struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UploadID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_upload_chunks_offset,priority:1"`
+	Offset    int64     `gorm:"column:upload_offset;not null;uniqueIndex:idx_upload_chunks_offset,priority:2"`
+	Size      int64     `gorm:"not null"`
+	Data      []byte    `gorm:"type:bytea"`
+	Backend   string    `gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time
+}
+
+// BlobKey is the key of the chunk's bytes in an external backend.
+func (c *UploadChunk) BlobKey() string {
+	return c.ID.String()
+}
@@ -6,26 +6,76 @@ import (
 	"github.com/google/uuid"
 )
 
+// Role grants access to staff-only endpoints. There is no API to change it;
+// staff are appointed directly in the database.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// AccountStatus says whether a user may use the API at all.
+type AccountStatus string
+
+const (
+	AccountActive    AccountStatus = "active"
+	AccountSuspended AccountStatus = "suspended" // until User.SuspendedUntil
+	AccountBanned    AccountStatus = "banned"
+)
+
 // User is the core user entity stored in the database.
+// Status and SuspendedUntil are managed by staff only; see StatusAt.
+// AvatarURL is not a column: it is filled in by the use case, as the URL
+// depends on when it is made (see package imageurl).
 type User struct {
-	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Name      string     `gorm:"type:varchar(100);not null"                     json:"name"`
-	Email     string     `gorm:"type:varchar(255);uniqueIndex;not null"          json:"email"`
-	Password  string     `gorm:"type:varchar(255);not null"                     json:"-"` // never serialized
-	Bio       string     `gorm:"type:text"                                       json:"bio"`
-	AvatarID  *uuid.UUID `gorm:"type:uuid"                                       json:"avatar_id,omitempty"`
-	CreatedAt time.Time  `                                                       json:"created_at"`
-	UpdatedAt time.Time  `                                                       json:"updated_at"`
+	ID             uuid.UUID     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Name           string        `gorm:"type:varchar(100);not null"                     json:"name"`
+	Email          string        `gorm:"type:varchar(255);uniqueIndex;not null"          json:"email"`
+	Password       string        `gorm:"type:varchar(255);not null"                     json:"-"` // never serialized
+	Bio            string        `gorm:"type:text"                                       json:"bio"`
+	AvatarID       *uuid.UUID    `gorm:"type:uuid"                                       json:"avatar_id,omitempty"`
+	AvatarURL      string        `gorm:"-"                                               json:"avatar_url,omitempty"`
+	Role           Role          `gorm:"type:varchar(16);not null;default:user"          json:"role"`
+	Status         AccountStatus `gorm:"type:varchar(16);not null;default:active"        json:"status"`
+	SuspendedUntil *time.Time    `                                                       json:"suspended_until,omitempty"`
+	CreatedAt      time.Time     `                                                       json:"created_at"`
+	UpdatedAt      time.Time     `                                                       json:"updated_at"`
+}
+
+// IsModerator reports whether the user may work the moderation queue.
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
+// IsAdmin reports whether the user may manage other accounts.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// StatusAt returns the user's effective status at the given time: a
+// suspension that has run out counts as active.
+func (u *User) StatusAt(now time.Time) AccountStatus {
+	if u.Status == AccountSuspended && (u.SuspendedUntil == nil || !now.Before(*u.SuspendedUntil)) {
+		return AccountActive
+	}
+	return u.Status
 }
 
 // UserPublic is the safe public representation of a user (no password).
+// The follow counts are only filled in for full profiles.
 type UserPublic struct {
-	ID        uuid.UUID  `json:"id"`
-	Name      string     `json:"name"`
-	Email     string     `json:"email"`
-	Bio       string     `json:"bio"`
-	AvatarID  *uuid.UUID `json:"avatar_id,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	Bio            string     `json:"bio"`
+	AvatarID       *uuid.UUID `json:"avatar_id,omitempty"`
+	AvatarURL      string     `json:"avatar_url,omitempty"`
+	Role           Role       `json:"role"`
+	FollowersCount int64      `json:"followers_count"`
+	FollowingCount int64      `json:"following_count"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 func (u *User) ToPublic() UserPublic {
@@ -35,6 +85,8 @@ func (u *User) ToPublic() UserPublic {
 		Email:     u.Email,
 		Bio:       u.Bio,
 		AvatarID:  u.AvatarID,
+		AvatarURL: u.AvatarURL,
+		Role:      u.Role,
 		CreatedAt: u.CreatedAt,
 	}
 }
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AccountHandler serves the admin endpoints that suspend, ban and reinstate users.
+type AccountHandler struct {
+	accountUC *usecase.AccountUseCase
+}
+
+func NewAccountHandler(accountUC *usecase.AccountUseCase) *AccountHandler {
+	return &AccountHandler{accountUC: accountUC}
+}
+
+// GetStatus godoc
+// @Summary      Get account status
+// @Description  Returns whether the user is active, suspended (and until when) or banned. Admins only.
+// @Tags         moderation
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User UUID"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /admin/users/{id}/status [get]
+func (h *AccountHandler) GetStatus(c *gin.Context) {
+	actorID := mustGetUserID(c).(uuid.UUID)
+
+	userID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	view, ucErr := h.accountUC.GetStatus(c.Request.Context(), userID, actorID)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, view)
+}
+
+// SetStatus godoc
+// @Summary      Set account status
+// @Description  Suspends the user until a given time, bans them, or reinstates them. Suspending or banning
+// @Description  revokes the user's sessions. Admins only; admins cannot change their own status.
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                         true  "User UUID"
+// @Param        body  body      usecase.SetAccountStatusInput  true  "New status"
+// @Success      200   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      403   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Router       /admin/users/{id}/status [put]
+func (h *AccountHandler) SetStatus(c *gin.Context) {
+	actorID := mustGetUserID(c).(uuid.UUID)
+
+	userID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.SetAccountStatusInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	view, ucErr := h.accountUC.SetStatus(c.Request.Context(), userID, actorID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, view)
+}
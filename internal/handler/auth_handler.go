@@ -1,18 +1,31 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
 
 	"github.com/acidsoft/gorestteach/internal/middleware"
 	"github.com/acidsoft/gorestteach/internal/usecase"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/patch"
 	"github.com/acidsoft/gorestteach/pkg/response"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 )
 
-var validate = validator.New()
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// Merge-patch members are validated by their value, with the same tags.
+	v.RegisterCustomTypeFunc(patch.ValidationValue,
+		patch.Field[string]{}, patch.Field[[]string]{}, patch.Field[uuid.UUID]{})
+	return v
+}
 
 // AuthHandler handles auth-related HTTP requests.
 type AuthHandler struct {
@@ -144,6 +157,39 @@ func bindAndValidate(c *gin.Context, dst any) error {
 		return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Invalid JSON: "+err.Error())
 	}
 
+	return validateStruct(dst)
+}
+
+// bindMergePatch binds a JSON Merge Patch (RFC 7386) body into dst, whose
+// fields are patch.Field values, and validates it. application/json is
+// accepted too for clients that cannot set a custom media type.
+func bindMergePatch(c *gin.Context, dst any) error {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		return apperror.UnsupportedMedia("Content-Type must be application/merge-patch+json")
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Failed to read request body")
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Request body is required")
+	}
+	// A non-object patch would replace the whole resource, which is never allowed.
+	if body[0] != '{' {
+		return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Merge patch must be a JSON object")
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Invalid JSON: "+err.Error())
+	}
+
+	return validateStruct(dst)
+}
+
+// validateStruct runs struct-level validation and maps failures to field errors.
+func validateStruct(dst any) error {
 	if err := validate.Struct(dst); err != nil {
 		var fieldErrors []apperror.FieldError
 		for _, fe := range err.(validator.ValidationErrors) {
@@ -163,17 +209,7 @@ func bindQueryAndValidate(c *gin.Context, dst any) error {
 	if err := c.ShouldBindQuery(dst); err != nil {
 		return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Invalid query parameters: "+err.Error())
 	}
-	if err := validate.Struct(dst); err != nil {
-		var fieldErrors []apperror.FieldError
-		for _, fe := range err.(validator.ValidationErrors) {
-			fieldErrors = append(fieldErrors, apperror.FieldError{
-				Field:   fe.Field(),
-				Message: validationMessage(fe),
-			})
-		}
-		return apperror.ValidationError(fieldErrors)
-	}
-	return nil
+	return validateStruct(dst)
 }
 
 // validationMessage produces a human-readable message for each validator tag.
@@ -187,6 +223,10 @@ func validationMessage(fe validator.FieldError) string {
 		return "Must be at least " + fe.Param() + " characters long"
 	case "max":
 		return "Must be at most " + fe.Param() + " characters long"
+	case "uuid":
+		return "Must be a valid UUID"
+	case "oneof":
+		return "Must be one of: " + fe.Param()
 	default:
 		return "Invalid value"
 	}
@@ -201,3 +241,12 @@ func mustGetUserID(c *gin.Context) interface{} {
 	}
 	return id
 }
+
+// optionalUserID returns the authenticated user's UUID, or uuid.Nil on
+// routes behind OptionalAuth that were called anonymously.
+func optionalUserID(c *gin.Context) uuid.UUID {
+	if id, exists := c.Get(middleware.ContextUserID); exists {
+		return id.(uuid.UUID)
+	}
+	return uuid.Nil
+}
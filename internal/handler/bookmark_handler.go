@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BookmarkHandler handles saved posts and bookmark collections.
+type BookmarkHandler struct {
+	bookmarkUC *usecase.BookmarkUseCase
+}
+
+func NewBookmarkHandler(bookmarkUC *usecase.BookmarkUseCase) *BookmarkHandler {
+	return &BookmarkHandler{bookmarkUC: bookmarkUC}
+}
+
+// Save godoc
+// @Summary      Bookmark post
+// @Description  Saves the post for later. Send collection_id to file it into a collection;
+// @Description  calling it again moves the bookmark. The body is optional.
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                     true   "Post UUID"
+// @Param        body  body      usecase.SaveBookmarkInput  false  "Target collection"
+// @Success      200   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Router       /posts/{id}/bookmark [put]
+func (h *BookmarkHandler) Save(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.SaveBookmarkInput
+	if c.Request.ContentLength != 0 {
+		if err := bindAndValidate(c, &input); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+
+	bookmark, ucErr := h.bookmarkUC.Save(c.Request.Context(), userID, postID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, bookmark)
+}
+
+// Remove godoc
+// @Summary      Remove bookmark
+// @Description  Un-saves the post. Idempotent.
+// @Tags         bookmarks
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Post UUID"
+// @Success      204
+// @Router       /posts/{id}/bookmark [delete]
+func (h *BookmarkHandler) Remove(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if ucErr := h.bookmarkUC.Remove(c.Request.Context(), userID, postID); ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// List godoc
+// @Summary      List my bookmarks
+// @Description  Returns the caller's saved posts, most recently saved first.
+// @Tags         bookmarks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        collection_id  query  string  false  "Only bookmarks in this collection"
+// @Param        page           query  int     false  "Page number (default: 1)"
+// @Param        per_page       query  int     false  "Items per page (default: 10, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Router       /users/me/bookmarks [get]
+func (h *BookmarkHandler) List(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.ListBookmarksInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	posts, total, err := h.bookmarkUC.List(c.Request.Context(), userID, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 10
+	}
+
+	response.OKWithMeta(c, posts, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
+
+// ListCollections godoc
+// @Summary      List bookmark collections
+// @Tags         bookmarks
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]any
+// @Router       /users/me/bookmark-collections [get]
+func (h *BookmarkHandler) ListCollections(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	collections, err := h.bookmarkUC.ListCollections(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.OK(c, collections)
+}
+
+// CreateCollection godoc
+// @Summary      Create bookmark collection
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body  body      usecase.BookmarkCollectionInput  true  "Collection name"
+// @Success      201   {object}  map[string]any
+// @Failure      409   {object}  map[string]any
+// @Router       /users/me/bookmark-collections [post]
+func (h *BookmarkHandler) CreateCollection(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.BookmarkCollectionInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	collection, err := h.bookmarkUC.CreateCollection(c.Request.Context(), userID, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Created(c, collection)
+}
+
+// RenameCollection godoc
+// @Summary      Rename bookmark collection
+// @Tags         bookmarks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                           true  "Collection UUID"
+// @Param        body  body      usecase.BookmarkCollectionInput  true  "New name"
+// @Success      200   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Failure      409   {object}  map[string]any
+// @Router       /users/me/bookmark-collections/{id} [put]
+func (h *BookmarkHandler) RenameCollection(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.BookmarkCollectionInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	collection, ucErr := h.bookmarkUC.RenameCollection(c.Request.Context(), userID, id, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, collection)
+}
+
+// DeleteCollection godoc
+// @Summary      Delete bookmark collection
+// @Description  Deletes the collection. Its bookmarks are kept and become uncategorized.
+// @Tags         bookmarks
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Collection UUID"
+// @Success      204
+// @Failure      404  {object}  map[string]any
+// @Router       /users/me/bookmark-collections/{id} [delete]
+func (h *BookmarkHandler) DeleteCollection(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if ucErr := h.bookmarkUC.DeleteCollection(c.Request.Context(), userID, id); ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.NoContent(c)
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CommentHandler handles threaded comments under posts.
+type CommentHandler struct {
+	commentUC *usecase.CommentUseCase
+}
+
+func NewCommentHandler(commentUC *usecase.CommentUseCase) *CommentHandler {
+	return &CommentHandler{commentUC: commentUC}
+}
+
+// List godoc
+// @Summary      List comments of a post
+// @Description  Returns one level of the thread: top-level comments, or the replies to ?parent_id=.
+// @Description  Each comment carries reply_count so clients can lazily expand threads.
+// @Tags         comments
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id         path   string  true   "Post UUID"
+// @Param        parent_id  query  string  false  "Comment UUID whose replies to list"
+// @Param        page       query  int     false  "Page number (default: 1)"
+// @Param        per_page   query  int     false  "Items per page (default: 20, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/comments [get]
+func (h *CommentHandler) List(c *gin.Context) {
+	viewerID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.ListCommentsInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	comments, total, ucErr := h.commentUC.List(c.Request.Context(), postID, viewerID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	response.OKWithMeta(c, comments, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
+
+// Create godoc
+// @Summary      Comment on a post
+// @Description  Adds a top-level comment, or a reply when parent_id is set.
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                      true  "Post UUID"
+// @Param        body  body      usecase.CreateCommentInput  true  "Comment payload"
+// @Success      201   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Router       /posts/{id}/comments [post]
+func (h *CommentHandler) Create(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.CreateCommentInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	comment, ucErr := h.commentUC.Create(c.Request.Context(), postID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.Created(c, comment)
+}
+
+// Update godoc
+// @Summary      Edit comment
+// @Description  Updates a comment's body. Only the comment author can edit it.
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                      true  "Comment UUID"
+// @Param        body  body      usecase.UpdateCommentInput  true  "Update payload"
+// @Success      200   {object}  map[string]any
+// @Failure      403   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Router       /comments/{id} [put]
+func (h *CommentHandler) Update(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.UpdateCommentInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	comment, ucErr := h.commentUC.Update(c.Request.Context(), id, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, comment)
+}
+
+// Delete godoc
+// @Summary      Delete comment
+// @Description  Deletes a comment and all of its replies. Allowed for the comment author and the post owner.
+// @Tags         comments
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Comment UUID"
+// @Success      204
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /comments/{id} [delete]
+func (h *CommentHandler) Delete(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if ucErr := h.commentUC.Delete(c.Request.Context(), id, userID); ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.NoContent(c)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FollowHandler handles the follow graph endpoints.
+type FollowHandler struct {
+	followUC *usecase.FollowUseCase
+}
+
+func NewFollowHandler(followUC *usecase.FollowUseCase) *FollowHandler {
+	return &FollowHandler{followUC: followUC}
+}
+
+// Follow godoc
+// @Summary      Follow user
+// @Description  Makes the caller follow the given user. Idempotent.
+// @Tags         follows
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path  string  true  "User UUID"
+// @Success      204
+// @Failure      400  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /users/{id}/follow [post]
+func (h *FollowHandler) Follow(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	targetID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if ucErr := h.followUC.Follow(c.Request.Context(), userID, targetID); ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// Unfollow godoc
+// @Summary      Unfollow user
+// @Description  Stops following the given user. Idempotent.
+// @Tags         follows
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path  string  true  "User UUID"
+// @Success      204
+// @Failure      404  {object}  map[string]any
+// @Router       /users/{id}/follow [delete]
+func (h *FollowHandler) Unfollow(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	targetID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if ucErr := h.followUC.Unfollow(c.Request.Context(), userID, targetID); ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// Followers godoc
+// @Summary      List followers
+// @Description  Returns the users following the given user, most recent first.
+// @Tags         follows
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path   string  true   "User UUID"
+// @Param        page      query  int     false  "Page number (default: 1)"
+// @Param        per_page  query  int     false  "Items per page (default: 20, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /users/{id}/followers [get]
+func (h *FollowHandler) Followers(c *gin.Context) {
+	h.list(c, h.followUC.Followers)
+}
+
+// Following godoc
+// @Summary      List followed users
+// @Description  Returns the users the given user follows, most recent first.
+// @Tags         follows
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path   string  true   "User UUID"
+// @Param        page      query  int     false  "Page number (default: 1)"
+// @Param        per_page  query  int     false  "Items per page (default: 20, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /users/{id}/following [get]
+func (h *FollowHandler) Following(c *gin.Context) {
+	h.list(c, h.followUC.Following)
+}
+
+func (h *FollowHandler) list(c *gin.Context, fetch func(ctx context.Context, userID uuid.UUID, input usecase.ListFollowsInput) ([]domain.UserPublic, int64, error)) {
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.ListFollowsInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	users, total, ucErr := fetch(c.Request.Context(), id, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	response.OKWithMeta(c, users, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GalleryHandler serves the image galleries of posts.
+type GalleryHandler struct {
+	galleryUC *usecase.GalleryUseCase
+	uploadCfg *config.UploadConfig
+}
+
+func NewGalleryHandler(galleryUC *usecase.GalleryUseCase, uploadCfg *config.UploadConfig) *GalleryHandler {
+	return &GalleryHandler{galleryUC: galleryUC, uploadCfg: uploadCfg}
+}
+
+// List godoc
+// @Summary      List post images
+// @Description  Returns the post's gallery in display order. The first image is the cover.
+// @Tags         gallery
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Post UUID"
+// @Success      200  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/images [get]
+func (h *GalleryHandler) List(c *gin.Context) {
+	viewerID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	images, ucErr := h.galleryUC.List(c.Request.Context(), postID, viewerID)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, images)
+}
+
+// Add godoc
+// @Summary      Add images to post
+// @Description  Uploads one or more images and appends them to the gallery in order. Captions and alt texts are matched to files by position. Only the owner can add images; together they must fit in the storage quota.
+// @Tags         gallery
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path      string  true   "Post UUID"
+// @Param        images    formData  file    true   "Image files (JPEG, PNG, WebP, GIF)"
+// @Param        captions  formData  string  false  "Caption per file, in file order"
+// @Param        alts      formData  string  false  "Alt text per file, in file order"
+// @Success      201  {object}  map[string]any
+// @Failure      400  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Failure      409  {object}  map[string]any
+// @Failure      413  {object}  map[string]any
+// @Failure      415  {object}  map[string]any
+// @Router       /posts/{id}/images [post]
+func (h *GalleryHandler) Add(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// Files are spooled to disk past formMemory, so only the use case holds
+	// an image in memory, one at a time.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body,
+		domain.MaxPostImages*h.uploadCfg.MaxSizeMB<<20+formOverhead)
+	if err := c.Request.ParseMultipartForm(formMemory); err != nil {
+		if isBodyTooLarge(err) {
+			_ = c.Error(apperror.FileTooLarge(h.uploadCfg.MaxSizeMB))
+			return
+		}
+		_ = c.Error(apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+			"Request must be multipart/form-data with field 'images'"))
+		return
+	}
+	form := c.Request.MultipartForm
+
+	files := form.File["images"]
+	captions := form.Value["captions"]
+	alts := form.Value["alts"]
+	if len(captions) > len(files) || len(alts) > len(files) {
+		_ = c.Error(apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+			"There are more captions or alt texts than images"))
+		return
+	}
+
+	uploads := make([]usecase.GalleryUpload, len(files))
+	for i, fh := range files {
+		upload, file, err := formUpload(fh, h.uploadCfg)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		defer file.Close()
+
+		uploads[i] = usecase.GalleryUpload{ImageUpload: upload.ImageUpload}
+		if i < len(captions) {
+			uploads[i].Caption = captions[i]
+		}
+		if i < len(alts) {
+			uploads[i].Alt = alts[i]
+		}
+	}
+
+	images, ucErr := h.galleryUC.Add(c.Request.Context(), postID, userID, uploads)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.Created(c, images)
+}
+
+// Reorder godoc
+// @Summary      Reorder post images
+// @Description  Sets the gallery order. image_ids must list every image of the post exactly once; the first becomes the cover. Only the owner can reorder.
+// @Tags         gallery
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                      true  "Post UUID"
+// @Param        body  body      usecase.ReorderImagesInput  true  "New order"
+// @Success      200   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      403   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Router       /posts/{id}/images/order [put]
+func (h *GalleryHandler) Reorder(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.ReorderImagesInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	images, ucErr := h.galleryUC.Reorder(c.Request.Context(), postID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, images)
+}
+
+// Update godoc
+// @Summary      Update image caption and alt text
+// @Description  Applies a JSON Merge Patch to a gallery entry; null clears a field. Only the owner can update it.
+// @Tags         gallery
+// @Accept       application/merge-patch+json,json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string                       true  "Post UUID"
+// @Param        imageId  path      string                       true  "Image UUID"
+// @Param        body     body      usecase.PatchPostImageInput  true  "Merge patch"
+// @Success      200      {object}  map[string]any
+// @Failure      400      {object}  map[string]any
+// @Failure      403      {object}  map[string]any
+// @Failure      404      {object}  map[string]any
+// @Failure      415      {object}  map[string]any
+// @Router       /posts/{id}/images/{imageId} [patch]
+func (h *GalleryHandler) Update(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	imageID, err := parseUUID(c, "imageId")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.PatchPostImageInput
+	if err := bindMergePatch(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	image, ucErr := h.galleryUC.Update(c.Request.Context(), postID, imageID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, image)
+}
+
+// Remove godoc
+// @Summary      Remove image from post
+// @Description  Removes an image from the gallery and deletes it unless something else uses it. Removing the cover promotes the next image. Only the owner can remove images.
+// @Tags         gallery
+// @Security     BearerAuth
+// @Param        id       path  string  true  "Post UUID"
+// @Param        imageId  path  string  true  "Image UUID"
+// @Success      204
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/images/{imageId} [delete]
+func (h *GalleryHandler) Remove(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	imageID, err := parseUUID(c, "imageId")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if ucErr := h.galleryUC.Remove(c.Request.Context(), postID, imageID, userID); ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.NoContent(c)
+}
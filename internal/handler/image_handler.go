@@ -1,31 +1,75 @@
 package handler
 
 import (
-	"github.com/acidsoft/gorestteach/internal/repository"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/usecase"
 	"github.com/acidsoft/gorestteach/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
-// ImageHandler serves image blobs from the database.
+// ImageHandler serves uploaded images and resized variants of them.
 type ImageHandler struct {
-	imageRepo repository.ImageRepository
+	imageUC *usecase.ImageUseCase
 }
 
-func NewImageHandler(imageRepo repository.ImageRepository) *ImageHandler {
-	return &ImageHandler{imageRepo: imageRepo}
+func NewImageHandler(imageUC *usecase.ImageUseCase) *ImageHandler {
+	return &ImageHandler{imageUC: imageUC}
 }
 
 // GetImage godoc
 // @Summary      Get image by ID
 // @Description  Returns the raw image bytes with the correct Content-Type header.
 //
-//	Use the image_id from user.avatar_id or post.image_id to build this URL.
+//	Use the avatar_url or image_url the API returns next to an image ID:
+//	it carries a signature (exp, kid and sig) that lets anyone holding it
+//	load the image, without a token, until it expires. Expired links are
+//	refused with 403 LINK_EXPIRED; fetch the resource again for a new one.
+//	Avatar URLs are unsigned when avatars are configured to be public.
+//
+//	Unsigned URLs built from an ID still work with a Bearer token of a user
+//	who can see the image. Images of followers-only and private posts are
+//	reported as not found to others. Without a token, unsigned requests
+//	are only served for avatars, and only while avatars are public.
+//
+//	Pass w and/or h (64, 128, 256, 512, 1024 or 2048) for a resized variant.
+//	fit=contain (default) fits the image inside the box; fit=cover fills it
+//	and crops the overflow. Images are never enlarged. Variants are sent as
+//	JPEG or PNG, whichever the Accept header prefers; JPEG sources default
+//	to JPEG and others to PNG.
+//
+//	Images never change, so responses carry a strong ETag (the SHA-256 of
+//	the bytes) and may be cached for a year, or until a signed link
+//	expires. If-None-Match and
+//	If-Modified-Since are answered with 304, Range with 206. HEAD answers
+//	from the metadata alone, and never generates a variant: for one not
+//	generated yet, it has no Content-Length or ETag.
 //
 // @Tags         images
 // @Produce      image/jpeg
-// @Param        id   path  string  true  "Image UUID"
+// @Produce      image/png
+// @Param        id     path    string  true   "Image UUID"
+// @Param        w      query   int     false  "Width of the variant"
+// @Param        h      query   int     false  "Height of the variant"
+// @Param        fit    query   string  false  "contain | cover"
+// @Param        exp    query   int     false  "Expiry of a signed URL (Unix time)"
+// @Param        kid    query   string  false  "Key ID of a signed URL"
+// @Param        sig    query   string  false  "Signature of a signed URL"
+// @Param        Range  header  string  false  "Byte range, e.g. bytes=0-1023"
 // @Success      200  {file}  binary
+// @Success      206  {file}  binary
+// @Success      304
+// @Failure      400  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
+// @Failure      416
 // @Router       /images/{id} [get]
 func (h *ImageHandler) GetImage(c *gin.Context) {
 	id, err := parseUUID(c, "id")
@@ -34,14 +78,93 @@ func (h *ImageHandler) GetImage(c *gin.Context) {
 		return
 	}
 
-	img, ucErr := h.imageRepo.GetByID(c.Request.Context(), id)
+	var input usecase.ImageVariantInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var link imageurl.Link
+	if err := bindQueryAndValidate(c, &link); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if !input.IsOriginal() {
+		c.Header("Vary", "Accept")
+	}
+
+	ctx := c.Request.Context()
+	// HEAD must not make the server resize an image.
+	open := h.imageUC.Open
+	if c.Request.Method == http.MethodHead {
+		open = h.imageUC.Stat
+	}
+	content, ucErr := open(ctx, id, optionalUserID(c), link, input, c.GetHeader("Accept"))
 	if ucErr != nil {
 		_ = c.Error(ucErr)
 		return
 	}
 
-	// Serve raw bytes with proper Content-Type — no JSON wrapper needed here
-	c.Data(200, img.ContentType, img.Data)
+	// http.ServeContent only reads the body for a full or partial GET; the
+	// size is all it needs otherwise.
+	var body io.ReadSeeker = io.NewSectionReader(unreadable{}, 0, content.Size)
+	if c.Request.Method != http.MethodHead && !notModified(c.Request, content) {
+		data, err := content.Load(ctx)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		body = bytes.NewReader(data)
+	}
+
+	c.Header("Content-Type", content.ContentType)
+	if content.SHA256 != "" {
+		c.Header("ETag", `"`+content.SHA256+`"`)
+	}
+	visibility := "public"
+	if !content.Public {
+		visibility = "private"
+	}
+	if content.Expires.IsZero() {
+		c.Header("Cache-Control", visibility+", max-age=31536000, immutable")
+	} else {
+		// The bytes never change, but the link stops working.
+		maxAge := int(time.Until(content.Expires) / time.Second)
+		c.Header("Cache-Control", visibility+", max-age="+strconv.Itoa(maxAge))
+	}
+	if content.Size < 0 {
+		// A variant not generated yet: its length and ETag are only known
+		// once a GET has made it.
+		c.Status(http.StatusOK)
+		return
+	}
+	http.ServeContent(c.Writer, c.Request, "", content.ModTime, body)
+}
+
+// notModified reports whether a GET is answered with 304 by the rules of
+// http.ServeContent, so the bytes need not be loaded: If-None-Match, when
+// present, decides on its own; otherwise If-Modified-Since.
+func notModified(r *http.Request, content *usecase.ImageContent) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if content.SHA256 == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == `"`+content.SHA256+`"` {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !content.ModTime.Truncate(time.Second).After(ims)
+}
+
+// unreadable stands in for bytes that were not loaded.
+type unreadable struct{}
+
+func (unreadable) ReadAt([]byte, int64) (int, error) {
+	return 0, errors.New("image bytes not loaded")
 }
 
 // ─── Health check handler ─────────────────────────────────────────────────────
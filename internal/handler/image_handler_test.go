@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// avatarRepo serves one avatar and counts how often its bytes are read.
+type avatarRepo struct {
+	repository.ImageRepository
+	img   domain.Image
+	loads int
+}
+
+func (r *avatarRepo) GetAvatar(_ context.Context, id uuid.UUID) (*domain.Image, error) {
+	if id != r.img.ID {
+		return nil, apperror.NotFound("Image")
+	}
+	img := r.img
+	return &img, nil
+}
+
+func (r *avatarRepo) LoadData(_ context.Context, img *domain.Image) error {
+	r.loads++
+	img.Data = r.img.Data
+	return nil
+}
+
+// variantCache keeps variants in memory and counts the ones saved.
+type variantCache struct {
+	repository.ImageVariantRepository
+	variants map[string]*domain.ImageVariant
+	saves    int
+}
+
+func (r *variantCache) Get(_ context.Context, imageID uuid.UUID, name string) (*domain.ImageVariant, error) {
+	v, ok := r.variants[imageID.String()+name]
+	if !ok {
+		return nil, apperror.NotFound("Image variant")
+	}
+	meta := *v
+	meta.Data = nil
+	return &meta, nil
+}
+
+func (r *variantCache) LoadData(_ context.Context, v *domain.ImageVariant) error {
+	v.Data = r.variants[v.ImageID.String()+v.Name].Data
+	return nil
+}
+
+func (r *variantCache) Save(_ context.Context, v *domain.ImageVariant) error {
+	r.saves++
+	r.variants[v.ImageID.String()+v.Name] = v
+	return nil
+}
+
+func newImageTestRouter(t *testing.T) (*gin.Engine, *avatarRepo, *variantCache) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 300, 200))); err != nil {
+		t.Fatal(err)
+	}
+	images := &avatarRepo{img: domain.Image{
+		ID:          uuid.New(),
+		ContentType: "image/png",
+		Size:        int64(buf.Len()),
+		SHA256:      domain.ContentHash(buf.Bytes()),
+		CreatedAt:   time.Now(),
+		Public:      true,
+		Data:        buf.Bytes(),
+	}}
+	variants := &variantCache{variants: map[string]*domain.ImageVariant{}}
+	urls := imageurl.NewSigner(&config.ImageURLConfig{PublicAvatars: true})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	h := NewImageHandler(usecase.NewImageUseCase(images, variants, urls))
+	router.GET("/images/:id", h.GetImage)
+	router.HEAD("/images/:id", h.GetImage)
+	return router, images, variants
+}
+
+func serve(router http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestHeadImageDoesNotGenerateVariants(t *testing.T) {
+	router, images, variants := newImageTestRouter(t)
+	target := "/images/" + images.img.ID.String() + "?w=64"
+
+	head := serve(router, http.MethodHead, target)
+	if head.Code != http.StatusOK {
+		t.Fatalf("HEAD status = %d, want 200", head.Code)
+	}
+	if images.loads != 0 || variants.saves != 0 {
+		t.Fatalf("HEAD read the original %d times and saved %d variants", images.loads, variants.saves)
+	}
+	if got := head.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", got)
+	}
+	for _, name := range []string{"Content-Length", "ETag"} {
+		if got := head.Header().Get(name); got != "" {
+			t.Errorf("%s = %q for a variant not generated yet", name, got)
+		}
+	}
+
+	get := serve(router, http.MethodGet, target)
+	if get.Code != http.StatusOK || variants.saves != 1 {
+		t.Fatalf("GET status = %d with %d variants saved, want 200 and 1", get.Code, variants.saves)
+	}
+
+	// Once generated, HEAD describes the variant in full.
+	head = serve(router, http.MethodHead, target)
+	if head.Code != http.StatusOK || variants.saves != 1 {
+		t.Fatalf("HEAD status = %d with %d variants saved", head.Code, variants.saves)
+	}
+	if got, want := head.Header().Get("Content-Length"), get.Header().Get("Content-Length"); got == "" || got != want {
+		t.Errorf("Content-Length = %q, want %q", got, want)
+	}
+	if got, want := head.Header().Get("ETag"), get.Header().Get("ETag"); got == "" || got != want {
+		t.Errorf("ETag = %q, want %q", got, want)
+	}
+}
+
+func TestHeadImageOriginal(t *testing.T) {
+	router, images, _ := newImageTestRouter(t)
+
+	head := serve(router, http.MethodHead, "/images/"+images.img.ID.String())
+	if head.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", head.Code)
+	}
+	if images.loads != 0 {
+		t.Errorf("HEAD read the image bytes")
+	}
+	if got, want := head.Header().Get("ETag"), `"`+images.img.SHA256+`"`; got != want {
+		t.Errorf("ETag = %q, want %q", got, want)
+	}
+}
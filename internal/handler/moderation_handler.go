@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ModerationHandler serves abuse reports and the staff moderation queue.
+type ModerationHandler struct {
+	moderationUC *usecase.ModerationUseCase
+}
+
+func NewModerationHandler(moderationUC *usecase.ModerationUseCase) *ModerationHandler {
+	return &ModerationHandler{moderationUC: moderationUC}
+}
+
+// ReportPost godoc
+// @Summary      Report post
+// @Description  Reports a post for review by moderators. Each user can report a post once.
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string               true  "Post UUID"
+// @Param        body  body      usecase.ReportInput  true  "Report payload"
+// @Success      201   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Failure      409   {object}  map[string]any
+// @Router       /posts/{id}/report [post]
+func (h *ModerationHandler) ReportPost(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.ReportInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	report, ucErr := h.moderationUC.ReportPost(c.Request.Context(), postID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.Created(c, report)
+}
+
+// ReportComment godoc
+// @Summary      Report comment
+// @Description  Reports a comment for review by moderators. Each user can report a comment once.
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string               true  "Comment UUID"
+// @Param        body  body      usecase.ReportInput  true  "Report payload"
+// @Success      201   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Failure      409   {object}  map[string]any
+// @Router       /comments/{id}/report [post]
+func (h *ModerationHandler) ReportComment(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	commentID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.ReportInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	report, ucErr := h.moderationUC.ReportComment(c.Request.Context(), commentID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.Created(c, report)
+}
+
+// ListReports godoc
+// @Summary      List reports
+// @Description  Returns the moderation queue, oldest first. Moderators and admins only.
+// @Tags         moderation
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status       query  string  false  "open (default), dismissed, actioned or all"
+// @Param        target_type  query  string  false  "post or comment"
+// @Param        page         query  int     false  "Page number (default: 1)"
+// @Param        per_page     query  int     false  "Items per page (default: 20, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Router       /admin/reports [get]
+func (h *ModerationHandler) ListReports(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.ListReportsInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	reports, total, ucErr := h.moderationUC.ListReports(c.Request.Context(), userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	response.OKWithMeta(c, reports, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
+
+// GetReport godoc
+// @Summary      Get report
+// @Description  Returns a report, including a snapshot of the content as it was when reported. Moderators and admins only.
+// @Tags         moderation
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Report UUID"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /admin/reports/{id} [get]
+func (h *ModerationHandler) GetReport(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	reportID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	report, ucErr := h.moderationUC.GetReport(c.Request.Context(), reportID, userID)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, report)
+}
+
+// Resolve godoc
+// @Summary      Resolve report
+// @Description  Dismisses the report, removes the reported content, or removes it and suspends its author.
+// @Description  Closes every open report on the same content. Moderators and admins only.
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                      true  "Report UUID"
+// @Param        body  body      usecase.ResolveReportInput  true  "Decision"
+// @Success      200   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      403   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Failure      409   {object}  map[string]any
+// @Router       /admin/reports/{id}/resolve [post]
+func (h *ModerationHandler) Resolve(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	reportID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.ResolveReportInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	report, ucErr := h.moderationUC.Resolve(c.Request.Context(), reportID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, report)
+}
+
+// ListAudit godoc
+// @Summary      List moderation audit log
+// @Description  Returns moderation actions, newest first. Moderators and admins only.
+// @Tags         moderation
+// @Produce      json
+// @Security     BearerAuth
+// @Param        target_id  query  string  false  "Only entries about this post or comment"
+// @Param        page       query  int     false  "Page number (default: 1)"
+// @Param        per_page   query  int     false  "Items per page (default: 50, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Router       /admin/audit-log [get]
+func (h *ModerationHandler) ListAudit(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.ListAuditInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	entries, total, ucErr := h.moderationUC.ListAudit(c.Request.Context(), userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 50
+	}
+
+	response.OKWithMeta(c, entries, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// NotificationHandler serves the authenticated user's notification stream.
+type NotificationHandler struct {
+	notificationUC *usecase.NotificationUseCase
+}
+
+func NewNotificationHandler(notificationUC *usecase.NotificationUseCase) *NotificationHandler {
+	return &NotificationHandler{notificationUC: notificationUC}
+}
+
+// List godoc
+// @Summary      List my notifications
+// @Description  Returns the user's notifications, newest first. meta.unread counts all unread notifications.
+// @Tags         notifications
+// @Produce      json
+// @Security     BearerAuth
+// @Param        unread    query  bool  false  "Only unread notifications"
+// @Param        page      query  int   false  "Page number (default: 1)"
+// @Param        per_page  query  int   false  "Items per page (default: 20, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Router       /users/me/notifications [get]
+func (h *NotificationHandler) List(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.ListNotificationsInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	notifications, total, unread, ucErr := h.notificationUC.List(c.Request.Context(), userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	response.OKWithInbox(c, notifications, response.InboxMeta{
+		PaginationMeta: response.PaginationMeta{
+			Page:    page,
+			PerPage: perPage,
+			Total:   total,
+		},
+		Unread: unread,
+	})
+}
+
+// MarkRead godoc
+// @Summary      Mark notifications as read
+// @Description  Marks the given notifications as read, or all of them when ids is empty.
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body  body      usecase.MarkNotificationsReadInput  true  "Notification IDs"
+// @Success      200   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Router       /users/me/notifications/read [post]
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.MarkNotificationsReadInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	marked, ucErr := h.notificationUC.MarkRead(c.Request.Context(), userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, gin.H{"marked": marked})
+}
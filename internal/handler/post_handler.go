@@ -1,23 +1,28 @@
 package handler
 
 import (
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
 	"github.com/acidsoft/gorestteach/internal/usecase"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
 	"github.com/acidsoft/gorestteach/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 )
 
 // PostHandler handles post CRUD and image attachment endpoints.
 type PostHandler struct {
-	postUC *usecase.PostUseCase
+	postUC    *usecase.PostUseCase
+	uploadCfg *config.UploadConfig
 }
 
-func NewPostHandler(postUC *usecase.PostUseCase) *PostHandler {
-	return &PostHandler{postUC: postUC}
+func NewPostHandler(postUC *usecase.PostUseCase, uploadCfg *config.UploadConfig) *PostHandler {
+	return &PostHandler{postUC: postUC, uploadCfg: uploadCfg}
 }
 
 // Create godoc
@@ -46,28 +51,40 @@ func (h *PostHandler) Create(c *gin.Context) {
 		return
 	}
 
+	setPostETag(c, post)
 	response.Created(c, post)
 }
 
 // List godoc
 // @Summary      List posts
-// @Description  Returns a paginated list of posts. Supports ?page=1&per_page=10&search=keyword
+// @Description  Returns a paginated list of posts. Supports ?page=1&per_page=10&search=keyword,
+// @Description  filters (user_id, created_from/to, updated_from/to, has_image, tag) and ?sort=-created_at,title
 // @Tags         posts
 // @Produce      json
 // @Security     BearerAuth
-// @Param        page      query   int     false  "Page number (default: 1)"
-// @Param        per_page  query   int     false  "Items per page (default: 10, max: 100)"
-// @Param        search    query   string  false  "Search keyword in title/body"
+// @Param        page          query   int     false  "Page number (default: 1)"
+// @Param        per_page      query   int     false  "Items per page (default: 10, max: 100)"
+// @Param        search        query   string  false  "Search keyword in title/body"
+// @Param        user_id       query   string  false  "Only posts by this author (UUID)"
+// @Param        created_from  query   string  false  "RFC3339 lower bound on created_at"
+// @Param        created_to    query   string  false  "RFC3339 upper bound on created_at"
+// @Param        updated_from  query   string  false  "RFC3339 lower bound on updated_at"
+// @Param        updated_to    query   string  false  "RFC3339 upper bound on updated_at"
+// @Param        has_image     query   bool    false  "Only posts with (true) or without (false) an image"
+// @Param        tag           query   string  false  "Only posts carrying this tag (slug); needs a letter or digit"
+// @Param        sort          query   string  false  "Comma-separated fields, '-' prefix for DESC (created_at, updated_at, title)"
 // @Success      200  {object}  map[string]any
 // @Router       /posts [get]
 func (h *PostHandler) List(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
 	var input usecase.ListPostsInput
 	if err := bindQueryAndValidate(c, &input); err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	posts, total, err := h.postUC.List(c.Request.Context(), input)
+	posts, total, err := h.postUC.List(c.Request.Context(), userID, input)
 	if err != nil {
 		_ = c.Error(err)
 		return
@@ -91,7 +108,8 @@ func (h *PostHandler) List(c *gin.Context) {
 
 // GetByID godoc
 // @Summary      Get post by ID
-// @Description  Returns a single post with the author's info.
+// @Description  Returns a single post with the author's info. The ETag header carries the post
+// @Description  version; send it back as If-Match on PUT/DELETE to avoid overwriting concurrent edits.
 // @Tags         posts
 // @Produce      json
 // @Security     BearerAuth
@@ -100,33 +118,77 @@ func (h *PostHandler) List(c *gin.Context) {
 // @Failure      404  {object}  map[string]any
 // @Router       /posts/{id} [get]
 func (h *PostHandler) GetByID(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
 	id, err := parseUUID(c, "id")
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	post, ucErr := h.postUC.GetByID(c.Request.Context(), id)
+	post, ucErr := h.postUC.GetByID(c.Request.Context(), id, userID)
 	if ucErr != nil {
 		_ = c.Error(ucErr)
 		return
 	}
 
+	setPostETag(c, post)
 	response.OK(c, post)
 }
 
+// Feed godoc
+// @Summary      Home feed
+// @Description  Returns posts from users the caller follows, newest first.
+// @Description  Keyset-paginated: pass meta.next_cursor back as ?cursor= for the next page.
+// @Tags         posts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        cursor  query  string  false  "Opaque cursor from the previous page"
+// @Param        limit   query  int     false  "Items per page (default: 20, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Failure      400  {object}  map[string]any
+// @Router       /feed [get]
+func (h *PostHandler) Feed(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.FeedInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	posts, next, err := h.postUC.Feed(c.Request.Context(), userID, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	limit := input.Limit
+	if limit < 1 {
+		limit = 20
+	}
+
+	response.OKWithCursor(c, posts, response.CursorMeta{
+		Limit:      limit,
+		NextCursor: next,
+	})
+}
+
 // Update godoc
 // @Summary      Update post
 // @Description  Updates a post. Only the post owner can update it.
+// @Description  With If-Match the update only applies if the post is still at that ETag.
 // @Tags         posts
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id    path      string                   true  "Post UUID"
-// @Param        body  body      usecase.UpdatePostInput  true  "Update payload"
-// @Success      200   {object}  map[string]any
-// @Failure      403   {object}  map[string]any
-// @Failure      404   {object}  map[string]any
+// @Param        id        path      string                   true   "Post UUID"
+// @Param        If-Match  header    string                   false  "ETag from a previous read"
+// @Param        body      body      usecase.UpdatePostInput  true   "Update payload"
+// @Success      200       {object}  map[string]any
+// @Failure      403       {object}  map[string]any
+// @Failure      404       {object}  map[string]any
+// @Failure      412       {object}  map[string]any
 // @Router       /posts/{id} [put]
 func (h *PostHandler) Update(c *gin.Context) {
 	userID := mustGetUserID(c).(uuid.UUID)
@@ -137,31 +199,91 @@ func (h *PostHandler) Update(c *gin.Context) {
 		return
 	}
 
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	var input usecase.UpdatePostInput
 	if err := bindAndValidate(c, &input); err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	post, ucErr := h.postUC.Update(c.Request.Context(), id, userID, input)
+	post, ucErr := h.postUC.Update(c.Request.Context(), id, userID, input, ifMatch)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	setPostETag(c, post)
+	response.OK(c, post)
+}
+
+// Patch godoc
+// @Summary      Patch post
+// @Description  Applies a JSON Merge Patch (RFC 7386): absent members are kept, null removes
+// @Description  (image_id, tags) and values replace. Only the post owner can patch it.
+// @Tags         posts
+// @Accept       application/merge-patch+json,json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path      string                  true   "Post UUID"
+// @Param        If-Match  header    string                  false  "ETag from a previous read"
+// @Param        body      body      usecase.PatchPostInput  true   "Merge patch document"
+// @Success      200       {object}  map[string]any
+// @Failure      400       {object}  map[string]any
+// @Failure      403       {object}  map[string]any
+// @Failure      404       {object}  map[string]any
+// @Failure      412       {object}  map[string]any
+// @Failure      415       {object}  map[string]any
+// @Router       /posts/{id} [patch]
+func (h *PostHandler) Patch(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.PatchPostInput
+	if err := bindMergePatch(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	post, ucErr := h.postUC.Patch(c.Request.Context(), id, userID, input, ifMatch)
 	if ucErr != nil {
 		_ = c.Error(ucErr)
 		return
 	}
 
+	setPostETag(c, post)
 	response.OK(c, post)
 }
 
 // Delete godoc
 // @Summary      Delete post
-// @Description  Deletes a post. Only the post owner can delete it.
+// @Description  Moves a post to the trash. Only the post owner can delete it.
+// @Description  It can be restored until the retention window expires.
+// @Description  With If-Match the delete only applies if the post is still at that ETag.
 // @Tags         posts
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id   path  string  true  "Post UUID"
+// @Param        id        path    string  true   "Post UUID"
+// @Param        If-Match  header  string  false  "ETag from a previous read"
 // @Success      204
-// @Failure      403  {object}  map[string]any
-// @Failure      404  {object}  map[string]any
+// @Failure      403       {object}  map[string]any
+// @Failure      404       {object}  map[string]any
+// @Failure      412       {object}  map[string]any
 // @Router       /posts/{id} [delete]
 func (h *PostHandler) Delete(c *gin.Context) {
 	userID := mustGetUserID(c).(uuid.UUID)
@@ -172,7 +294,13 @@ func (h *PostHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if ucErr := h.postUC.Delete(c.Request.Context(), id, userID); ucErr != nil {
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if ucErr := h.postUC.Delete(c.Request.Context(), id, userID, ifMatch); ucErr != nil {
 		_ = c.Error(ucErr)
 		return
 	}
@@ -180,17 +308,91 @@ func (h *PostHandler) Delete(c *gin.Context) {
 	response.NoContent(c)
 }
 
+// ListTrash godoc
+// @Summary      List my trash
+// @Description  Returns the caller's deleted posts that can still be restored, most recently deleted first.
+// @Tags         posts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page      query  int  false  "Page number (default: 1)"
+// @Param        per_page  query  int  false  "Items per page (default: 10, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Router       /users/me/trash [get]
+func (h *PostHandler) ListTrash(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.ListTrashInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	posts, total, err := h.postUC.ListTrash(c.Request.Context(), userID, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 10
+	}
+
+	response.OKWithMeta(c, posts, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
+
+// Restore godoc
+// @Summary      Restore post
+// @Description  Moves a deleted post out of the trash. Only the post owner can restore it.
+// @Tags         posts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Post UUID"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/restore [post]
+func (h *PostHandler) Restore(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	post, ucErr := h.postUC.Restore(c.Request.Context(), id, userID)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	setPostETag(c, post)
+	response.OK(c, post)
+}
+
 // AttachImage godoc
 // @Summary      Attach image to post
-// @Description  Uploads an image and attaches it to the post. Only the owner can attach images.
+// @Description  Uploads an image and attaches it to the post. Only the owner can attach images, within their storage quota. Instead of a file, a JSON body can name a complete resumable upload.
 // @Tags         posts
-// @Accept       multipart/form-data
+// @Accept       multipart/form-data,json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id     path      string  true  "Post UUID"
-// @Param        image  formData  file    true  "Image file (JPEG, PNG, WebP, GIF)"
+// @Param        id     path      string                   true   "Post UUID"
+// @Param        image  formData  file                     false  "Image file (JPEG, PNG, WebP, GIF)"
+// @Param        body   body      usecase.FromUploadInput  false  "Resumable upload to use instead"
 // @Success      200    {object}  map[string]any
 // @Failure      403    {object}  map[string]any
+// @Failure      404    {object}  map[string]any
+// @Failure      409    {object}  map[string]any
 // @Failure      413    {object}  map[string]any
 // @Failure      415    {object}  map[string]any
 // @Router       /posts/{id}/image [post]
@@ -203,33 +405,63 @@ func (h *PostHandler) AttachImage(c *gin.Context) {
 		return
 	}
 
-	fileHeader, err := c.FormFile("image")
-	if err != nil {
-		_ = c.Error(apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
-			"Field 'image' with image file is required"))
+	if c.ContentType() == binding.MIMEJSON {
+		var input usecase.FromUploadInput
+		if err := bindAndValidate(c, &input); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		post, err := h.postUC.AttachUpload(c.Request.Context(), id, userID, input.UploadID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		setPostETag(c, post)
+		response.OK(c, post)
 		return
 	}
 
-	file, err := fileHeader.Open()
+	upload, err := streamUpload(c, h.uploadCfg, "image")
 	if err != nil {
-		_ = c.Error(apperror.Internal(err))
+		_ = c.Error(err)
 		return
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
-	if err != nil {
-		_ = c.Error(apperror.Internal(err))
+	post, ucErr := h.postUC.AttachImage(c.Request.Context(), id, userID, upload.ImageUpload)
+	if ucErr != nil {
+		_ = c.Error(upload.err(ucErr))
 		return
 	}
 
-	contentType := http.DetectContentType(data)
+	setPostETag(c, post)
+	response.OK(c, post)
+}
 
-	post, ucErr := h.postUC.AttachImage(c.Request.Context(), id, userID, data, contentType)
-	if ucErr != nil {
-		_ = c.Error(ucErr)
-		return
+// setPostETag exposes the post version as a strong entity tag.
+func setPostETag(c *gin.Context, post *domain.Post) {
+	c.Header("ETag", `"`+strconv.FormatInt(post.Version, 10)+`"`)
+}
+
+// parseIfMatch reads the post version a write is conditional on. A missing
+// header or "*" means unconditional (nil). Only a single strong ETag as issued
+// by setPostETag is accepted; weak tags never match under If-Match.
+func parseIfMatch(c *gin.Context) (*int64, error) {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" || raw == "*" {
+		return nil, nil
 	}
 
-	response.OK(c, post)
+	if strings.HasPrefix(raw, "W/") {
+		return nil, apperror.PreconditionFailed("Weak ETags cannot be used with If-Match")
+	}
+	invalid := apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+		"If-Match must be a single ETag returned by this API")
+	if len(raw) < 3 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return nil, invalid
+	}
+	version, err := strconv.ParseInt(raw[1:len(raw)-1], 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	return &version, nil
 }
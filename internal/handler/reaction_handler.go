@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReactionHandler handles likes/emoji reactions on posts.
+type ReactionHandler struct {
+	reactionUC *usecase.ReactionUseCase
+}
+
+func NewReactionHandler(reactionUC *usecase.ReactionUseCase) *ReactionHandler {
+	return &ReactionHandler{reactionUC: reactionUC}
+}
+
+// Set godoc
+// @Summary      React to a post
+// @Description  Sets the caller's reaction (like, love, laugh, wow, sad, angry). Replaces any previous reaction.
+// @Tags         reactions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string                    true  "Post UUID"
+// @Param        body  body      usecase.SetReactionInput  true  "Reaction payload"
+// @Success      200   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      404   {object}  map[string]any
+// @Router       /posts/{id}/reactions [put]
+func (h *ReactionHandler) Set(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.SetReactionInput
+	if err := bindAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	summary, ucErr := h.reactionUC.Set(c.Request.Context(), postID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, summary)
+}
+
+// Remove godoc
+// @Summary      Remove reaction
+// @Description  Removes the caller's reaction from the post. Idempotent.
+// @Tags         reactions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Post UUID"
+// @Success      200  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/reactions [delete]
+func (h *ReactionHandler) Remove(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	summary, ucErr := h.reactionUC.Remove(c.Request.Context(), postID, userID)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, summary)
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RevisionHandler serves a post's revision history.
+type RevisionHandler struct {
+	revisionUC *usecase.RevisionUseCase
+}
+
+func NewRevisionHandler(revisionUC *usecase.RevisionUseCase) *RevisionHandler {
+	return &RevisionHandler{revisionUC: revisionUC}
+}
+
+// List godoc
+// @Summary      List post revisions
+// @Description  Returns the post's revisions (without bodies), newest first. Author only.
+// @Tags         revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path   string  true   "Post UUID"
+// @Param        page      query  int     false  "Page number (default: 1)"
+// @Param        per_page  query  int     false  "Items per page (default: 20, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/revisions [get]
+func (h *RevisionHandler) List(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var input usecase.ListRevisionsInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	revisions, total, ucErr := h.revisionUC.List(c.Request.Context(), postID, userID, input)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	response.OKWithMeta(c, revisions, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
+
+// Get godoc
+// @Summary      Get post revision
+// @Description  Returns a revision and a line-level diff from it to the current version. Author only.
+// @Tags         revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Post UUID"
+// @Param        rev  path      int     true  "Revision number"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/revisions/{rev} [get]
+func (h *RevisionHandler) Get(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	rev, err := parseRevision(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	diff, ucErr := h.revisionUC.Get(c.Request.Context(), postID, userID, rev)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	response.OK(c, diff)
+}
+
+// Restore godoc
+// @Summary      Restore post revision
+// @Description  Rolls the post back to the revision's title and body, recorded as a new revision. Author only.
+// @Tags         revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Post UUID"
+// @Param        rev  path      int     true  "Revision number"
+// @Success      200  {object}  map[string]any
+// @Failure      403  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /posts/{id}/revisions/{rev}/restore [post]
+func (h *RevisionHandler) Restore(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	postID, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	rev, err := parseRevision(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	post, ucErr := h.revisionUC.Restore(c.Request.Context(), postID, userID, rev)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	setPostETag(c, post)
+	response.OK(c, post)
+}
+
+// parseRevision parses the positive :rev path parameter.
+func parseRevision(c *gin.Context) (int, error) {
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev < 1 {
+		return 0, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+			"Revision must be a positive integer")
+	}
+	return rev, nil
+}
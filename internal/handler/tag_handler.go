@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TagHandler handles tag browsing endpoints.
+type TagHandler struct {
+	tagUC *usecase.TagUseCase
+}
+
+func NewTagHandler(tagUC *usecase.TagUseCase) *TagHandler {
+	return &TagHandler{tagUC: tagUC}
+}
+
+// List godoc
+// @Summary      List tags
+// @Description  Returns tags ordered by usage. Use ?prefix= for autocomplete.
+// @Tags         tags
+// @Produce      json
+// @Security     BearerAuth
+// @Param        prefix    query  string  false  "Slug prefix, e.g. 'flu' matches 'flutter'"
+// @Param        page      query  int     false  "Page number (default: 1)"
+// @Param        per_page  query  int     false  "Items per page (default: 10, max: 100)"
+// @Success      200  {object}  map[string]any
+// @Router       /tags [get]
+func (h *TagHandler) List(c *gin.Context) {
+	var input usecase.ListTagsInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tags, total, err := h.tagUC.List(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 10
+	}
+
+	response.OKWithMeta(c, tags, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
+
+// ListPosts godoc
+// @Summary      List posts by tag
+// @Description  Returns a paginated list of posts carrying the tag. Accepts the same query parameters as GET /posts.
+// @Tags         tags
+// @Produce      json
+// @Security     BearerAuth
+// @Param        slug  path  string  true  "Tag slug"
+// @Success      200  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Router       /tags/{slug}/posts [get]
+func (h *TagHandler) ListPosts(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.ListPostsInput
+	if err := bindQueryAndValidate(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	posts, total, err := h.tagUC.ListPosts(c.Request.Context(), userID, c.Param("slug"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 10
+	}
+
+	response.OKWithMeta(c, posts, response.PaginationMeta{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
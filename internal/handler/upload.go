@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sniffLen is how much of a file http.DetectContentType looks at.
+	sniffLen = 512
+	// formOverhead bounds the rest of a multipart request besides its files:
+	// part headers, captions and the like.
+	formOverhead = 1 << 20
+	// formMemory is how much of a multi-file form is kept in memory; files
+	// beyond it are spooled to temporary files.
+	formMemory = 1 << 20
+)
+
+// upload is an image file streamed from a multipart request.
+type upload struct {
+	usecase.ImageUpload
+	limit *limitedReader
+}
+
+// streamUpload returns the file in the given field of a multipart request
+// without reading it: the use case reads it as it is stored, and fails once
+// the file exceeds the configured size. The content type is sniffed from
+// the first bytes. Fields before the file are skipped; fields after it are
+// never read.
+func streamUpload(c *gin.Context, cfg *config.UploadConfig, field string) (*upload, error) {
+	missing := apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+		"Field '"+field+"' with image file is required")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxSizeMB<<20+formOverhead)
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, missing
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if isBodyTooLarge(err) {
+				return nil, apperror.FileTooLarge(cfg.MaxSizeMB)
+			}
+			return nil, missing
+		}
+		if part.FormName() == field && part.FileName() != "" {
+			return newUpload(part, cfg.MaxSizeMB)
+		}
+	}
+}
+
+// formUpload returns a file of a parsed multipart form, rejecting it
+// up front if it exceeds the configured size.
+func formUpload(fh *multipart.FileHeader, cfg *config.UploadConfig) (*upload, io.Closer, error) {
+	if fh.Size > cfg.MaxSizeMB<<20 {
+		return nil, nil, apperror.FileTooLarge(cfg.MaxSizeMB)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return nil, nil, apperror.Internal(err)
+	}
+	u, err := newUpload(file, cfg.MaxSizeMB)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return u, file, nil
+}
+
+func newUpload(r io.Reader, maxMB int64) (*upload, error) {
+	limit := &limitedReader{r: r, left: maxMB << 20, maxMB: maxMB}
+	br := bufio.NewReaderSize(limit, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		if isBodyTooLarge(err) {
+			return nil, apperror.FileTooLarge(maxMB)
+		}
+		return nil, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Upload could not be read")
+	}
+	return &upload{
+		ImageUpload: usecase.ImageUpload{Body: br, ContentType: http.DetectContentType(head)},
+		limit:       limit,
+	}, nil
+}
+
+// err returns the error to report for a use case that read the upload and
+// failed with err: reading past the size limit surfaces there as a broken
+// image or a storage failure.
+func (u *upload) err(err error) error {
+	if u.limit.exceeded {
+		return apperror.FileTooLarge(u.limit.maxMB)
+	}
+	return err
+}
+
+// limitedReader fails once more than left bytes are read from r.
+type limitedReader struct {
+	r        io.Reader
+	left     int64
+	maxMB    int64
+	exceeded bool
+}
+
+var errUploadTooLarge = errors.New("upload exceeds the size limit")
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errUploadTooLarge
+	}
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1] // one more byte tells whether the file goes on
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.left {
+		n, err, l.exceeded = int(l.left), errUploadTooLarge, true
+	}
+	l.left -= int64(n)
+	if isBodyTooLarge(err) {
+		l.exceeded = true
+	}
+	return n, err
+}
+
+// isBodyTooLarge reports whether err comes from http.MaxBytesReader.
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// tusVersion is the version of the tus protocol spoken at /uploads.
+	tusVersion = "1.0.0"
+	// tusExtensions are the protocol extensions supported.
+	tusExtensions = "creation,termination,expiration"
+	// tusContentType is the content type of the chunks sent with PATCH.
+	tusContentType = "application/offset+octet-stream"
+)
+
+// UploadHandler handles resumable uploads, following the tus protocol
+// (https://tus.io/protocols/resumable-upload) so that its client libraries
+// can be used. A complete upload is attached by sending its ID to
+// POST /users/me/avatar or POST /posts/:id/image.
+type UploadHandler struct {
+	uploadUC *usecase.UploadUseCase
+}
+
+func NewUploadHandler(uploadUC *usecase.UploadUseCase) *UploadHandler {
+	return &UploadHandler{uploadUC: uploadUC}
+}
+
+// Options godoc
+// @Summary      Describe resumable uploads
+// @Description  Lists the tus protocol versions and extensions supported, and the largest upload accepted. No authentication required.
+// @Tags         uploads
+// @Success      204
+// @Router       /uploads [options]
+func (h *UploadHandler) Options(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.uploadUC.MaxLength(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// Create godoc
+// @Summary      Start a resumable upload
+// @Description  Starts an upload of a file of Upload-Length bytes, to be sent with PATCH to the returned Location. Files that would not fit in the storage quota are refused upfront. Uploads not completed in time expire.
+// @Tags         uploads
+// @Produce      json
+// @Security     BearerAuth
+// @Param        Tus-Resumable    header    string  true   "1.0.0"
+// @Param        Upload-Length    header    int     true   "Size of the file in bytes"
+// @Param        Upload-Metadata  header    string  false  "tus metadata, returned as sent"
+// @Success      201              {object}  map[string]any
+// @Failure      400              {object}  map[string]any
+// @Failure      412              {object}  map[string]any
+// @Failure      413              {object}  map[string]any
+// @Router       /uploads [post]
+func (h *UploadHandler) Create(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	if err := tusRequest(c); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if c.GetHeader("Upload-Length") == "" && c.GetHeader("Upload-Defer-Length") != "" {
+		_ = c.Error(apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+			"Upload-Defer-Length is not supported; send Upload-Length"))
+		return
+	}
+	length, err := parseTusOffset(c, "Upload-Length")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	upload, ucErr := h.uploadUC.Create(c.Request.Context(), userID, usecase.CreateUploadInput{
+		Length:   length,
+		Metadata: c.GetHeader("Upload-Metadata"),
+	})
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID.String())
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	response.Created(c, upload)
+}
+
+// Head godoc
+// @Summary      Get the progress of a resumable upload
+// @Description  Returns in Upload-Offset how many bytes of the upload have been received, which is where the next PATCH must start.
+// @Tags         uploads
+// @Security     BearerAuth
+// @Param        id             path    string  true  "Upload UUID"
+// @Param        Tus-Resumable  header  string  true  "1.0.0"
+// @Success      200
+// @Failure      404
+// @Failure      412
+// @Router       /uploads/{id} [head]
+func (h *UploadHandler) Head(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	if err := tusRequest(c); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	upload, ucErr := h.uploadUC.Get(c.Request.Context(), userID, id)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Metadata != "" {
+		c.Header("Upload-Metadata", upload.Metadata)
+	}
+	setUploadProgress(c, upload)
+	c.Status(http.StatusOK)
+}
+
+// Patch godoc
+// @Summary      Send part of a resumable upload
+// @Description  Appends the request body to the upload. Upload-Offset must be the upload's current offset. If the connection breaks, the bytes received so far are kept: ask for the offset with HEAD and continue from there.
+// @Tags         uploads
+// @Accept       application/offset+octet-stream
+// @Security     BearerAuth
+// @Param        id             path    string  true  "Upload UUID"
+// @Param        Tus-Resumable  header  string  true  "1.0.0"
+// @Param        Upload-Offset  header  int     true  "Offset the body starts at"
+// @Success      204
+// @Failure      400  {object}  map[string]any
+// @Failure      404  {object}  map[string]any
+// @Failure      409  {object}  map[string]any
+// @Failure      412  {object}  map[string]any
+// @Failure      413  {object}  map[string]any
+// @Failure      415  {object}  map[string]any
+// @Router       /uploads/{id} [patch]
+func (h *UploadHandler) Patch(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	if err := tusRequest(c); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if c.ContentType() != tusContentType {
+		_ = c.Error(apperror.UnsupportedMedia("Content-Type must be " + tusContentType))
+		return
+	}
+	offset, err := parseTusOffset(c, "Upload-Offset")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// The request is canceled when the client goes away, which is when the
+	// bytes it did send matter most.
+	ctx := context.WithoutCancel(c.Request.Context())
+	upload, ucErr := h.uploadUC.Append(ctx, userID, id, offset, partialBody{c.Request.Body}, c.Request.ContentLength)
+	if ucErr != nil {
+		_ = c.Error(ucErr)
+		return
+	}
+
+	setUploadProgress(c, upload)
+	c.Status(http.StatusNoContent)
+}
+
+// Terminate godoc
+// @Summary      Cancel a resumable upload
+// @Description  Deletes the upload and the bytes received for it.
+// @Tags         uploads
+// @Security     BearerAuth
+// @Param        id             path    string  true  "Upload UUID"
+// @Param        Tus-Resumable  header  string  true  "1.0.0"
+// @Success      204
+// @Failure      404  {object}  map[string]any
+// @Failure      412  {object}  map[string]any
+// @Router       /uploads/{id} [delete]
+func (h *UploadHandler) Terminate(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	if err := tusRequest(c); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	id, err := parseUUID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.uploadUC.Terminate(c.Request.Context(), userID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// tusRequest marks the response as speaking the tus protocol, and rejects
+// requests for another version of it.
+func tusRequest(c *gin.Context) error {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		return apperror.PreconditionFailed("Tus-Resumable must be " + tusVersion)
+	}
+	return nil
+}
+
+// parseTusOffset parses a header holding a size or offset in bytes.
+func parseTusOffset(c *gin.Context, header string) (int64, error) {
+	n, err := strconv.ParseInt(c.GetHeader(header), 10, 64)
+	if err != nil || n < 0 {
+		return 0, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+			header+" must be a non-negative integer")
+	}
+	return n, nil
+}
+
+func setUploadProgress(c *gin.Context, upload *domain.Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// partialBody ends a request body where the client stopped sending it, as
+// if it were complete, so that the part of a chunk that arrived is kept.
+type partialBody struct {
+	r io.Reader
+}
+
+func (b partialBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math/rand/v2"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/middleware"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/internal/storage"
+	"github.com/acidsoft/gorestteach/internal/usecase"
+	"github.com/acidsoft/gorestteach/pkg/imaging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// blobImageRepo stores the bytes of images like the real repository does
+// with a filesystem primary backend, without recording them in a database.
+type blobImageRepo struct {
+	repository.ImageRepository
+	store storage.BlobStore
+}
+
+func (r *blobImageRepo) Save(ctx context.Context, img *domain.Image) error {
+	img.ID = uuid.New()
+	h := sha256.New()
+	if err := r.store.Put(ctx, img.ID.String(), io.TeeReader(img.Body, h), -1, img.ContentType); err != nil {
+		return err
+	}
+	img.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+type anyUserRepo struct{ repository.UserRepository }
+
+func (anyUserRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	return &domain.User{ID: id}, nil
+}
+
+func (anyUserRepo) UpdateAvatar(context.Context, uuid.UUID, uuid.UUID) error { return nil }
+
+type noFollowRepo struct{ repository.FollowRepository }
+
+func (noFollowRepo) Counts(context.Context, uuid.UUID) (int64, int64, error) { return 0, 0, nil }
+
+// bufferedUpload handles an avatar upload the way it was done before
+// streaming: the form is parsed with Gin's default memory budget, the file
+// read whole, decoded, re-encoded into a buffer and only then stored.
+func bufferedUpload(store storage.BlobStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fh, err := c.FormFile("avatar")
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		file, err := fh.Open()
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		contentType := http.DetectContentType(data)
+		img, err := imaging.Decode(data, contentType)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnsupportedMediaType)
+			return
+		}
+		var clean bytes.Buffer
+		if err := jpeg.Encode(&clean, img, &jpeg.Options{Quality: 92}); err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		_ = domain.ContentHash(clean.Bytes())
+		err = store.Put(c.Request.Context(), uuid.NewString(), bytes.NewReader(clean.Bytes()), int64(clean.Len()), contentType)
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+}
+
+// noisePhoto returns a JPEG of random pixels, which compresses about as
+// badly as a detailed photo.
+func noisePhoto(b *testing.B, w, h int) []byte {
+	b.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewPCG(1, 2))
+	for y := range h {
+		for x := range w {
+			v := uint8(rng.IntN(256))
+			img.SetRGBA(x, y, color.RGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92}); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// peakHeap calls fn and returns the peak heap in use above what it was
+// before.
+func peakHeap(fn func()) uint64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	base := ms.HeapInuse
+
+	var peak uint64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(2 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			if ms.HeapInuse > base && ms.HeapInuse-base > peak {
+				peak = ms.HeapInuse - base
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	fn()
+	close(done)
+	wg.Wait()
+	return peak
+}
+
+// postAvatar posts photo as a multipart avatar. The request body is
+// generated as it is sent, so it does not count towards the peak.
+func postAvatar(url string, photo []byte) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		fw, err := mw.CreateFormFile("avatar", "photo.jpg")
+		if err == nil {
+			_, err = fw.Write(photo)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	resp, err := http.Post(url, mw.FormDataContentType(), pr)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// BenchmarkUpload compares the peak heap of buffered and streamed avatar
+// uploads of a 12-megapixel photo, sent by many clients at once. The
+// streamed upload goes through the real handler into a filesystem store;
+// the buffered one reads the file and its re-encoding into memory first.
+// Nothing touches the database:
+//
+//	go test -run '^$' -bench Upload ./internal/handler/
+func BenchmarkUpload(b *testing.B) {
+	const clients = 16
+	photo := noisePhoto(b, 4000, 3000)
+
+	store, err := storage.NewFilesystemStore(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	images := &blobImageRepo{store: store}
+	urls := imageurl.NewSigner(&config.ImageURLConfig{
+		Keys: []config.SigningKey{{ID: "bench", Secret: []byte("bench")}},
+		TTL:  time.Hour,
+	})
+	uploadCfg := &config.UploadConfig{MaxSizeMB: int64(len(photo))>>20 + 1} // no quota
+	storageUC := usecase.NewStorageUseCase(images, uploadCfg)
+	userUC := usecase.NewUserUseCase(anyUserRepo{}, images, noFollowRepo{}, nil, storageUC, urls)
+	userH := NewUserHandler(userUC, uploadCfg)
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+		c.Set(middleware.ContextUserID, uuid.New())
+	})
+	router.POST("/streamed/users/me/avatar", userH.UploadAvatar)
+	router.POST("/buffered/users/me/avatar", bufferedUpload(store))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	for _, mode := range []string{"buffered", "streamed"} {
+		b.Run(mode, func(b *testing.B) {
+			url := srv.URL + "/" + mode + "/users/me/avatar"
+			b.SetBytes(int64(clients * len(photo)))
+			var peak uint64
+			for b.Loop() {
+				errs := make([]error, clients)
+				p := peakHeap(func() {
+					var wg sync.WaitGroup
+					for i := range clients {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							errs[i] = postAvatar(url, photo)
+						}()
+					}
+					wg.Wait()
+				})
+				for _, err := range errs {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				peak = max(peak, p)
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
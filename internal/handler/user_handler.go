@@ -1,23 +1,25 @@
 package handler
 
 import (
-	"io"
 	"net/http"
 
+	"github.com/acidsoft/gorestteach/internal/config"
 	"github.com/acidsoft/gorestteach/internal/usecase"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
 	"github.com/acidsoft/gorestteach/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 )
 
 // UserHandler handles user profile endpoints.
 type UserHandler struct {
-	userUC *usecase.UserUseCase
+	userUC    *usecase.UserUseCase
+	uploadCfg *config.UploadConfig
 }
 
-func NewUserHandler(userUC *usecase.UserUseCase) *UserHandler {
-	return &UserHandler{userUC: userUC}
+func NewUserHandler(userUC *usecase.UserUseCase, uploadCfg *config.UploadConfig) *UserHandler {
+	return &UserHandler{userUC: userUC, uploadCfg: uploadCfg}
 }
 
 // GetMe godoc
@@ -41,6 +43,27 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 	response.OK(c, profile)
 }
 
+// GetStorage godoc
+// @Summary      Get storage usage
+// @Description  Returns how many bytes of images the authenticated user has stored, and how much of their quota is left. quota_bytes and remaining_bytes are null when there is no quota. Each image is charged once, to the user who first uploaded its bytes; uploading a duplicate is free. Images no longer used count until they are collected.
+// @Tags         users
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]any
+// @Failure      401  {object}  map[string]any
+// @Router       /users/me/storage [get]
+func (h *UserHandler) GetStorage(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	usage, err := h.userUC.Storage(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.OK(c, usage)
+}
+
 // GetUser godoc
 // @Summary      Get public user profile
 // @Description  Returns the public profile of any user by their UUID.
@@ -69,7 +92,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 // UpdateMe godoc
 // @Summary      Update current user profile
-// @Description  Updates the authenticated user's name and/or bio.
+// @Description  Updates the authenticated user's name and/or bio. Omitted fields are left unchanged.
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -96,47 +119,80 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 	response.OK(c, profile)
 }
 
+// PatchMe godoc
+// @Summary      Patch current user profile
+// @Description  Applies a JSON Merge Patch (RFC 7386) to the caller's profile: absent members are kept,
+// @Description  null clears bio or removes the avatar (avatar_id), values replace.
+// @Tags         users
+// @Accept       application/merge-patch+json,json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        body  body      usecase.PatchUserInput  true  "Merge patch document"
+// @Success      200   {object}  map[string]any
+// @Failure      400   {object}  map[string]any
+// @Failure      415   {object}  map[string]any
+// @Router       /users/me [patch]
+func (h *UserHandler) PatchMe(c *gin.Context) {
+	userID := mustGetUserID(c).(uuid.UUID)
+
+	var input usecase.PatchUserInput
+	if err := bindMergePatch(c, &input); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	profile, err := h.userUC.PatchProfile(c.Request.Context(), userID, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.OK(c, profile)
+}
+
 // UploadAvatar godoc
 // @Summary      Upload avatar
-// @Description  Uploads a JPEG/PNG/WebP avatar image. Stored as blob in PostgreSQL. Max 5MB.
+// @Description  Uploads a JPEG/PNG/WebP avatar image. Max 5MB, and it must fit in the storage quota. Instead of a file, a JSON body can name a complete resumable upload.
 // @Tags         users
-// @Accept       multipart/form-data
+// @Accept       multipart/form-data,json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        avatar  formData  file  true  "Avatar image file"
+// @Param        avatar  formData  file                      false  "Avatar image file"
+// @Param        body    body      usecase.FromUploadInput  false  "Resumable upload to use instead"
 // @Success      200     {object}  map[string]any
 // @Failure      400     {object}  map[string]any
+// @Failure      404     {object}  map[string]any
+// @Failure      409     {object}  map[string]any
 // @Failure      413     {object}  map[string]any
 // @Failure      415     {object}  map[string]any
 // @Router       /users/me/avatar [post]
 func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	userID := mustGetUserID(c).(uuid.UUID)
 
-	fileHeader, err := c.FormFile("avatar")
-	if err != nil {
-		_ = c.Error(apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
-			"Field 'avatar' with image file is required"))
-		return
-	}
-
-	file, err := fileHeader.Open()
-	if err != nil {
-		_ = c.Error(apperror.Internal(err))
+	if c.ContentType() == binding.MIMEJSON {
+		var input usecase.FromUploadInput
+		if err := bindAndValidate(c, &input); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		profile, err := h.userUC.AvatarFromUpload(c.Request.Context(), userID, input.UploadID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		response.OK(c, profile)
 		return
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
+	upload, err := streamUpload(c, h.uploadCfg, "avatar")
 	if err != nil {
-		_ = c.Error(apperror.Internal(err))
+		_ = c.Error(err)
 		return
 	}
 
-	contentType := http.DetectContentType(data)
-
-	profile, ucErr := h.userUC.UploadAvatar(c.Request.Context(), userID, data, contentType)
+	profile, ucErr := h.userUC.UploadAvatar(c.Request.Context(), userID, upload.ImageUpload)
 	if ucErr != nil {
-		_ = c.Error(ucErr)
+		_ = c.Error(upload.err(ucErr))
 		return
 	}
 
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// sweepBatchSize bounds how many blobs are deleted per round trip.
+const sweepBatchSize = 100
+
+// BlobSweeper deletes the stored bytes of deleted images from filesystem and
+// object storage. Deleting an image only queues its blob; see
+// domain.BlobTombstone.
+type BlobSweeper struct {
+	imageRepo repository.ImageRepository
+	cfg       *config.StorageConfig
+}
+
+func NewBlobSweeper(imageRepo repository.ImageRepository, cfg *config.StorageConfig) *BlobSweeper {
+	return &BlobSweeper{imageRepo: imageRepo, cfg: cfg}
+}
+
+// Run sweeps once immediately and then every SweepInterval until ctx is done.
+func (s *BlobSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.SweepOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SweepOnce drains the queue of deleted blobs in batches and logs the total.
+func (s *BlobSweeper) SweepOnce(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		n, err := s.imageRepo.SweepDeletedBlobs(ctx, sweepBatchSize)
+		total += n
+		if err != nil {
+			log.Error().Err(err).Msg("blob sweep failed")
+			break
+		}
+		if n < sweepBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info().Int("blobs", total).Msg("Deleted blobs of deleted images")
+	}
+}
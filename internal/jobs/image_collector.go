@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// collectBatchSize bounds how many images are deleted per transaction.
+const collectBatchSize = 100
+
+// Totals since the process started, published on /debug/vars of the debug
+// listener (DEBUG_ADDR).
+var (
+	orphanImagesDeleted  = expvar.NewInt("image_gc_images_deleted")
+	orphanBytesReclaimed = expvar.NewInt("image_gc_bytes_reclaimed")
+	orphanRuns           = expvar.NewInt("image_gc_runs")
+)
+
+// ImageCollector deletes images that no avatar or post references any more
+// — replaced avatars, images of purged posts — once they have been
+// unreferenced for the grace period. Their blobs go to the BlobSweeper.
+type ImageCollector struct {
+	imageRepo repository.ImageRepository
+	cfg       *config.ImageGCConfig
+}
+
+func NewImageCollector(imageRepo repository.ImageRepository, cfg *config.ImageGCConfig) *ImageCollector {
+	return &ImageCollector{imageRepo: imageRepo, cfg: cfg}
+}
+
+// Run collects once immediately and then every Interval until ctx is done.
+func (c *ImageCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.CollectOnce(ctx, false); err != nil {
+			log.Error().Err(err).Msg("image collection failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CollectOnce deletes every collectable image in batches, or with dryRun
+// only counts them, and logs the totals. The totals are returned even if a
+// batch fails.
+func (c *ImageCollector) CollectOnce(ctx context.Context, dryRun bool) (repository.OrphanResult, error) {
+	cutoff := time.Now().Add(-c.cfg.GracePeriod)
+	if dryRun {
+		total, err := c.imageRepo.CountOrphans(ctx, cutoff)
+		if err == nil {
+			log.Info().
+				Int64("images", total.Images).
+				Int64("bytes", total.Bytes).
+				Msg("Unreferenced images that would be deleted")
+		}
+		return total, err
+	}
+
+	var total repository.OrphanResult
+	var err error
+	for ctx.Err() == nil {
+		var res repository.OrphanResult
+		res, err = c.imageRepo.DeleteOrphans(ctx, cutoff, collectBatchSize)
+		if err != nil {
+			break
+		}
+		total.Images += res.Images
+		total.Bytes += res.Bytes
+		if res.Images < collectBatchSize {
+			break
+		}
+	}
+
+	orphanRuns.Add(1)
+	orphanImagesDeleted.Add(total.Images)
+	orphanBytesReclaimed.Add(total.Bytes)
+	if total.Images > 0 {
+		log.Info().
+			Int64("images", total.Images).
+			Int64("bytes", total.Bytes).
+			Msg("Deleted unreferenced images")
+	}
+	return total, err
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// purgeBatchSize bounds how many posts are removed per transaction so a large
+// backlog never holds locks for long.
+const purgeBatchSize = 100
+
+// TrashPurger permanently deletes posts that have been in the trash longer
+// than the configured retention window, along with images nothing else uses.
+type TrashPurger struct {
+	postRepo repository.PostRepository
+	cfg      *config.TrashConfig
+}
+
+func NewTrashPurger(postRepo repository.PostRepository, cfg *config.TrashConfig) *TrashPurger {
+	return &TrashPurger{postRepo: postRepo, cfg: cfg}
+}
+
+// Run purges once immediately and then every PurgeInterval until ctx is done.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		p.PurgeOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce drains every expired post in batches and logs the totals.
+func (p *TrashPurger) PurgeOnce(ctx context.Context) {
+	cutoff := time.Now().Add(-p.cfg.Retention)
+
+	var total repository.PurgeResult
+	for ctx.Err() == nil {
+		res, err := p.postRepo.PurgeDeletedBefore(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("trash purge failed")
+			return
+		}
+		total.Posts += res.Posts
+		total.Images += res.Images
+		if res.Posts < purgeBatchSize {
+			break
+		}
+	}
+
+	if total.Posts > 0 {
+		log.Info().
+			Int64("posts", total.Posts).
+			Int64("images", total.Images).
+			Msg("Purged expired posts from trash")
+	}
+}
@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// expireInterval is how often expired uploads are looked for. Uploads
+	// last a day by default, so they are deleted at most this late.
+	expireInterval = time.Hour
+	// expireBatchSize bounds how many uploads are deleted per transaction.
+	expireBatchSize = 100
+)
+
+// UploadExpirer deletes resumable uploads that were not completed in time.
+// The bytes of their chunks go to the BlobSweeper.
+type UploadExpirer struct {
+	uploadRepo repository.UploadRepository
+}
+
+func NewUploadExpirer(uploadRepo repository.UploadRepository) *UploadExpirer {
+	return &UploadExpirer{uploadRepo: uploadRepo}
+}
+
+// Run expires once immediately and then every expireInterval until ctx is done.
+func (e *UploadExpirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		e.ExpireOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireOnce deletes every expired upload in batches and logs the total.
+func (e *UploadExpirer) ExpireOnce(ctx context.Context) {
+	now := time.Now()
+
+	var total int64
+	for ctx.Err() == nil {
+		n, err := e.uploadRepo.DeleteExpired(ctx, now, expireBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("upload expiry failed")
+			return
+		}
+		total += n
+		if n < expireBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info().Int64("uploads", total).Msg("Deleted expired uploads")
+	}
+}
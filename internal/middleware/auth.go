@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"strings"
 
 	"github.com/acidsoft/gorestteach/internal/jwt"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
@@ -15,9 +17,17 @@ const (
 	ContextUserEmail = "user_email"
 )
 
-// Auth verifies the Bearer JWT access token in the Authorization header.
+// AccountChecker tells whether the account behind a valid access token may
+// still use the API. It is consulted on every authenticated request, so
+// implementations are expected to cache.
+type AccountChecker interface {
+	CheckAccount(ctx context.Context, userID uuid.UUID) error
+}
+
+// Auth verifies the Bearer JWT access token in the Authorization header and
+// rejects tokens of accounts that have since been suspended or banned.
 // On success, it stores user_id and user_email into the Gin context.
-func Auth(jwtService *jwt.Service) gin.HandlerFunc {
+func Auth(jwtService *jwt.Service, accounts AccountChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -40,6 +50,12 @@ func Auth(jwtService *jwt.Service) gin.HandlerFunc {
 			return
 		}
 
+		if err := accounts.CheckAccount(c.Request.Context(), claims.UserID); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
 		// Store user info into context for downstream handlers
 		c.Set(ContextUserID, claims.UserID)
 		c.Set(ContextUserEmail, claims.Email)
@@ -47,3 +63,17 @@ func Auth(jwtService *jwt.Service) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuth is Auth for endpoints that also serve anonymous callers: a
+// request without an Authorization header passes through unauthenticated,
+// while a malformed or invalid token is still rejected.
+func OptionalAuth(jwtService *jwt.Service, accounts AccountChecker) gin.HandlerFunc {
+	auth := Auth(jwtService, accounts)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
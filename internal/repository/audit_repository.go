@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditRepository reads the moderation audit log. Entries are written by the
+// repositories that perform the audited actions, inside their transactions.
+type AuditRepository interface {
+	// List returns entries newest first, optionally only those about targetID.
+	List(ctx context.Context, targetID *uuid.UUID, page, perPage int) ([]domain.AuditEntry, int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) List(ctx context.Context, targetID *uuid.UUID, page, perPage int) ([]domain.AuditEntry, int64, error) {
+	var entries []domain.AuditEntry
+	var total int64
+
+	q := r.db.WithContext(ctx).Model(&domain.AuditEntry{})
+	if targetID != nil {
+		q = q.Where("target_id = ?", *targetID)
+	}
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := q.Order("created_at DESC, id DESC").
+		Offset(offset).Limit(perPage).
+		Find(&entries).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return entries, total, nil
+}
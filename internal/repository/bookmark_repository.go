@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookmarkRepository interface {
+	// Save bookmarks the post, or moves an existing bookmark to collectionID.
+	Save(ctx context.Context, bookmark *domain.Bookmark) error
+	Delete(ctx context.Context, userID, postID uuid.UUID) error
+	// ListPosts returns the user's saved posts, most recently saved first.
+	// Posts the user may no longer see are skipped but stay bookmarked; a
+	// bookmark counts as a link, so unlisted posts are shown.
+	// A nil collectionID lists every bookmark regardless of collection.
+	ListPosts(ctx context.Context, userID uuid.UUID, collectionID *uuid.UUID, page, perPage int) ([]domain.Post, int64, error)
+	// BookmarkedPostIDs reports which of postIDs the user has bookmarked.
+	BookmarkedPostIDs(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+
+	CreateCollection(ctx context.Context, collection *domain.BookmarkCollection) error
+	GetCollection(ctx context.Context, userID, id uuid.UUID) (*domain.BookmarkCollection, error)
+	ListCollections(ctx context.Context, userID uuid.UUID) ([]domain.BookmarkCollection, error)
+	UpdateCollection(ctx context.Context, collection *domain.BookmarkCollection) error
+	// DeleteCollection removes the collection; its bookmarks are kept but
+	// become uncategorized (FK ON DELETE SET NULL).
+	DeleteCollection(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type bookmarkRepository struct {
+	db *gorm.DB
+}
+
+func NewBookmarkRepository(db *gorm.DB) BookmarkRepository {
+	return &bookmarkRepository{db: db}
+}
+
+func (r *bookmarkRepository) Save(ctx context.Context, bookmark *domain.Bookmark) error {
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "post_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"collection_id"}),
+		}).
+		Create(bookmark).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *bookmarkRepository) Delete(ctx context.Context, userID, postID uuid.UUID) error {
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND post_id = ?", userID, postID).
+		Delete(&domain.Bookmark{}).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *bookmarkRepository) ListPosts(ctx context.Context, userID uuid.UUID, collectionID *uuid.UUID, page, perPage int) ([]domain.Post, int64, error) {
+	var posts []domain.Post
+	var total int64
+
+	q := r.db.WithContext(ctx).
+		Model(&domain.Post{}).
+		Joins("JOIN bookmarks ON bookmarks.post_id = posts.id").
+		Where("bookmarks.user_id = ?", userID).
+		Scopes(visibleTo(userID))
+	if collectionID != nil {
+		q = q.Where("bookmarks.collection_id = ?", *collectionID)
+	}
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := q.Preload("User").Preload("Tags").Preload("Images", orderedImages).
+		Order("bookmarks.created_at DESC, posts.id DESC").
+		Offset(offset).Limit(perPage).
+		Find(&posts).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return posts, total, nil
+}
+
+func (r *bookmarkRepository) BookmarkedPostIDs(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	result := make(map[uuid.UUID]bool, len(postIDs))
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Bookmark{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &ids).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
+
+func (r *bookmarkRepository) CreateCollection(ctx context.Context, collection *domain.BookmarkCollection) error {
+	if err := r.db.WithContext(ctx).Create(collection).Error; err != nil {
+		if isUniqueViolation(err) {
+			return apperror.Conflict("A collection with this name already exists")
+		}
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *bookmarkRepository) GetCollection(ctx context.Context, userID, id uuid.UUID) (*domain.BookmarkCollection, error) {
+	var collection domain.BookmarkCollection
+	err := r.db.WithContext(ctx).First(&collection, "id = ? AND user_id = ?", id, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Bookmark collection")
+		}
+		return nil, apperror.Internal(err)
+	}
+	return &collection, nil
+}
+
+func (r *bookmarkRepository) ListCollections(ctx context.Context, userID uuid.UUID) ([]domain.BookmarkCollection, error) {
+	var collections []domain.BookmarkCollection
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("name ASC").
+		Find(&collections).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return collections, nil
+}
+
+func (r *bookmarkRepository) UpdateCollection(ctx context.Context, collection *domain.BookmarkCollection) error {
+	if err := r.db.WithContext(ctx).Save(collection).Error; err != nil {
+		if isUniqueViolation(err) {
+			return apperror.Conflict("A collection with this name already exists")
+		}
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *bookmarkRepository) DeleteCollection(ctx context.Context, userID, id uuid.UUID) error {
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&domain.BookmarkCollection{}).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation (23505).
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
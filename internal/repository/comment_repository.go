@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CommentRepository interface {
+	// Create inserts the comment and bumps posts.comment_count atomically.
+	Create(ctx context.Context, comment *domain.Comment) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Comment, error)
+	// ListByPost returns one level of the thread: top-level comments when
+	// parentID is nil, otherwise the direct replies to parentID (oldest first).
+	ListByPost(ctx context.Context, postID uuid.UUID, parentID *uuid.UUID, page, perPage int) ([]domain.Comment, int64, error)
+	UpdateBody(ctx context.Context, comment *domain.Comment) error
+	// Delete removes the comment with all its replies and decrements
+	// posts.comment_count by the number of rows removed.
+	Delete(ctx context.Context, comment *domain.Comment) error
+}
+
+type commentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+// replyCountSelect adds the computed reply_count column to comment queries.
+const replyCountSelect = "comments.*, (SELECT COUNT(*) FROM comments r WHERE r.parent_id = comments.id) AS reply_count"
+
+func (r *commentRepository) Create(ctx context.Context, comment *domain.Comment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("User").Create(comment).Error; err != nil {
+			return err
+		}
+		return tx.Exec(
+			"UPDATE posts SET comment_count = comment_count + 1 WHERE id = ?", comment.PostID,
+		).Error
+	})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *commentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Comment, error) {
+	var comment domain.Comment
+	err := r.db.WithContext(ctx).
+		Select(replyCountSelect).
+		Preload("User").
+		First(&comment, "comments.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Comment")
+		}
+		return nil, apperror.Internal(err)
+	}
+	return &comment, nil
+}
+
+func (r *commentRepository) ListByPost(ctx context.Context, postID uuid.UUID, parentID *uuid.UUID, page, perPage int) ([]domain.Comment, int64, error) {
+	var comments []domain.Comment
+	var total int64
+
+	q := r.db.WithContext(ctx).Model(&domain.Comment{}).Where("comments.post_id = ?", postID)
+	if parentID != nil {
+		q = q.Where("comments.parent_id = ?", *parentID)
+	} else {
+		q = q.Where("comments.parent_id IS NULL")
+	}
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := q.Select(replyCountSelect).
+		Preload("User").
+		Order("comments.created_at ASC, comments.id ASC").
+		Offset(offset).Limit(perPage).
+		Find(&comments).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return comments, total, nil
+}
+
+func (r *commentRepository) UpdateBody(ctx context.Context, comment *domain.Comment) error {
+	if err := r.db.WithContext(ctx).
+		Model(comment).
+		Update("body", comment.Body).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *commentRepository) Delete(ctx context.Context, comment *domain.Comment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Delete the whole subtree explicitly (rather than relying on the FK
+		// cascade) so RowsAffected tells us how far to move the counter.
+		res := tx.Exec(`
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM comments WHERE id = ?
+				UNION ALL
+				SELECT c.id FROM comments c JOIN subtree s ON c.parent_id = s.id
+			)
+			DELETE FROM comments WHERE id IN (SELECT id FROM subtree)`, comment.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		return tx.Exec(
+			"UPDATE posts SET comment_count = GREATEST(comment_count - ?, 0) WHERE id = ?",
+			res.RowsAffected, comment.PostID,
+		).Error
+	})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
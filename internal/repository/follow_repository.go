@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FollowRepository interface {
+	// Follow is idempotent: following someone twice is not an error.
+	Follow(ctx context.Context, followerID, followeeID uuid.UUID) error
+	Unfollow(ctx context.Context, followerID, followeeID uuid.UUID) error
+	// ListFollowers returns users following userID, most recent first.
+	ListFollowers(ctx context.Context, userID uuid.UUID, page, perPage int) ([]domain.User, int64, error)
+	// ListFollowing returns users that userID follows, most recent first.
+	ListFollowing(ctx context.Context, userID uuid.UUID, page, perPage int) ([]domain.User, int64, error)
+	Counts(ctx context.Context, userID uuid.UUID) (followers, following int64, err error)
+}
+
+type followRepository struct {
+	db *gorm.DB
+}
+
+func NewFollowRepository(db *gorm.DB) FollowRepository {
+	return &followRepository{db: db}
+}
+
+func (r *followRepository) Follow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.Follow{FollowerID: followerID, FolloweeID: followeeID}).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *followRepository) Unfollow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	if err := r.db.WithContext(ctx).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&domain.Follow{}).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *followRepository) ListFollowers(ctx context.Context, userID uuid.UUID, page, perPage int) ([]domain.User, int64, error) {
+	return r.listUsers(ctx, "follows.follower_id", "follows.followee_id", userID, page, perPage)
+}
+
+func (r *followRepository) ListFollowing(ctx context.Context, userID uuid.UUID, page, perPage int) ([]domain.User, int64, error) {
+	return r.listUsers(ctx, "follows.followee_id", "follows.follower_id", userID, page, perPage)
+}
+
+// listUsers joins users on joinCol of the follows rows where matchCol = userID.
+// Both column names are constants supplied by the callers above.
+func (r *followRepository) listUsers(ctx context.Context, joinCol, matchCol string, userID uuid.UUID, page, perPage int) ([]domain.User, int64, error) {
+	var users []domain.User
+	var total int64
+
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Follow{}).
+		Where(matchCol+" = ?", userID).
+		Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Joins("JOIN follows ON users.id = "+joinCol).
+		Where(matchCol+" = ?", userID).
+		Order("follows.created_at DESC, users.id DESC").
+		Offset(offset).Limit(perPage).
+		Find(&users).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return users, total, nil
+}
+
+func (r *followRepository) Counts(ctx context.Context, userID uuid.UUID) (followers, following int64, err error) {
+	row := struct {
+		Followers int64
+		Following int64
+	}{}
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT
+			(SELECT COUNT(*) FROM follows WHERE followee_id = ?) AS followers,
+			(SELECT COUNT(*) FROM follows WHERE follower_id = ?) AS following`,
+		userID, userID).Scan(&row).Error; err != nil {
+		return 0, 0, apperror.Internal(err)
+	}
+	return row.Followers, row.Following, nil
+}
@@ -1,38 +1,561 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"time"
 
 	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/storage"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type ImageRepository interface {
+	// Save stores a new image. If an image with the same bytes exists, it
+	// is returned instead: image is updated to match it and the bytes just
+	// written are discarded.
 	Save(ctx context.Context, image *domain.Image) error
-	GetByID(ctx context.Context, id uuid.UUID) (*domain.Image, error)
+	// GetByID returns the image's metadata if viewerID (uuid.Nil when
+	// anonymous) may see it. Avatars and loose images are public; a post image
+	// is only served to viewers who can see at least one post it belongs to
+	// that is not in the trash.
+	GetByID(ctx context.Context, id, viewerID uuid.UUID) (*domain.Image, error)
+	// GetAvatar returns the image's metadata if it is some user's avatar, for
+	// unsigned requests without a viewer.
+	GetAvatar(ctx context.Context, id uuid.UUID) (*domain.Image, error)
+	// GetForLink returns the image's metadata whoever the viewer is, for a
+	// signed URL: visibility was checked when the URL was made.
+	GetForLink(ctx context.Context, id uuid.UUID) (*domain.Image, error)
+	// LoadData reads the bytes of an image returned by GetByID, GetAvatar or
+	// GetForLink into its Data.
+	LoadData(ctx context.Context, image *domain.Image) error
+	// SetSHA256 records the content hash of an image that has none yet.
+	SetSHA256(ctx context.Context, id uuid.UUID, hash string) error
+
+	// ListIDsByBackend returns, in ID order, up to limit IDs after `after` of
+	// the images whose bytes are kept in backend.
+	ListIDsByBackend(ctx context.Context, backend string, after uuid.UUID, limit int) ([]uuid.UUID, error)
+	// MoveBlob copies the image's bytes from one backend to another, points
+	// the image at the copy and queues the original for deletion. The image
+	// stays readable throughout. moved is false if the image was deleted or
+	// is no longer in `from`.
+	MoveBlob(ctx context.Context, id uuid.UUID, from, to string) (moved bool, err error)
+	// SweepDeletedBlobs deletes up to limit blobs of deleted images from the
+	// configured backends and returns how many it deleted.
+	SweepDeletedBlobs(ctx context.Context, limit int) (int, error)
+
+	// CountOrphans reports what DeleteOrphans would delete in total: the
+	// images no avatar or post references that were last saved before
+	// savedBefore.
+	CountOrphans(ctx context.Context, savedBefore time.Time) (OrphanResult, error)
+	// DeleteOrphans deletes up to limit of those images, with their cached
+	// variants, and queues their blobs for the sweeper. Images being
+	// referenced at the same moment are skipped.
+	DeleteOrphans(ctx context.Context, savedBefore time.Time, limit int) (OrphanResult, error)
+	// Usage counts the images charged to userID and the bytes they take up
+	// as uploaded; cached variants are not charged.
+	Usage(ctx context.Context, userID uuid.UUID) (ImageUsage, error)
+}
+
+// OrphanResult counts unreferenced images and the bytes they take up,
+// cached variants included.
+type OrphanResult struct {
+	Images int64
+	Bytes  int64
+}
+
+// ImageUsage counts the images charged to a user and their size.
+type ImageUsage struct {
+	Images int64
+	Bytes  int64
 }
 
 type imageRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	blobs *storage.Stores
 }
 
-func NewImageRepository(db *gorm.DB) ImageRepository {
-	return &imageRepository{db: db}
+func NewImageRepository(db *gorm.DB, blobs *storage.Stores) ImageRepository {
+	return &imageRepository{db: db, blobs: blobs}
 }
 
+// ErrQuotaExceeded is returned, wrapped, when saving an image would take its
+// uploader past the image's Quota.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
 func (r *imageRepository) Save(ctx context.Context, image *domain.Image) error {
-	if err := r.db.WithContext(ctx).Create(image).Error; err != nil {
+	undo, err := putBlobs(ctx, r.blobs, []*domain.Image{image})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return insertImage(tx, r.blobs, image)
+	})
+	if err != nil {
+		undo()
 		return apperror.Internal(err)
 	}
 	return nil
 }
 
-func (r *imageRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Image, error) {
+// imageColumns selects an image's metadata, and whether it is public.
+const imageColumns = `images.id, images.backend, images.content_type, images.size,
+	images.width, images.height, images.sha256, images.created_at,
+	(NOT EXISTS (SELECT 1 FROM post_images WHERE post_images.image_id = images.id)
+		OR EXISTS (SELECT 1 FROM users WHERE users.avatar_id = images.id)) AS public`
+
+func (r *imageRepository) GetByID(ctx context.Context, id, viewerID uuid.UUID) (*domain.Image, error) {
 	var img domain.Image
-	if err := r.db.WithContext(ctx).First(&img, "id = ?", id).Error; err != nil {
-		return nil, apperror.NotFound("Image")
+	err := r.db.WithContext(ctx).
+		Select(imageColumns).
+		Where(`(NOT EXISTS (SELECT 1 FROM post_images WHERE post_images.image_id = images.id)
+			OR EXISTS (SELECT 1 FROM users WHERE users.avatar_id = images.id)
+			OR EXISTS (
+				SELECT 1 FROM post_images
+				JOIN posts ON posts.id = post_images.post_id AND posts.deleted_at IS NULL
+				WHERE post_images.image_id = images.id AND `+visiblePostCond+`))`,
+			sql.Named("viewer", viewerID)).
+		First(&img, "images.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Image")
+		}
+		return nil, apperror.Internal(err)
 	}
+
 	return &img, nil
 }
+
+func (r *imageRepository) GetAvatar(ctx context.Context, id uuid.UUID) (*domain.Image, error) {
+	var img domain.Image
+	err := r.db.WithContext(ctx).
+		Select(imageColumns).
+		Where("EXISTS (SELECT 1 FROM users WHERE users.avatar_id = images.id)").
+		First(&img, "images.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Image")
+		}
+		return nil, apperror.Internal(err)
+	}
+
+	return &img, nil
+}
+
+func (r *imageRepository) GetForLink(ctx context.Context, id uuid.UUID) (*domain.Image, error) {
+	var img domain.Image
+	err := r.db.WithContext(ctx).Select(imageColumns).First(&img, "images.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Image")
+		}
+		return nil, apperror.Internal(err)
+	}
+
+	return &img, nil
+}
+
+func (r *imageRepository) LoadData(ctx context.Context, image *domain.Image) error {
+	data, err := r.readBlob(ctx, image.Backend, image.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return apperror.NotFound("Image") // deleted meanwhile
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	image.Data = data
+	return nil
+}
+
+func (r *imageRepository) SetSHA256(ctx context.Context, id uuid.UUID, hash string) error {
+	err := r.db.WithContext(ctx).
+		Model(&domain.Image{}).
+		Where("id = ? AND sha256 = ''", id).
+		Update("sha256", hash).Error
+	if isUniqueViolation(err) {
+		// Another image has the same bytes, both having been uploaded before
+		// hashes were recorded; this one stays unhashed rather than merged.
+		return nil
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *imageRepository) readBlob(ctx context.Context, backend string, id uuid.UUID) ([]byte, error) {
+	store, err := r.blobs.Get(backend)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := store.Get(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func (r *imageRepository) ListIDsByBackend(ctx context.Context, backend string, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Image{}).
+		Where("backend = ? AND id > ?", backend, after).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return ids, nil
+}
+
+func (r *imageRepository) MoveBlob(ctx context.Context, id uuid.UUID, from, to string) (bool, error) {
+	src, err := r.blobs.Get(from)
+	if err != nil {
+		return false, apperror.Internal(err)
+	}
+	dst, err := r.blobs.Get(to)
+	if err != nil {
+		return false, apperror.Internal(err)
+	}
+	key := id.String()
+
+	var img domain.Image
+	err = r.db.WithContext(ctx).
+		Select("id", "backend", "content_type", "size").
+		First(&img, "id = ? AND backend = ?", id, from).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperror.Internal(err)
+	}
+
+	// A blob left in `to` by an earlier move away from it may still be
+	// queued for deletion; cancel that before writing the new copy there.
+	if err := r.db.WithContext(ctx).
+		Where("backend = ? AND key = ?", to, key).
+		Delete(&domain.BlobTombstone{}).Error; err != nil {
+		return false, apperror.Internal(err)
+	}
+
+	rc, err := src.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperror.Internal(err)
+	}
+	err = dst.Put(ctx, key, rc, img.Size, img.ContentType)
+	rc.Close()
+	if err != nil {
+		return false, apperror.Internal(err)
+	}
+
+	// Switch the row over only if nobody deleted or moved the image meanwhile.
+	moved := false
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{"backend": to}
+		if from == storage.BackendPostgres {
+			updates["data"] = nil
+		}
+		res := tx.Model(&domain.Image{}).Where("id = ? AND backend = ?", id, from).Updates(updates)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		moved = true
+		if from == storage.BackendPostgres {
+			return nil
+		}
+		return tx.Create(&domain.BlobTombstone{Backend: from, Key: key}).Error
+	})
+	if err != nil {
+		return false, apperror.Internal(err)
+	}
+	if !moved && to != storage.BackendPostgres {
+		_ = dst.Delete(ctx, key) // best effort: nothing points at the copy
+	}
+	return moved, nil
+}
+
+func (r *imageRepository) SweepDeletedBlobs(ctx context.Context, limit int) (int, error) {
+	var tombstones []domain.BlobTombstone
+	if err := r.db.WithContext(ctx).
+		Where("backend IN ?", r.blobs.Names()).
+		Order("created_at").
+		Limit(limit).
+		Find(&tombstones).Error; err != nil {
+		return 0, apperror.Internal(err)
+	}
+
+	swept := 0
+	for _, t := range tombstones {
+		store, err := r.blobs.Get(t.Backend)
+		if err != nil {
+			return swept, apperror.Internal(err)
+		}
+		// The tombstone stays locked while the blob is deleted, so MoveBlob
+		// cannot cancel it and write a new copy that would then be lost.
+		err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			res := tx.Exec("SELECT 1 FROM blob_tombstones WHERE id = ? FOR UPDATE", t.ID)
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error // cancelled meanwhile
+			}
+			if err := store.Delete(ctx, t.Key); err != nil {
+				return err
+			}
+			swept++
+			return tx.Delete(&t).Error
+		})
+		if err != nil {
+			return swept, apperror.Internal(err)
+		}
+	}
+	return swept, nil
+}
+
+// orphanCond selects unreferenced images saved before the named cutoff. A
+// post's cover is always in its gallery, but is checked for all that.
+const orphanCond = `images.ref_count = 0 AND images.saved_at < @cutoff
+	AND NOT EXISTS (SELECT 1 FROM posts WHERE posts.image_id = images.id)`
+
+func (r *imageRepository) CountOrphans(ctx context.Context, savedBefore time.Time) (OrphanResult, error) {
+	var result OrphanResult
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT count(*) AS images,
+			coalesce(sum(images.size + (SELECT coalesce(sum(v.size), 0) FROM image_variants v WHERE v.image_id = images.id)), 0) AS bytes
+		FROM images WHERE `+orphanCond,
+		sql.Named("cutoff", savedBefore)).Scan(&result).Error; err != nil {
+		return OrphanResult{}, apperror.Internal(err)
+	}
+	return result, nil
+}
+
+func (r *imageRepository) Usage(ctx context.Context, userID uuid.UUID) (ImageUsage, error) {
+	var usage ImageUsage
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Image{}).
+		Select("count(*) AS images, coalesce(sum(size), 0) AS bytes").
+		Where("user_id = ?", userID).
+		Scan(&usage).Error; err != nil {
+		return ImageUsage{}, apperror.Internal(err)
+	}
+	return usage, nil
+}
+
+func (r *imageRepository) DeleteOrphans(ctx context.Context, savedBefore time.Time, limit int) (OrphanResult, error) {
+	// Locking the victims makes a concurrent reference either wait for the
+	// deletion (and fail cleanly) or win, in which case the row no longer
+	// matches once the lock is granted and is left alone.
+	var result OrphanResult
+	if err := r.db.WithContext(ctx).Raw(`
+		WITH victims AS (
+			SELECT id FROM images WHERE `+orphanCond+`
+			ORDER BY saved_at LIMIT @limit
+			FOR UPDATE SKIP LOCKED
+		), gone AS (
+			DELETE FROM images USING victims WHERE images.id = victims.id
+			RETURNING images.id, images.backend, images.size
+		), variants AS (
+			SELECT v.backend, v.image_id, v.name, v.size
+			FROM image_variants v JOIN gone ON gone.id = v.image_id
+		), queued AS (
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT backend, id::text, now() FROM gone WHERE backend <> @postgres
+		), queued_variants AS (
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT backend, image_id::text || '_' || name, now() FROM variants WHERE backend <> @postgres
+		)
+		SELECT (SELECT count(*) FROM gone) AS images,
+			(SELECT coalesce(sum(size), 0) FROM gone) + (SELECT coalesce(sum(size), 0) FROM variants) AS bytes`,
+		sql.Named("cutoff", savedBefore), sql.Named("limit", limit),
+		sql.Named("postgres", storage.BackendPostgres)).Scan(&result).Error; err != nil {
+		return OrphanResult{}, apperror.Internal(err)
+	}
+	return result, nil
+}
+
+// putBlobs writes the bytes of new images to the primary backend before
+// their rows are inserted, so that a committed row never points at a missing
+// blob. With the postgres backend the bytes simply go into the rows. Images
+// are streamed from Body, one after the other, when it is set; Size and
+// SHA256 are filled in from what was written. The returned undo deletes the
+// written blobs if the rows are not inserted after all.
+func putBlobs(ctx context.Context, blobs *storage.Stores, images []*domain.Image) (undo func(), err error) {
+	primary := blobs.Primary()
+	var written []string
+	undo = func() {
+		for _, key := range written {
+			_ = primary.Delete(context.WithoutCancel(ctx), key) // best effort: nothing points at it
+		}
+	}
+
+	for _, img := range images {
+		img.Backend = primary.Name()
+		size := int64(-1)
+		body := img.Body
+		if body == nil {
+			size, body = int64(len(img.Data)), bytes.NewReader(img.Data)
+		}
+		hr := &hashingReader{r: body, h: sha256.New()}
+
+		if img.Backend == storage.BackendPostgres {
+			if img.Body != nil {
+				if img.Data, err = io.ReadAll(hr); err != nil {
+					undo()
+					return nil, err
+				}
+			} else if _, err = io.Copy(io.Discard, hr); err != nil {
+				return nil, err
+			}
+		} else {
+			if img.ID == uuid.Nil {
+				img.ID = uuid.New()
+			}
+			key := img.ID.String()
+			if err := primary.Put(ctx, key, hr, size, img.ContentType); err != nil {
+				undo()
+				return nil, err
+			}
+			written = append(written, key)
+			img.Data = nil
+		}
+		img.Body = nil
+		img.Size, img.SHA256 = hr.n, hex.EncodeToString(hr.h.Sum(nil))
+	}
+	return undo, nil
+}
+
+// hashingReader hashes and counts the bytes read through it.
+type hashingReader struct {
+	r io.Reader
+	h hash.Hash
+	n int64
+}
+
+func (hr *hashingReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	hr.h.Write(p[:n])
+	hr.n += int64(n)
+	return n, err
+}
+
+// insertImage inserts the row of an image whose bytes putBlobs has written,
+// unless an image with the same hash exists. Then image is updated to match
+// that one, keeping its uploader if it has one, and the bytes just written
+// are deleted. Concurrent inserts of
+// the same bytes wait on each other at the unique index, so all of them end
+// up with the same image; the existing row stays locked until the caller's
+// transaction ends, so it cannot be deleted before the caller references it,
+// and its SavedAt is renewed to keep the orphan collector off it until then.
+//
+// With a Quota, the inserts of one uploader are serialized until the
+// caller's transaction ends, and the uploader's total is checked once the
+// image is charged to them: it fails with ErrQuotaExceeded if that is over
+// the quota, so the caller must roll back.
+func insertImage(tx *gorm.DB, blobs *storage.Stores, image *domain.Image) error {
+	if image.ID == uuid.Nil {
+		image.ID = uuid.New()
+	}
+	now := time.Now()
+	if image.CreatedAt.IsZero() {
+		image.CreatedAt = now
+	}
+	image.SavedAt = now
+
+	quota, uploader := image.Quota, image.UserID
+	if quota > 0 && uploader != nil {
+		if err := tx.Exec(
+			"SELECT pg_advisory_xact_lock(hashtextextended(?, 0))",
+			"image_quota:"+uploader.String(),
+		).Error; err != nil {
+			return err
+		}
+	}
+
+	var row domain.Image
+	err := tx.Raw(`
+		INSERT INTO images (id, user_id, data, backend, content_type, size, width, height, sha256, saved_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (sha256) WHERE sha256 <> '' DO UPDATE SET
+			saved_at = EXCLUDED.saved_at,
+			user_id = coalesce(images.user_id, EXCLUDED.user_id)
+		RETURNING id, user_id, backend, content_type, size, width, height, ref_count, saved_at, created_at`,
+		image.ID, image.UserID, image.Data, image.Backend, image.ContentType, image.Size,
+		image.Width, image.Height, image.SHA256, image.SavedAt, image.CreatedAt).
+		Scan(&row).Error
+	if err != nil {
+		return err
+	}
+
+	if row.ID != image.ID {
+		if image.Backend != storage.BackendPostgres {
+			_ = blobs.Primary().Delete(context.WithoutCancel(tx.Statement.Context), image.ID.String()) // best effort: nothing points at it
+		}
+		*image = domain.Image{
+			ID:          row.ID,
+			UserID:      row.UserID,
+			Backend:     row.Backend,
+			ContentType: row.ContentType,
+			Size:        row.Size,
+			Width:       row.Width,
+			Height:      row.Height,
+			SHA256:      image.SHA256,
+			RefCount:    row.RefCount,
+			SavedAt:     row.SavedAt,
+			CreatedAt:   row.CreatedAt,
+		}
+	}
+
+	if quota > 0 && uploader != nil && row.UserID != nil && *row.UserID == *uploader {
+		var used int64
+		if err := tx.Model(&domain.Image{}).
+			Select("coalesce(sum(size), 0)").
+			Where("user_id = ?", *uploader).
+			Scan(&used).Error; err != nil {
+			return err
+		}
+		if used > quota {
+			return ErrQuotaExceeded
+		}
+	}
+	return nil
+}
+
+// deleteUnreferencedImages deletes those of ids that no post cover, gallery
+// or avatar references any more, and returns how many were deleted. Their
+// cached variants go with them. Blobs kept outside Postgres, the variants'
+// included, are queued for the blob sweeper in the same statement.
+func deleteUnreferencedImages(tx *gorm.DB, ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	var deleted int64
+	err := tx.Raw(`
+		WITH gone AS (
+			DELETE FROM images
+			WHERE id IN ?
+			  AND ref_count = 0
+			  AND NOT EXISTS (SELECT 1 FROM posts WHERE posts.image_id = images.id)
+			RETURNING id, backend
+		), queued AS (
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT backend, id::text, now() FROM gone WHERE backend <> ?
+		), queued_variants AS (
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT v.backend, v.image_id::text || '_' || v.name, now()
+			FROM image_variants v JOIN gone ON gone.id = v.image_id
+			WHERE v.backend <> ?
+		)
+		SELECT count(*) FROM gone`,
+		ids, storage.BackendPostgres, storage.BackendPostgres).Scan(&deleted).Error
+	return deleted, err
+}
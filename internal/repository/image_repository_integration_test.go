@@ -0,0 +1,198 @@
+//go:build integration
+
+// Tests against a real PostgreSQL database, configured with the DB_*
+// variables the server uses:
+//
+//	DB_NAME=gorestteach_test go test -tags integration ./internal/repository/
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/database"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/storage"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	port, _ := strconv.Atoi(getenv("DB_PORT", "5432"))
+	db, err := database.Connect(&config.DatabaseConfig{
+		Host:     getenv("DB_HOST", "localhost"),
+		Port:     port,
+		User:     getenv("DB_USER", "postgres"),
+		Password: getenv("DB_PASSWORD", "postgres"),
+		Name:     getenv("DB_NAME", "gorestteach_test"),
+		SSLMode:  getenv("DB_SSLMODE", "disable"),
+	})
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	return db
+}
+
+func newTestStores(t *testing.T, db *gorm.DB) *storage.Stores {
+	t.Helper()
+	blobs, err := storage.NewStores(storage.BackendPostgres, storage.NewPostgresStore(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blobs
+}
+
+func newTestImageRepo(t *testing.T) (ImageRepository, *gorm.DB) {
+	t.Helper()
+	db := openTestDB(t)
+	return NewImageRepository(db, newTestStores(t, db)), db
+}
+
+// createTestUser creates a user whose images are deleted with it when the
+// test ends.
+func createTestUser(t *testing.T, db *gorm.DB) uuid.UUID {
+	t.Helper()
+	user := domain.User{Name: "Quota Test", Email: uuid.NewString() + "@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM images WHERE user_id = ?", user.ID)
+		db.Exec("DELETE FROM users WHERE id = ?", user.ID)
+	})
+	return user.ID
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newTestImage(data []byte, userID *uuid.UUID, quota int64) *domain.Image {
+	return &domain.Image{Data: data, ContentType: "image/png", UserID: userID, Quota: quota}
+}
+
+func TestImageQuotaConcurrentSaves(t *testing.T) {
+	repo, db := newTestImageRepo(t)
+	userID := createTestUser(t, db)
+	ctx := context.Background()
+
+	const quota, size, uploads = 10_000, 3_000, 8
+	var wg sync.WaitGroup
+	errs := make([]error, uploads)
+	for i := range uploads {
+		img := newTestImage(randomBytes(t, size), &userID, quota)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = repo.Save(ctx, img)
+		}()
+	}
+	wg.Wait()
+
+	saved := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			saved++
+		case !errors.Is(err, ErrQuotaExceeded):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if want := quota / size; saved != want {
+		t.Errorf("%d uploads saved, want %d", saved, want)
+	}
+	usage, err := repo.Usage(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Bytes > quota {
+		t.Errorf("usage %d bytes is over the quota of %d", usage.Bytes, quota)
+	}
+}
+
+// Deduplicated bytes are charged once, to the first uploader.
+func TestImageDedupCharging(t *testing.T) {
+	repo, db := newTestImageRepo(t)
+	first, second := createTestUser(t, db), createTestUser(t, db)
+	ctx := context.Background()
+	data := randomBytes(t, 3_000)
+
+	original := newTestImage(data, &first, 10_000)
+	if err := repo.Save(ctx, original); err != nil {
+		t.Fatal(err)
+	}
+
+	// A quota smaller than the image: the duplicate costs nothing.
+	duplicate := newTestImage(append([]byte{}, data...), &second, 1_000)
+	if err := repo.Save(ctx, duplicate); err != nil {
+		t.Fatalf("saving a duplicate: %v", err)
+	}
+	if duplicate.ID != original.ID {
+		t.Errorf("duplicate saved as %s, want %s", duplicate.ID, original.ID)
+	}
+	if duplicate.UserID == nil || *duplicate.UserID != first {
+		t.Errorf("duplicate charged to %v, want the first uploader", duplicate.UserID)
+	}
+
+	for user, want := range map[uuid.UUID]ImageUsage{first: {1, 3_000}, second: {0, 0}} {
+		usage, err := repo.Usage(ctx, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage != want {
+			t.Errorf("usage = %+v, want %+v", usage, want)
+		}
+	}
+
+	// Uploading one's own image again at a full quota is not an overshoot.
+	again := newTestImage(append([]byte{}, data...), &first, 3_000)
+	if err := repo.Save(ctx, again); err != nil {
+		t.Errorf("saving one's own image again: %v", err)
+	}
+}
+
+// An image nobody is charged for goes to the next user to upload it.
+func TestImageDedupAdoptsOwnerless(t *testing.T) {
+	repo, db := newTestImageRepo(t)
+	userID := createTestUser(t, db)
+	ctx := context.Background()
+	data := randomBytes(t, 3_000)
+
+	ownerless := newTestImage(data, nil, 0)
+	if err := repo.Save(ctx, ownerless); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM images WHERE id = ?", ownerless.ID) })
+
+	err := repo.Save(ctx, newTestImage(append([]byte{}, data...), &userID, 2_000))
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("adopting an image over the quota: err = %v, want ErrQuotaExceeded", err)
+	}
+
+	adopted := newTestImage(append([]byte{}, data...), &userID, 10_000)
+	if err := repo.Save(ctx, adopted); err != nil {
+		t.Fatal(err)
+	}
+	if adopted.ID != ownerless.ID || adopted.UserID == nil || *adopted.UserID != userID {
+		t.Errorf("saved as %s charged to %v, want %s charged to the uploader", adopted.ID, adopted.UserID, ownerless.ID)
+	}
+}
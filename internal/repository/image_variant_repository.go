@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/storage"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImageVariantRepository caches resized renditions of images. Variants are
+// deleted along with their image, so a cached variant never outlives it.
+type ImageVariantRepository interface {
+	// Get returns the metadata of a cached variant.
+	Get(ctx context.Context, imageID uuid.UUID, name string) (*domain.ImageVariant, error)
+	// LoadData reads the bytes of a variant returned by Get into its Data. A
+	// variant whose bytes cannot be found any more is reported as not found,
+	// to be regenerated.
+	LoadData(ctx context.Context, variant *domain.ImageVariant) error
+	// Save caches a variant in the primary backend, replacing one cached
+	// under the same name. It returns NotFound if the image has been deleted.
+	Save(ctx context.Context, variant *domain.ImageVariant) error
+	// DropBackend forgets every variant kept in backend and queues their
+	// bytes for deletion; they are regenerated on the next request.
+	DropBackend(ctx context.Context, backend string) (int64, error)
+}
+
+type imageVariantRepository struct {
+	db    *gorm.DB
+	blobs *storage.Stores
+}
+
+func NewImageVariantRepository(db *gorm.DB, blobs *storage.Stores) ImageVariantRepository {
+	return &imageVariantRepository{db: db, blobs: blobs}
+}
+
+func (r *imageVariantRepository) Get(ctx context.Context, imageID uuid.UUID, name string) (*domain.ImageVariant, error) {
+	var v domain.ImageVariant
+	err := r.db.WithContext(ctx).
+		Omit("data").
+		First(&v, "image_id = ? AND name = ?", imageID, name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Image variant")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return &v, nil
+}
+
+func (r *imageVariantRepository) LoadData(ctx context.Context, variant *domain.ImageVariant) error {
+	if variant.Backend == storage.BackendPostgres {
+		var rows []struct{ Data []byte }
+		if err := r.db.WithContext(ctx).
+			Raw("SELECT data FROM image_variants WHERE image_id = ? AND name = ? AND data IS NOT NULL",
+				variant.ImageID, variant.Name).
+			Scan(&rows).Error; err != nil {
+			return apperror.Internal(err)
+		}
+		if len(rows) == 0 {
+			return apperror.NotFound("Image variant")
+		}
+		variant.Data = rows[0].Data
+		return nil
+	}
+
+	store, err := r.blobs.Get(variant.Backend)
+	if err != nil {
+		return apperror.NotFound("Image variant") // backend retired
+	}
+	rc, err := store.Get(ctx, variant.BlobKey())
+	if errors.Is(err, storage.ErrNotFound) {
+		return apperror.NotFound("Image variant")
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	defer rc.Close()
+	if variant.Data, err = io.ReadAll(rc); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *imageVariantRepository) Save(ctx context.Context, variant *domain.ImageVariant) error {
+	primary := r.blobs.Primary()
+	row := *variant
+	row.Backend = primary.Name()
+	if row.Backend != storage.BackendPostgres {
+		if err := primary.Put(ctx, row.BlobKey(), bytes.NewReader(row.Data), row.Size, row.ContentType); err != nil {
+			return apperror.Internal(err)
+		}
+		row.Data = nil
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "image_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "backend", "content_type", "size", "width", "height", "sha256", "created_at"}),
+	}).Create(&row).Error
+	if err != nil {
+		if row.Backend != storage.BackendPostgres {
+			_ = primary.Delete(context.WithoutCancel(ctx), row.BlobKey()) // best effort: nothing points at it
+		}
+		if isForeignKeyViolation(err) {
+			return apperror.NotFound("Image")
+		}
+		return apperror.Internal(err)
+	}
+	variant.Backend = row.Backend
+	variant.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *imageVariantRepository) DropBackend(ctx context.Context, backend string) (int64, error) {
+	var dropped int64
+	err := r.db.WithContext(ctx).Raw(`
+		WITH gone AS (
+			DELETE FROM image_variants WHERE backend = ?
+			RETURNING image_id, name
+		), queued AS (
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT ?, image_id::text || '_' || name, now() FROM gone WHERE ? <> ?
+		)
+		SELECT count(*) FROM gone`,
+		backend, backend, backend, storage.BackendPostgres).Scan(&dropped).Error
+	if err != nil {
+		return 0, apperror.Internal(err)
+	}
+	return dropped, nil
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign_key_violation (23503).
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notifications []domain.Notification) error
+	// List returns the user's notifications, newest first, together with the
+	// total matching and the number of unread ones.
+	List(ctx context.Context, userID uuid.UUID, unreadOnly bool, page, perPage int) ([]domain.Notification, int64, int64, error)
+	// MarkRead marks the given notifications of the user as read, or all of
+	// them when ids is empty, and returns how many changed.
+	MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error)
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(ctx context.Context, notifications []domain.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(&notifications).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *notificationRepository) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, page, perPage int) ([]domain.Notification, int64, int64, error) {
+	var notifications []domain.Notification
+	var total, unread int64
+
+	base := r.db.WithContext(ctx).Model(&domain.Notification{}).Where("user_id = ?", userID)
+	if err := base.Session(&gorm.Session{}).Where("read_at IS NULL").Count(&unread).Error; err != nil {
+		return nil, 0, 0, apperror.Internal(err)
+	}
+
+	q := base
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := q.Order("created_at DESC, id DESC").
+		Offset(offset).Limit(perPage).
+		Find(&notifications).Error; err != nil {
+		return nil, 0, 0, apperror.Internal(err)
+	}
+
+	return notifications, total, unread, nil
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	q := r.db.WithContext(ctx).
+		Model(&domain.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		q = q.Where("id IN ?", ids)
+	}
+	res := q.Update("read_at", time.Now())
+	if res.Error != nil {
+		return 0, apperror.Internal(res.Error)
+	}
+	return res.RowsAffected, nil
+}
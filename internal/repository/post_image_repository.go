@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/storage"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PostImageRepository manages post galleries. Every change re-derives the
+// post's cover (posts.image_id) and bumps its version in the same transaction.
+type PostImageRepository interface {
+	List(ctx context.Context, postID uuid.UUID) ([]domain.PostImage, error)
+	Get(ctx context.Context, postID, imageID uuid.UUID) (*domain.PostImage, error)
+	// Add inserts images into the gallery in the given order: appended, or in
+	// front (the first one becoming the cover) when front is set. Entries
+	// carrying an Image have it stored in the same transaction, or get the
+	// existing image with the same bytes. It returns Conflict if an image
+	// would be in the gallery twice.
+	Add(ctx context.Context, postID uuid.UUID, images []domain.PostImage, front bool) error
+	// Reorder sets the gallery order; imageIDs must list every image exactly once.
+	Reorder(ctx context.Context, postID uuid.UUID, imageIDs []uuid.UUID) error
+	UpdateMeta(ctx context.Context, image *domain.PostImage) error
+	// Remove takes an image out of the gallery and deletes it if nothing else
+	// references it.
+	Remove(ctx context.Context, postID, imageID uuid.UUID) error
+}
+
+type postImageRepository struct {
+	db    *gorm.DB
+	blobs *storage.Stores
+}
+
+func NewPostImageRepository(db *gorm.DB, blobs *storage.Stores) PostImageRepository {
+	return &postImageRepository{db: db, blobs: blobs}
+}
+
+// orderedImages is the Preload scope for Post.Images.
+func orderedImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func (r *postImageRepository) List(ctx context.Context, postID uuid.UUID) ([]domain.PostImage, error) {
+	var images []domain.PostImage
+	if err := r.db.WithContext(ctx).
+		Where("post_id = ?", postID).
+		Order("position ASC").
+		Find(&images).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return images, nil
+}
+
+func (r *postImageRepository) Get(ctx context.Context, postID, imageID uuid.UUID) (*domain.PostImage, error) {
+	var image domain.PostImage
+	err := r.db.WithContext(ctx).
+		First(&image, "post_id = ? AND image_id = ?", postID, imageID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Post image")
+		}
+		return nil, apperror.Internal(err)
+	}
+	return &image, nil
+}
+
+func (r *postImageRepository) Add(ctx context.Context, postID uuid.UUID, images []domain.PostImage, front bool) error {
+	var uploads []*domain.Image
+	for i := range images {
+		if images[i].Image != nil {
+			uploads = append(uploads, images[i].Image)
+		}
+	}
+	undo, err := putBlobs(ctx, r.blobs, uploads)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	err = r.mutate(ctx, postID, func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&domain.PostImage{}).Where("post_id = ?", postID).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count)+len(images) > domain.MaxPostImages {
+			return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+				fmt.Sprintf("A post can have at most %d images", domain.MaxPostImages))
+		}
+
+		start := int(count)
+		if front {
+			if err := tx.Model(&domain.PostImage{}).
+				Where("post_id = ?", postID).
+				Update("position", gorm.Expr("position + ?", len(images))).Error; err != nil {
+				return err
+			}
+			start = 0
+		}
+		ids := make([]uuid.UUID, len(images))
+		for i := range images {
+			if images[i].Image != nil {
+				if err := insertImage(tx, r.blobs, images[i].Image); err != nil {
+					return err
+				}
+				images[i].ImageID = images[i].Image.ID
+			}
+			images[i].PostID = postID
+			images[i].Position = start + i
+			ids[i] = images[i].ImageID
+		}
+
+		// Uploads of the same bytes are the same image, which a gallery
+		// holds only once.
+		var present int64
+		if err := tx.Model(&domain.PostImage{}).
+			Where("post_id = ? AND image_id IN ?", postID, ids).
+			Count(&present).Error; err != nil {
+			return err
+		}
+		if present > 0 || hasDuplicates(ids) {
+			return apperror.Conflict("The post's gallery already contains this image")
+		}
+		return tx.Create(&images).Error
+	})
+	if err != nil {
+		undo()
+	}
+	return err
+}
+
+func (r *postImageRepository) Reorder(ctx context.Context, postID uuid.UUID, imageIDs []uuid.UUID) error {
+	return r.mutate(ctx, postID, func(tx *gorm.DB) error {
+		var current []uuid.UUID
+		if err := tx.Model(&domain.PostImage{}).Where("post_id = ?", postID).Pluck("image_id", &current).Error; err != nil {
+			return err
+		}
+
+		known := make(map[uuid.UUID]bool, len(current))
+		for _, id := range current {
+			known[id] = true
+		}
+		seen := make(map[uuid.UUID]bool, len(imageIDs))
+		for _, id := range imageIDs {
+			if !known[id] || seen[id] {
+				return errIncompleteOrder
+			}
+			seen[id] = true
+		}
+		if len(seen) != len(current) {
+			return errIncompleteOrder
+		}
+
+		for pos, id := range imageIDs {
+			if err := tx.Model(&domain.PostImage{}).
+				Where("post_id = ? AND image_id = ?", postID, id).
+				Update("position", pos).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func hasDuplicates(ids []uuid.UUID) bool {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+var errIncompleteOrder = apperror.ValidationError([]apperror.FieldError{{
+	Field:   "image_ids",
+	Message: "Must list every image of the post exactly once",
+}})
+
+func (r *postImageRepository) UpdateMeta(ctx context.Context, image *domain.PostImage) error {
+	return r.mutate(ctx, image.PostID, func(tx *gorm.DB) error {
+		return tx.Model(image).
+			Select("caption", "alt").
+			Updates(image).Error
+	})
+}
+
+func (r *postImageRepository) Remove(ctx context.Context, postID, imageID uuid.UUID) error {
+	return r.mutate(ctx, postID, func(tx *gorm.DB) error {
+		var image domain.PostImage
+		if err := tx.First(&image, "post_id = ? AND image_id = ?", postID, imageID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.NotFound("Post image")
+			}
+			return err
+		}
+
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		// Close the gap so positions stay contiguous.
+		if err := tx.Model(&domain.PostImage{}).
+			Where("post_id = ? AND position > ?", postID, image.Position).
+			Update("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		// The cover must be re-derived before the image can count as
+		// unreferenced; mutate bumps the version afterwards.
+		if err := setPostCover(tx, postID); err != nil {
+			return err
+		}
+		_, err := deleteUnreferencedImages(tx, []uuid.UUID{imageID})
+		return err
+	})
+}
+
+// mutate runs fn with the post row locked, then re-derives the cover and
+// bumps the post version so ETags held by clients go stale.
+func (r *postImageRepository) mutate(ctx context.Context, postID uuid.UUID, fn func(tx *gorm.DB) error) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Exec("SELECT 1 FROM posts WHERE id = ? AND deleted_at IS NULL FOR UPDATE", postID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apperror.NotFound("Post")
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return syncPostCover(tx, postID)
+	})
+	if err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) {
+			return appErr
+		}
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// firstGalleryImage selects the cover of the post given as its parameter.
+const firstGalleryImage = `(SELECT image_id FROM post_images WHERE post_id = ? ORDER BY position LIMIT 1)`
+
+// syncPostCover points posts.image_id at the first gallery image and bumps
+// the post version.
+func syncPostCover(tx *gorm.DB, postID uuid.UUID) error {
+	return tx.Exec(`
+		UPDATE posts SET
+			image_id = `+firstGalleryImage+`,
+			version = version + 1,
+			updated_at = ?
+		WHERE id = ?`,
+		postID, time.Now(), postID).Error
+}
+
+// setPostCover points posts.image_id at the first gallery image, leaving the
+// version to the caller.
+func setPostCover(tx *gorm.DB, postID uuid.UUID) error {
+	return tx.Exec("UPDATE posts SET image_id = "+firstGalleryImage+" WHERE id = ?", postID, postID).Error
+}
+
+// clearGallery removes every gallery entry of the post, deleting the images
+// nothing else references. The caller owns the post row lock and the cover.
+func clearGallery(tx *gorm.DB, postID uuid.UUID) error {
+	var imageIDs []uuid.UUID
+	if err := tx.Model(&domain.PostImage{}).Where("post_id = ?", postID).Pluck("image_id", &imageIDs).Error; err != nil {
+		return err
+	}
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("post_id = ?", postID).Delete(&domain.PostImage{}).Error; err != nil {
+		return err
+	}
+	_, err := deleteUnreferencedImages(tx, imageIDs)
+	return err
+}
@@ -0,0 +1,76 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Every gallery change bumps the post version exactly once, so a client can
+// tell the ETag of the post it just changed.
+func TestPostImageChangesBumpVersionOnce(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPostImageRepository(db, newTestStores(t, db))
+	userID := createTestUser(t, db)
+	ctx := context.Background()
+
+	post := domain.Post{UserID: userID, Title: "Gallery", Body: "Body"}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM posts WHERE id = ?", post.ID) })
+
+	readVersion := func() int64 {
+		t.Helper()
+		var v int64
+		if err := db.Model(&domain.Post{}).Where("id = ?", post.ID).Pluck("version", &v).Error; err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	version := readVersion()
+	expectBump := func(change string) {
+		t.Helper()
+		if got := readVersion(); got != version+1 {
+			t.Errorf("%s: version %d → %d, want %d", change, version, got, version+1)
+		}
+		version++
+	}
+
+	images := []domain.PostImage{
+		{Image: newTestImage(randomBytes(t, 100), &userID, 0)},
+		{Image: newTestImage(randomBytes(t, 100), &userID, 0)},
+	}
+	if err := repo.Add(ctx, post.ID, images, false); err != nil {
+		t.Fatal(err)
+	}
+	expectBump("Add")
+
+	first, second := images[0].ImageID, images[1].ImageID
+	if err := repo.Reorder(ctx, post.ID, []uuid.UUID{second, first}); err != nil {
+		t.Fatal(err)
+	}
+	expectBump("Reorder")
+
+	if err := repo.UpdateMeta(ctx, &domain.PostImage{PostID: post.ID, ImageID: first, Caption: "Caption"}); err != nil {
+		t.Fatal(err)
+	}
+	expectBump("UpdateMeta")
+
+	if err := repo.Remove(ctx, post.ID, second); err != nil {
+		t.Fatal(err)
+	}
+	expectBump("Remove")
+
+	var cover uuid.UUID
+	if err := db.Model(&domain.Post{}).Where("id = ?", post.ID).Pluck("image_id", &cover).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cover != first {
+		t.Errorf("cover = %s after removing the first image, want %s", cover, first)
+	}
+}
@@ -2,21 +2,132 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/acidsoft/gorestteach/internal/domain"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostRepository interface {
 	Create(ctx context.Context, post *domain.Post) error
-	GetByID(ctx context.Context, id uuid.UUID) (*domain.Post, error)
-	List(ctx context.Context, page, perPage int, search string) ([]domain.Post, int64, error)
-	Update(ctx context.Context, post *domain.Post) error
-	Delete(ctx context.Context, id uuid.UUID) error
-	UpdateImage(ctx context.Context, postID, imageID uuid.UUID) error
+	// GetByID returns the post if viewerID may see it, NOT_FOUND otherwise.
+	GetByID(ctx context.Context, id, viewerID uuid.UUID) (*domain.Post, error)
+	// List returns the posts listed for filter.ViewerID; unlisted posts of
+	// other users are left out.
+	List(ctx context.Context, filter PostFilter) ([]domain.Post, int64, error)
+	// Update saves the post and, if title or body changed, records a new
+	// revision attributed to editorID in the same transaction. The write only
+	// applies while the stored version still equals post.Version; otherwise it
+	// fails with PRECONDITION_FAILED. On success post.Version is bumped.
+	Update(ctx context.Context, post *domain.Post, editorID uuid.UUID) error
+	// UpdateWithTags is Update that also replaces the post's tag set.
+	UpdateWithTags(ctx context.Context, post *domain.Post, editorID uuid.UUID) error
+	// Delete soft-deletes the post, moving it to the owner's trash. When
+	// version is set the delete only applies to that exact version.
+	Delete(ctx context.Context, id uuid.UUID, version *int64) error
+	// GetDeletedByID returns a post that is currently in the trash.
+	GetDeletedByID(ctx context.Context, id uuid.UUID) (*domain.Post, error)
+	// ListTrash returns the user's soft-deleted posts, most recently deleted first.
+	ListTrash(ctx context.Context, userID uuid.UUID, page, perPage int) ([]domain.Post, int64, error)
+	// Restore moves a post out of the trash, NOT_FOUND if it is not in it.
+	Restore(ctx context.Context, id uuid.UUID) error
+	// PurgeDeletedBefore permanently removes up to limit posts trashed before
+	// cutoff, together with the images only they referenced.
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time, limit int) (PurgeResult, error)
+	// Feed returns the posts listed for followerID by users they follow, newest first,
+	// strictly older than after (keyset pagination) when it is set.
+	Feed(ctx context.Context, followerID uuid.UUID, after *FeedCursor, limit int) ([]domain.Post, error)
+}
+
+// PostFilter narrows and orders a post listing. Nil/zero fields mean "no filter".
+type PostFilter struct {
+	ViewerID    uuid.UUID // whose visibility applies; always set
+	Page        int
+	PerPage     int
+	Search      string
+	UserID      *uuid.UUID
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	HasImage    *bool
+	Tag         string // normalized slug
+	Sort        []SortField
+}
+
+// SortField is a single ORDER BY term. Column must come from an allowlist —
+// it is interpolated into SQL as-is.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// PurgeResult reports what a single purge batch removed.
+type PurgeResult struct {
+	Posts  int64
+	Images int64
+}
+
+// FeedCursor identifies the last post of a feed page: (created_at, id) is the
+// keyset the feed is ordered by.
+type FeedCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// errStalePost aborts a post write whose version no longer matches the row.
+var errStalePost = errors.New("post version changed")
+
+// postWriteError maps a failed post write transaction to an AppError.
+func postWriteError(err error) error {
+	if errors.Is(err, errStalePost) {
+		return apperror.PreconditionFailed("The post has been modified since it was read")
+	}
+	return apperror.Internal(err)
+}
+
+// ─── Visibility ───────────────────────────────────────────────────────────────
+
+// followerSeesPost matches followers-only posts whose author @viewer follows.
+const followerSeesPost = `(posts.visibility = 'followers' AND EXISTS (
+	SELECT 1 FROM follows
+	WHERE follows.follower_id = @viewer AND follows.followee_id = posts.user_id))`
+
+// moderationAllowsPost hides posts taken down by moderation: hidden ones
+// from everyone but their author, removed ones from everyone.
+const moderationAllowsPost = `(posts.moderation = 'visible'
+	OR (posts.moderation = 'hidden' AND posts.user_id = @viewer))`
+
+// visiblePostCond matches the posts @viewer may open by link.
+const visiblePostCond = `((posts.visibility IN ('public', 'unlisted')
+	OR posts.user_id = @viewer
+	OR ` + followerSeesPost + `)
+	AND ` + moderationAllowsPost + `)`
+
+// listedPostCond matches the posts @viewer may see in listings and feeds:
+// like visiblePostCond, minus other users' unlisted posts.
+const listedPostCond = `((posts.visibility = 'public'
+	OR posts.user_id = @viewer
+	OR ` + followerSeesPost + `)
+	AND ` + moderationAllowsPost + `)`
+
+// visibleTo is a scope limiting a posts query to what viewerID may open.
+func visibleTo(viewerID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(visiblePostCond, sql.Named("viewer", viewerID))
+	}
+}
+
+// listedFor is a scope limiting a posts query to what viewerID may see listed.
+func listedFor(viewerID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(listedPostCond, sql.Named("viewer", viewerID))
+	}
 }
 
 type postRepository struct {
@@ -27,17 +138,33 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 	return &postRepository{db: db}
 }
 
+// Create inserts the post together with its tags (post.Tags) and its first
+// revision atomically.
 func (r *postRepository) Create(ctx context.Context, post *domain.Post) error {
-	if err := r.db.WithContext(ctx).Create(post).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Create(post).Error; err != nil {
+			return err
+		}
+		if err := replacePostTags(tx, post.ID, post.Tags); err != nil {
+			return err
+		}
+		return recordRevision(tx, post, post.UserID)
+	})
+	if err != nil {
 		return apperror.Internal(err)
 	}
 	return nil
 }
 
-func (r *postRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Post, error) {
+func (r *postRepository) GetByID(ctx context.Context, id, viewerID uuid.UUID) (*domain.Post, error) {
 	var post domain.Post
+	// Posts the viewer may not see are reported as missing, not forbidden,
+	// so that their existence does not leak.
 	err := r.db.WithContext(ctx).
+		Scopes(visibleTo(viewerID)).
 		Preload("User"). // eager-load author info
+		Preload("Tags").
+		Preload("Images", orderedImages).
 		First(&post, "posts.id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -48,24 +175,69 @@ func (r *postRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Pos
 	return &post, nil
 }
 
-// List supports pagination and optional full-text search on title/body.
-func (r *postRepository) List(ctx context.Context, page, perPage int, search string) ([]domain.Post, int64, error) {
+// List supports pagination, filtering, sorting and optional search on title/body.
+func (r *postRepository) List(ctx context.Context, filter PostFilter) ([]domain.Post, int64, error) {
 	var posts []domain.Post
 	var total int64
 
-	q := r.db.WithContext(ctx).Model(&domain.Post{}).Preload("User")
-	if search != "" {
-		pattern := "%" + search + "%"
-		q = q.Where("title ILIKE ? OR body ILIKE ?", pattern, pattern)
+	q := r.db.WithContext(ctx).Model(&domain.Post{}).
+		Scopes(listedFor(filter.ViewerID)).
+		Preload("User").
+		Preload("Tags").
+		Preload("Images", orderedImages)
+	if filter.Search != "" {
+		pattern := "%" + filter.Search + "%"
+		q = q.Where("(posts.title ILIKE ? OR posts.body ILIKE ?)", pattern, pattern)
+	}
+	if filter.UserID != nil {
+		q = q.Where("posts.user_id = ?", *filter.UserID)
+	}
+	if filter.CreatedFrom != nil {
+		q = q.Where("posts.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		q = q.Where("posts.created_at <= ?", *filter.CreatedTo)
+	}
+	if filter.UpdatedFrom != nil {
+		q = q.Where("posts.updated_at >= ?", *filter.UpdatedFrom)
+	}
+	if filter.UpdatedTo != nil {
+		q = q.Where("posts.updated_at <= ?", *filter.UpdatedTo)
+	}
+	if filter.Tag != "" {
+		q = q.Where(`EXISTS (
+			SELECT 1 FROM post_tags
+			JOIN tags ON tags.id = post_tags.tag_id
+			WHERE post_tags.post_id = posts.id AND tags.slug = ?)`, filter.Tag)
+	}
+	if filter.HasImage != nil {
+		if *filter.HasImage {
+			q = q.Where("posts.image_id IS NOT NULL")
+		} else {
+			q = q.Where("posts.image_id IS NULL")
+		}
 	}
 
 	if err := q.Count(&total).Error; err != nil {
 		return nil, 0, apperror.Internal(err)
 	}
 
-	offset := (page - 1) * perPage
-	if err := q.Offset(offset).Limit(perPage).
-		Order("created_at DESC").
+	sort := filter.Sort
+	if len(sort) == 0 {
+		sort = []SortField{{Column: "created_at", Desc: true}}
+	}
+	for _, f := range sort {
+		dir := "ASC"
+		if f.Desc {
+			dir = "DESC"
+		}
+		q = q.Order("posts." + f.Column + " " + dir)
+	}
+	// Tie-breaker keeps pagination stable when sort keys collide.
+	q = q.Order("posts.id DESC")
+
+	offset := (filter.Page - 1) * filter.PerPage
+	if err := q.Offset(offset).Limit(filter.PerPage).
 		Find(&posts).Error; err != nil {
 		return nil, 0, apperror.Internal(err)
 	}
@@ -73,26 +245,211 @@ func (r *postRepository) List(ctx context.Context, page, perPage int, search str
 	return posts, total, nil
 }
 
-func (r *postRepository) Update(ctx context.Context, post *domain.Post) error {
-	if err := r.db.WithContext(ctx).Save(post).Error; err != nil {
-		return apperror.Internal(err)
+func (r *postRepository) Update(ctx context.Context, post *domain.Post, editorID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return savePostWithRevision(tx, post, editorID)
+	})
+	if err != nil {
+		return postWriteError(err)
 	}
 	return nil
 }
 
-func (r *postRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	if err := r.db.WithContext(ctx).Delete(&domain.Post{}, "id = ?", id).Error; err != nil {
-		return apperror.Internal(err)
+func (r *postRepository) UpdateWithTags(ctx context.Context, post *domain.Post, editorID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := savePostWithRevision(tx, post, editorID); err != nil {
+			return err
+		}
+		return replacePostTags(tx, post.ID, post.Tags)
+	})
+	if err != nil {
+		return postWriteError(err)
+	}
+	return nil
+}
+
+// savePostWithRevision writes the post only if its version is unchanged and
+// records the revision. Only content columns are written — tags are handled
+// separately and counters belong to other repositories. The UPDATE keeps the row locked until commit, so
+// concurrent edits also get distinct, gap-free revision numbers: a writer that
+// waited on the lock re-checks the version and matches zero rows.
+func savePostWithRevision(tx *gorm.DB, post *domain.Post, editorID uuid.UUID) error {
+	now := time.Now()
+	res := tx.Model(&domain.Post{}).
+		Where("id = ? AND version = ?", post.ID, post.Version).
+		Updates(map[string]any{
+			"title":       post.Title,
+			"body":        post.Body,
+			"body_format": post.BodyFormat,
+			"body_html":   post.BodyHTML,
+			"visibility":  post.Visibility,
+			"image_id":    post.ImageID,
+			"version":     gorm.Expr("version + 1"),
+			"updated_at":  now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errStalePost
+	}
+	post.Version++
+	post.UpdatedAt = now
+
+	// The cover is derived from the gallery, so removing it empties the gallery.
+	if post.ImageID == nil {
+		if err := clearGallery(tx, post.ID); err != nil {
+			return err
+		}
+		post.Images = nil
+	}
+	return recordRevision(tx, post, editorID)
+}
+
+func (r *postRepository) Delete(ctx context.Context, id uuid.UUID, version *int64) error {
+	q := r.db.WithContext(ctx).Where("id = ?", id)
+	if version != nil {
+		q = q.Where("version = ?", *version)
+	}
+	res := q.Delete(&domain.Post{})
+	if res.Error != nil {
+		return apperror.Internal(res.Error)
+	}
+	if version != nil && res.RowsAffected == 0 {
+		return postWriteError(errStalePost)
 	}
 	return nil
 }
 
-func (r *postRepository) UpdateImage(ctx context.Context, postID, imageID uuid.UUID) error {
-	if err := r.db.WithContext(ctx).
+func (r *postRepository) GetDeletedByID(ctx context.Context, id uuid.UUID) (*domain.Post, error) {
+	var post domain.Post
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("posts.deleted_at IS NOT NULL").
+		First(&post, "posts.id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Deleted post")
+		}
+		return nil, apperror.Internal(err)
+	}
+	return &post, nil
+}
+
+func (r *postRepository) ListTrash(ctx context.Context, userID uuid.UUID, page, perPage int) ([]domain.Post, int64, error) {
+	var posts []domain.Post
+	var total int64
+
+	q := r.db.WithContext(ctx).
+		Unscoped().
 		Model(&domain.Post{}).
-		Where("id = ?", postID).
-		Update("image_id", imageID).Error; err != nil {
-		return apperror.Internal(err)
+		Where("posts.user_id = ? AND posts.deleted_at IS NOT NULL", userID)
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := q.Preload("Tags").
+		Preload("Images", orderedImages).
+		Order("posts.deleted_at DESC, posts.id DESC").
+		Offset(offset).Limit(perPage).
+		Find(&posts).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return posts, total, nil
+}
+
+func (r *postRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	res := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&domain.Post{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		return apperror.Internal(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		// Purged or restored since it was read.
+		return apperror.NotFound("Post")
 	}
 	return nil
 }
+
+func (r *postRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time, limit int) (PurgeResult, error) {
+	var result PurgeResult
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var victims []domain.Post
+		// SKIP LOCKED lets several purger instances work side by side.
+		if err := tx.Unscoped().
+			Select("id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("deleted_at ASC").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&victims).Error; err != nil {
+			return err
+		}
+		if len(victims) == 0 {
+			return nil
+		}
+
+		postIDs := make([]uuid.UUID, 0, len(victims))
+		for _, p := range victims {
+			postIDs = append(postIDs, p.ID)
+		}
+		var imageIDs []uuid.UUID
+		if err := tx.Model(&domain.PostImage{}).
+			Where("post_id IN ?", postIDs).
+			Pluck("image_id", &imageIDs).Error; err != nil {
+			return err
+		}
+
+		// Hard delete — FK cascades clean up comments, reactions, bookmarks,
+		// tag links and gallery entries.
+		res := tx.Unscoped().Where("id IN ?", postIDs).Delete(&domain.Post{})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Posts = res.RowsAffected
+
+		deleted, err := deleteUnreferencedImages(tx, imageIDs)
+		if err != nil {
+			return err
+		}
+		result.Images = deleted
+		return nil
+	})
+	if err != nil {
+		return PurgeResult{}, apperror.Internal(err)
+	}
+	return result, nil
+}
+
+func (r *postRepository) Feed(ctx context.Context, followerID uuid.UUID, after *FeedCursor, limit int) ([]domain.Post, error) {
+	var posts []domain.Post
+
+	// The semi-join lets Postgres walk idx_posts_user_created_id per followee
+	// (or idx_posts_created_at_id with a hashed filter for huge follow lists)
+	// instead of materializing every followed user's posts.
+	q := r.db.WithContext(ctx).
+		Model(&domain.Post{}).
+		Preload("User").
+		Preload("Tags").
+		Preload("Images", orderedImages).
+		Where("posts.user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)", followerID).
+		Scopes(listedFor(followerID))
+	if after != nil {
+		q = q.Where("(posts.created_at, posts.id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	if err := q.Order("posts.created_at DESC, posts.id DESC").
+		Limit(limit).
+		Find(&posts).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	return posts, nil
+}
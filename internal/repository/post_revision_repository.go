@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PostRevisionRepository interface {
+	// List returns the post's revisions, newest first, without their bodies.
+	List(ctx context.Context, postID uuid.UUID, page, perPage int) ([]domain.PostRevision, int64, error)
+	Get(ctx context.Context, postID uuid.UUID, revision int) (*domain.PostRevision, error)
+	// LatestNumber returns the highest revision number of the post (0 if none).
+	LatestNumber(ctx context.Context, postID uuid.UUID) (int, error)
+}
+
+type postRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostRevisionRepository(db *gorm.DB) PostRevisionRepository {
+	return &postRevisionRepository{db: db}
+}
+
+func (r *postRevisionRepository) List(ctx context.Context, postID uuid.UUID, page, perPage int) ([]domain.PostRevision, int64, error) {
+	var revisions []domain.PostRevision
+	var total int64
+
+	q := r.db.WithContext(ctx).Model(&domain.PostRevision{}).Where("post_id = ?", postID)
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := q.Omit("body").
+		Order("revision DESC").
+		Offset(offset).Limit(perPage).
+		Find(&revisions).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return revisions, total, nil
+}
+
+func (r *postRevisionRepository) Get(ctx context.Context, postID uuid.UUID, revision int) (*domain.PostRevision, error) {
+	var rev domain.PostRevision
+	err := r.db.WithContext(ctx).
+		First(&rev, "post_id = ? AND revision = ?", postID, revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Revision")
+		}
+		return nil, apperror.Internal(err)
+	}
+	return &rev, nil
+}
+
+func (r *postRevisionRepository) LatestNumber(ctx context.Context, postID uuid.UUID) (int, error) {
+	var latest int
+	if err := r.db.WithContext(ctx).
+		Model(&domain.PostRevision{}).
+		Where("post_id = ?", postID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return 0, apperror.Internal(err)
+	}
+	return latest, nil
+}
+
+// recordRevision appends a snapshot of the post's title and body, unless they
+// are identical to the latest revision (e.g. a tags-only edit). The caller
+// must hold the post row lock (or be creating the post).
+func recordRevision(tx *gorm.DB, post *domain.Post, editorID uuid.UUID) error {
+	var latest domain.PostRevision
+	err := tx.Where("post_id = ?", post.ID).Order("revision DESC").Take(&latest).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		latest.Revision = 0
+	case err != nil:
+		return err
+	case latest.Title == post.Title && latest.Body == post.Body:
+		return nil
+	}
+
+	return tx.Create(&domain.PostRevision{
+		PostID:   post.ID,
+		Revision: latest.Revision + 1,
+		EditorID: editorID,
+		Title:    post.Title,
+		Body:     post.Body,
+	}).Error
+}
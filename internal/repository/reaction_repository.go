@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReactionRepository interface {
+	// Set creates or changes the user's reaction to a post.
+	Set(ctx context.Context, postID, userID uuid.UUID, kind domain.ReactionKind) error
+	// Remove deletes the user's reaction, if any.
+	Remove(ctx context.Context, postID, userID uuid.UUID) error
+	// Summaries aggregates reactions for many posts in two queries, including
+	// viewerID's own reaction. Posts without reactions get an empty summary.
+	Summaries(ctx context.Context, postIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID]domain.ReactionSummary, error)
+}
+
+type reactionRepository struct {
+	db *gorm.DB
+}
+
+func NewReactionRepository(db *gorm.DB) ReactionRepository {
+	return &reactionRepository{db: db}
+}
+
+func (r *reactionRepository) Set(ctx context.Context, postID, userID uuid.UUID, kind domain.ReactionKind) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockAndGetReaction(tx, postID, userID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case existing == nil:
+			if err := tx.Create(&domain.PostReaction{PostID: postID, UserID: userID, Kind: kind}).Error; err != nil {
+				return err
+			}
+		case existing.Kind == kind:
+			return nil
+		default:
+			if err := tx.Model(existing).Update("kind", kind).Error; err != nil {
+				return err
+			}
+			if err := adjustReactionCount(tx, postID, existing.Kind, -1); err != nil {
+				return err
+			}
+		}
+		return adjustReactionCount(tx, postID, kind, 1)
+	})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *reactionRepository) Remove(ctx context.Context, postID, userID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockAndGetReaction(tx, postID, userID)
+		if err != nil || existing == nil {
+			return err
+		}
+		if err := tx.Delete(existing).Error; err != nil {
+			return err
+		}
+		return adjustReactionCount(tx, postID, existing.Kind, -1)
+	})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *reactionRepository) Summaries(ctx context.Context, postIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID]domain.ReactionSummary, error) {
+	result := make(map[uuid.UUID]domain.ReactionSummary, len(postIDs))
+	for _, id := range postIDs {
+		result[id] = domain.ReactionSummary{Counts: map[domain.ReactionKind]int64{}}
+	}
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	var counts []domain.PostReactionCount
+	if err := r.db.WithContext(ctx).
+		Where("post_id IN ? AND count > 0", postIDs).
+		Find(&counts).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+	for _, c := range counts {
+		result[c.PostID].Counts[c.Kind] = c.Count
+	}
+
+	var mine []domain.PostReaction
+	if err := r.db.WithContext(ctx).
+		Where("post_id IN ? AND user_id = ?", postIDs, viewerID).
+		Find(&mine).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+	for _, m := range mine {
+		s := result[m.PostID]
+		kind := m.Kind
+		s.Mine = &kind
+		result[m.PostID] = s
+	}
+
+	return result, nil
+}
+
+// lockAndGetReaction serializes concurrent toggles by the same user on the same
+// post with a transaction-scoped advisory lock — a row lock can't help when the
+// row doesn't exist yet — and then reads the current reaction (nil if none).
+// Different users never contend; the counters themselves are updated with
+// atomic increments.
+func lockAndGetReaction(tx *gorm.DB, postID, userID uuid.UUID) (*domain.PostReaction, error) {
+	if err := tx.Exec(
+		"SELECT pg_advisory_xact_lock(hashtextextended(?, 0))",
+		"post_reaction:"+postID.String()+":"+userID.String(),
+	).Error; err != nil {
+		return nil, err
+	}
+
+	var reaction domain.PostReaction
+	err := tx.Where("post_id = ? AND user_id = ?", postID, userID).Take(&reaction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reaction, nil
+}
+
+func adjustReactionCount(tx *gorm.DB, postID uuid.UUID, kind domain.ReactionKind, delta int64) error {
+	if delta > 0 {
+		return tx.Exec(`
+			INSERT INTO post_reaction_counts (post_id, kind, count) VALUES (?, ?, ?)
+			ON CONFLICT (post_id, kind) DO UPDATE SET count = post_reaction_counts.count + EXCLUDED.count`,
+			postID, kind, delta).Error
+	}
+	return tx.Exec(
+		"UPDATE post_reaction_counts SET count = GREATEST(count + ?, 0) WHERE post_id = ? AND kind = ?",
+		delta, postID, kind,
+	).Error
+}
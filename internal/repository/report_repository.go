@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReportRepository interface {
+	// Create files the report; reporting the same content twice is a
+	// CONFLICT. When threshold > 0 and the content now has that many open
+	// reports, visible content is hidden pending review and the auto-hide is
+	// audited in the same transaction; hidden tells whether that happened.
+	Create(ctx context.Context, report *domain.Report, threshold int) (hidden bool, err error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Report, error)
+	// List returns reports oldest first, so the queue is worked in order.
+	List(ctx context.Context, filter ReportFilter) ([]domain.Report, int64, error)
+	// Resolve applies a moderator's decision: it closes every open report on
+	// the reported content, changes the content's moderation status, suspends
+	// its author if asked to and records the audit entry, all atomically.
+	Resolve(ctx context.Context, res *ReportResolution) (*ResolveResult, error)
+}
+
+// ReportFilter narrows the moderation queue. Zero fields mean "any".
+type ReportFilter struct {
+	Status     domain.ReportStatus
+	TargetType domain.ReportTargetType
+	Page       int
+	PerPage    int
+}
+
+// ReportResolution is a moderator's decision on Report.
+type ReportResolution struct {
+	Report     *domain.Report
+	ResolvedBy uuid.UUID
+	Status     domain.ReportStatus // dismissed or actioned
+	// Moderation is the content's new status. ModerationVisible only lifts
+	// an auto-hide; it never brings back removed content.
+	Moderation domain.ModerationStatus
+	// SuspendUntil, when set, suspends the content's author and revokes
+	// their refresh tokens. An existing longer suspension or a ban is kept.
+	SuspendUntil *time.Time
+	Audit        domain.AuditEntry
+}
+
+// ResolveResult reports what a resolution changed.
+type ResolveResult struct {
+	ReporterIDs    []uuid.UUID // authors of the reports that were closed
+	ContentChanged bool        // the content's moderation status changed
+}
+
+type reportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+// moderatedTable maps a report target to its table. The result is
+// interpolated into SQL, so it must only ever come from this allowlist.
+func moderatedTable(t domain.ReportTargetType) string {
+	switch t {
+	case domain.ReportTargetPost:
+		return "posts"
+	case domain.ReportTargetComment:
+		return "comments"
+	}
+	panic("unknown report target type: " + string(t))
+}
+
+func (r *reportRepository) Create(ctx context.Context, report *domain.Report, threshold int) (bool, error) {
+	hidden := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(report)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apperror.Conflict("You have already reported this content")
+		}
+
+		if threshold <= 0 {
+			return nil
+		}
+		var open int64
+		if err := tx.Model(&domain.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, domain.ReportOpen).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open < int64(threshold) {
+			return nil
+		}
+
+		res = tx.Exec(
+			"UPDATE "+moderatedTable(report.TargetType)+" SET moderation = ? WHERE id = ? AND moderation = ?",
+			domain.ModerationHidden, report.TargetID, domain.ModerationVisible)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil // already hidden or removed
+		}
+		hidden = true
+		return tx.Create(&domain.AuditEntry{
+			Action:     domain.AuditAutoHide,
+			TargetType: report.TargetType,
+			TargetID:   report.TargetID,
+			ReportID:   &report.ID,
+			Note:       fmt.Sprintf("Hidden automatically after %d reports", open),
+		}).Error
+	})
+	if err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) {
+			return false, appErr
+		}
+		return false, apperror.Internal(err)
+	}
+	return hidden, nil
+}
+
+func (r *reportRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Report, error) {
+	var report domain.Report
+	if err := r.db.WithContext(ctx).First(&report, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Report")
+		}
+		return nil, apperror.Internal(err)
+	}
+	return &report, nil
+}
+
+func (r *reportRepository) List(ctx context.Context, filter ReportFilter) ([]domain.Report, int64, error) {
+	var reports []domain.Report
+	var total int64
+
+	q := r.db.WithContext(ctx).Model(&domain.Report{})
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.TargetType != "" {
+		q = q.Where("target_type = ?", filter.TargetType)
+	}
+
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (filter.Page - 1) * filter.PerPage
+	if err := q.Order("created_at ASC, id ASC").
+		Offset(offset).Limit(filter.PerPage).
+		Find(&reports).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return reports, total, nil
+}
+
+func (r *reportRepository) Resolve(ctx context.Context, res *ReportResolution) (*ResolveResult, error) {
+	report := res.Report
+	result := &ResolveResult{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the open reports on the content so two moderators cannot
+		// resolve the same case twice.
+		var open []domain.Report
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, domain.ReportOpen).
+			Find(&open).Error; err != nil {
+			return err
+		}
+		stillOpen := false
+		for _, o := range open {
+			result.ReporterIDs = append(result.ReporterIDs, o.ReporterID)
+			stillOpen = stillOpen || o.ID == report.ID
+		}
+		if !stillOpen {
+			return apperror.Conflict("The report has already been resolved")
+		}
+
+		now := time.Now()
+		if err := tx.Model(&domain.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, domain.ReportOpen).
+			Updates(map[string]any{
+				"status":      res.Status,
+				"resolved_by": res.ResolvedBy,
+				"resolved_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		report.Status = res.Status
+		report.ResolvedBy = &res.ResolvedBy
+		report.ResolvedAt = &now
+
+		from := "moderation <> ?"
+		fromArg := any(res.Moderation)
+		if res.Moderation == domain.ModerationVisible {
+			from, fromArg = "moderation = ?", domain.ModerationHidden
+		}
+		changed := tx.Exec(
+			"UPDATE "+moderatedTable(report.TargetType)+" SET moderation = ? WHERE id = ? AND "+from,
+			res.Moderation, report.TargetID, fromArg)
+		if changed.Error != nil {
+			return changed.Error
+		}
+		result.ContentChanged = changed.RowsAffected > 0
+
+		if res.SuspendUntil != nil {
+			// A banned author stays banned; a suspension still running
+			// keeps its end if that is later.
+			if err := tx.Exec(`
+				UPDATE users SET
+					suspended_until = CASE WHEN status = ? THEN GREATEST(suspended_until, ?) ELSE ? END,
+					status = ?
+				WHERE id = ? AND status <> ?`,
+				domain.AccountSuspended, *res.SuspendUntil, *res.SuspendUntil,
+				domain.AccountSuspended,
+				report.TargetAuthorID, domain.AccountBanned,
+			).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ?", report.TargetAuthorID).
+				Delete(&domain.RefreshToken{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&res.Audit).Error
+	})
+	if err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) {
+			return nil, appErr
+		}
+		return nil, apperror.Internal(err)
+	}
+	return result, nil
+}
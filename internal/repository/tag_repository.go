@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepository interface {
+	GetBySlug(ctx context.Context, slug string) (*domain.Tag, error)
+	// ListWithCounts returns tags used by at least one live public post, most
+	// used first. Counts only include public posts so they are the same for
+	// every viewer.
+	// prefix must already be a normalized slug fragment.
+	ListWithCounts(ctx context.Context, prefix string, page, perPage int) ([]domain.TagWithCount, int64, error)
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) GetBySlug(ctx context.Context, slug string) (*domain.Tag, error) {
+	var tag domain.Tag
+	err := r.db.WithContext(ctx).First(&tag, "slug = ?", slug).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("Tag")
+		}
+		return nil, apperror.Internal(err)
+	}
+	return &tag, nil
+}
+
+func (r *tagRepository) ListWithCounts(ctx context.Context, prefix string, page, perPage int) ([]domain.TagWithCount, int64, error) {
+	var tags []domain.TagWithCount
+	var total int64
+
+	q := r.db.WithContext(ctx).
+		Table("tags").
+		Select("tags.slug, tags.name, COUNT(post_tags.post_id) AS post_count").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL AND posts.visibility = ? AND posts.moderation = ?",
+			domain.VisibilityPublic, domain.ModerationVisible).
+		Group("tags.id")
+	if prefix != "" {
+		// Normalized slugs never contain LIKE wildcards, so no escaping is needed.
+		q = q.Where("tags.slug LIKE ?", prefix+"%")
+	}
+
+	if err := r.db.WithContext(ctx).Table("(?) AS t", q).Count(&total).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	offset := (page - 1) * perPage
+	if err := q.Order("post_count DESC, tags.slug ASC").
+		Offset(offset).Limit(perPage).
+		Scan(&tags).Error; err != nil {
+		return nil, 0, apperror.Internal(err)
+	}
+
+	return tags, total, nil
+}
+
+// ─── Shared helpers (used inside post transactions) ───────────────────────────
+
+// upsertTags inserts any tags that don't exist yet and fills in the IDs of all
+// of them. The no-op DO UPDATE makes Postgres return the id of existing rows;
+// the existing display name is kept.
+func upsertTags(tx *gorm.DB, tags []domain.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "slug"}},
+		DoUpdates: clause.Assignments(map[string]any{"slug": gorm.Expr("EXCLUDED.slug")}),
+	}).Create(&tags).Error
+}
+
+// replacePostTags makes tags the exact tag set of the post.
+func replacePostTags(tx *gorm.DB, postID uuid.UUID, tags []domain.Tag) error {
+	if err := upsertTags(tx, tags); err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM post_tags WHERE post_id = ?", postID).Error; err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if err := tx.Exec(
+			"INSERT INTO post_tags (post_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			postID, t.ID,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/storage"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UploadRepository keeps resumable uploads and the chunks received for them.
+// Chunks are written to the primary storage backend as they arrive, each
+// under its own key, so a chunk that loses a race never overwrites one that
+// won.
+type UploadRepository interface {
+	Create(ctx context.Context, upload *domain.Upload) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Upload, error)
+	// Append stores the bytes read from r as the chunk at upload.Offset and
+	// advances upload past them. It returns Conflict if the upload has moved
+	// on since it was read or has no chunks left, and NotFound if it has been
+	// deleted. Reading nothing is not an error and stores nothing.
+	Append(ctx context.Context, upload *domain.Upload, r io.Reader) error
+	// Open reads the bytes of the chunks received for upload, in order.
+	Open(ctx context.Context, upload *domain.Upload) (io.ReadCloser, error)
+	// Delete deletes an upload and queues the bytes of its chunks for the
+	// blob sweeper.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// DeleteExpired deletes up to limit uploads that expired before `before`
+	// like Delete, and returns how many it deleted.
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type uploadRepository struct {
+	db    *gorm.DB
+	blobs *storage.Stores
+}
+
+func NewUploadRepository(db *gorm.DB, blobs *storage.Stores) UploadRepository {
+	return &uploadRepository{db: db, blobs: blobs}
+}
+
+func (r *uploadRepository) Create(ctx context.Context, upload *domain.Upload) error {
+	if err := r.db.WithContext(ctx).Create(upload).Error; err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *uploadRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Upload, error) {
+	var upload domain.Upload
+	err := r.db.WithContext(ctx).First(&upload, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.NotFound("Upload")
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return &upload, nil
+}
+
+// errUploadMoved reports that the uploads row did not match what Append expected.
+var errUploadMoved = errors.New("upload moved on")
+
+func (r *uploadRepository) Append(ctx context.Context, upload *domain.Upload, body io.Reader) error {
+	primary := r.blobs.Primary()
+	chunk := domain.UploadChunk{
+		ID:       uuid.New(),
+		UploadID: upload.ID,
+		Offset:   upload.Offset,
+		Backend:  primary.Name(),
+	}
+	discard := func() {}
+	if chunk.Backend == storage.BackendPostgres {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return apperror.Internal(err)
+		}
+		chunk.Data, chunk.Size = data, int64(len(data))
+	} else {
+		cr := &countingReader{r: body}
+		if err := primary.Put(ctx, chunk.BlobKey(), cr, -1, "application/octet-stream"); err != nil {
+			return apperror.Internal(err)
+		}
+		chunk.Size = cr.n
+		discard = func() {
+			_ = primary.Delete(context.WithoutCancel(ctx), chunk.BlobKey()) // best effort: nothing points at it
+		}
+	}
+	if chunk.Size == 0 {
+		discard()
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Exec(`
+			UPDATE uploads SET upload_offset = upload_offset + ?, chunks = chunks + 1, updated_at = now()
+			WHERE id = ? AND upload_offset = ? AND upload_offset + ? <= length AND chunks < ?`,
+			chunk.Size, upload.ID, upload.Offset, chunk.Size, domain.MaxUploadChunks)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errUploadMoved
+		}
+		return tx.Create(&chunk).Error
+	})
+	if errors.Is(err, errUploadMoved) {
+		discard()
+		current, getErr := r.GetByID(ctx, upload.ID)
+		if getErr != nil {
+			return getErr
+		}
+		if current.Chunks >= domain.MaxUploadChunks {
+			return apperror.Conflict(fmt.Sprintf(
+				"The upload has reached the maximum of %d chunks; start a new one", domain.MaxUploadChunks))
+		}
+		return apperror.Conflict("Upload-Offset does not match the upload's offset")
+	}
+	if err != nil {
+		discard()
+		return apperror.Internal(err)
+	}
+	upload.Offset += chunk.Size
+	upload.Chunks++
+	return nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func (r *uploadRepository) Open(ctx context.Context, upload *domain.Upload) (io.ReadCloser, error) {
+	var chunks []domain.UploadChunk
+	if err := r.db.WithContext(ctx).
+		Omit("data").
+		Where("upload_id = ?", upload.ID).
+		Order("upload_offset").
+		Find(&chunks).Error; err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return &chunkReader{ctx: ctx, repo: r, chunks: chunks}, nil
+}
+
+// openChunk opens the bytes of one chunk.
+func (r *uploadRepository) openChunk(ctx context.Context, chunk *domain.UploadChunk) (io.ReadCloser, error) {
+	if chunk.Backend == storage.BackendPostgres {
+		var rows []struct{ Data []byte }
+		if err := r.db.WithContext(ctx).
+			Raw("SELECT data FROM upload_chunks WHERE id = ?", chunk.ID).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return nil, storage.ErrNotFound
+		}
+		return io.NopCloser(bytes.NewReader(rows[0].Data)), nil
+	}
+
+	store, err := r.blobs.Get(chunk.Backend)
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(ctx, chunk.BlobKey())
+}
+
+// chunkReader reads the chunks of an upload one after the other, opening
+// each as it is reached.
+type chunkReader struct {
+	ctx    context.Context
+	repo   *uploadRepository
+	chunks []domain.UploadChunk
+	cur    io.ReadCloser
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if cr.cur == nil {
+			if len(cr.chunks) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := cr.repo.openChunk(cr.ctx, &cr.chunks[0])
+			if err != nil {
+				return 0, fmt.Errorf("open chunk %s: %w", cr.chunks[0].ID, err)
+			}
+			cr.cur, cr.chunks = rc, cr.chunks[1:]
+		}
+		n, err := cr.cur.Read(p)
+		if err == io.EOF {
+			cr.cur.Close()
+			cr.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (cr *chunkReader) Close() error {
+	if cr.cur == nil {
+		return nil
+	}
+	err := cr.cur.Close()
+	cr.cur = nil
+	return err
+}
+
+func (r *uploadRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = deleteUploads(tx, tx.Raw("SELECT id FROM uploads WHERE id = ? FOR UPDATE", id))
+		return err
+	})
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if deleted == 0 {
+		return apperror.NotFound("Upload")
+	}
+	return nil
+}
+
+func (r *uploadRepository) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = deleteUploads(tx, tx.Raw(`
+			SELECT id FROM uploads WHERE expires_at < ?
+			ORDER BY expires_at LIMIT ?
+			FOR UPDATE SKIP LOCKED`, before, limit))
+		return err
+	})
+	if err != nil {
+		return 0, apperror.Internal(err)
+	}
+	return deleted, nil
+}
+
+// deleteUploads deletes the uploads that query selects and locks, with their
+// chunks, and queues the chunks' blobs for deletion. The uploads are locked
+// by a statement of their own, so that the deletion sees every chunk
+// appended before the lock was granted.
+func deleteUploads(tx *gorm.DB, query *gorm.DB) (int64, error) {
+	var ids []uuid.UUID
+	if err := query.Scan(&ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	err := tx.Raw(`
+		WITH chunks AS (
+			DELETE FROM upload_chunks WHERE upload_id IN @ids
+			RETURNING id, backend
+		), queued AS (
+			INSERT INTO blob_tombstones (backend, key, created_at)
+			SELECT backend, id::text, now() FROM chunks WHERE backend <> @postgres
+		), gone AS (
+			DELETE FROM uploads WHERE id IN @ids
+			RETURNING id
+		)
+		SELECT count(*) FROM gone`,
+		sql.Named("ids", ids), sql.Named("postgres", storage.BackendPostgres)).Scan(&deleted).Error
+	return deleted, err
+}
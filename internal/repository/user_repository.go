@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/acidsoft/gorestteach/internal/domain"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
@@ -17,7 +18,12 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
+	// UpdateAvatar makes a saved image the user's avatar. It returns NotFound
+	// if the image has been deleted meanwhile.
 	UpdateAvatar(ctx context.Context, userID, avatarID uuid.UUID) error
+	// SetStatus changes the account status and records audit, atomically.
+	// Unless the new status is active, the user's refresh tokens are revoked.
+	SetStatus(ctx context.Context, userID uuid.UUID, status domain.AccountStatus, until *time.Time, audit *domain.AuditEntry) error
 }
 
 type userRepository struct {
@@ -59,18 +65,66 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.
 	return &user, nil
 }
 
+// Update saves the profile. Role and status are left alone so that a profile
+// edit cannot undo a concurrent staff decision.
 func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
-	if err := r.db.WithContext(ctx).Save(user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Omit("role", "status", "suspended_until").Save(user).Error; err != nil {
 		return apperror.Internal(err)
 	}
 	return nil
 }
 
 func (r *userRepository) UpdateAvatar(ctx context.Context, userID, avatarID uuid.UUID) error {
-	if err := r.db.WithContext(ctx).
-		Model(&domain.User{}).
-		Where("id = ?", userID).
-		Update("avatar_id", avatarID).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A saved image may be an existing one that is being deleted as
+		// unreferenced; the lock settles which happens first.
+		res := tx.Exec("SELECT 1 FROM images WHERE id = ? FOR KEY SHARE", avatarID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apperror.NotFound("Image")
+		}
+		return tx.Model(&domain.User{}).
+			Where("id = ?", userID).
+			Update("avatar_id", avatarID).Error
+	})
+	if err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) {
+			return appErr
+		}
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func (r *userRepository) SetStatus(ctx context.Context, userID uuid.UUID, status domain.AccountStatus, until *time.Time, audit *domain.AuditEntry) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&domain.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{"status": status, "suspended_until": until})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apperror.NotFound("User")
+		}
+
+		if status != domain.AccountActive {
+			if err := tx.Where("user_id = ?", userID).
+				Delete(&domain.RefreshToken{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(audit).Error
+	})
+	if err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) {
+			return appErr
+		}
 		return apperror.Internal(err)
 	}
 	return nil
@@ -1,15 +1,21 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/acidsoft/gorestteach/internal/config"
 	"github.com/acidsoft/gorestteach/internal/handler"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/jobs"
 	"github.com/acidsoft/gorestteach/internal/jwt"
 	"github.com/acidsoft/gorestteach/internal/middleware"
 	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/internal/storage"
 	"github.com/acidsoft/gorestteach/internal/usecase"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -18,13 +24,19 @@ import (
 
 // Server encapsulates the HTTP server and all dependencies.
 type Server struct {
-	httpServer *http.Server
-	router     *gin.Engine
-	cfg        *config.Config
+	httpServer    *http.Server
+	debugServer   *http.Server // nil when disabled
+	router        *gin.Engine
+	cfg           *config.Config
+	trashPurger   *jobs.TrashPurger
+	blobSweeper   *jobs.BlobSweeper
+	imageGC       *jobs.ImageCollector
+	uploadExpirer *jobs.UploadExpirer
 }
 
-// New wires all dependencies and registers all routes.
-func New(cfg *config.Config, db *gorm.DB) *Server {
+// New wires all dependencies and registers all routes. Image bytes are kept
+// in blobs.
+func New(cfg *config.Config, db *gorm.DB, blobs *storage.Stores) *Server {
 	gin.SetMode(cfg.Server.Mode)
 
 	router := gin.New()
@@ -36,19 +48,65 @@ func New(cfg *config.Config, db *gorm.DB) *Server {
 
 	userRepo := repository.NewUserRepository(db)
 	postRepo := repository.NewPostRepository(db)
-	imageRepo := repository.NewImageRepository(db)
+	imageRepo := repository.NewImageRepository(db, blobs)
+	variantRepo := repository.NewImageVariantRepository(db, blobs)
 	tokenRepo := repository.NewRefreshTokenRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	reactionRepo := repository.NewReactionRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	bookmarkRepo := repository.NewBookmarkRepository(db)
+	revisionRepo := repository.NewPostRevisionRepository(db)
+	galleryRepo := repository.NewPostImageRepository(db, blobs)
+	reportRepo := repository.NewReportRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	uploadRepo := repository.NewUploadRepository(db, blobs)
+
+	accountStatuses := usecase.NewAccountStatusCache(userRepo, cfg.JWT.StatusCacheDuration)
+
+	imageURLs := imageurl.NewSigner(&cfg.ImageURL)
 
 	authUC := usecase.NewAuthUseCase(userRepo, tokenRepo, jwtService, &cfg.JWT)
-	userUC := usecase.NewUserUseCase(userRepo, imageRepo, &cfg.Upload)
-	postUC := usecase.NewPostUseCase(postRepo, imageRepo, &cfg.Upload)
+	storageUC := usecase.NewStorageUseCase(imageRepo, &cfg.Upload)
+	uploadUC := usecase.NewUploadUseCase(uploadRepo, storageUC, &cfg.Upload)
+	userUC := usecase.NewUserUseCase(userRepo, imageRepo, followRepo, uploadUC, storageUC, imageURLs)
+	postUC := usecase.NewPostUseCase(postRepo, galleryRepo, reactionRepo, bookmarkRepo, uploadUC, storageUC, imageURLs)
+	tagUC := usecase.NewTagUseCase(tagRepo, postUC)
+	commentUC := usecase.NewCommentUseCase(commentRepo, postRepo, imageURLs)
+	reactionUC := usecase.NewReactionUseCase(reactionRepo, postRepo)
+	followUC := usecase.NewFollowUseCase(followRepo, userRepo, imageURLs)
+	bookmarkUC := usecase.NewBookmarkUseCase(bookmarkRepo, postRepo, postUC)
+	revisionUC := usecase.NewRevisionUseCase(revisionRepo, postRepo, postUC)
+	galleryUC := usecase.NewGalleryUseCase(galleryRepo, postRepo, storageUC, imageURLs)
+	moderationUC := usecase.NewModerationUseCase(reportRepo, auditRepo, notificationRepo, userRepo, postRepo, commentRepo, accountStatuses, &cfg.Moderation)
+	notificationUC := usecase.NewNotificationUseCase(notificationRepo)
+	accountUC := usecase.NewAccountUseCase(userRepo, notificationRepo, accountStatuses)
+	imageUC := usecase.NewImageUseCase(imageRepo, variantRepo, imageURLs)
 
 	authH := handler.NewAuthHandler(authUC)
-	userH := handler.NewUserHandler(userUC)
-	postH := handler.NewPostHandler(postUC)
-	imageH := handler.NewImageHandler(imageRepo)
+	userH := handler.NewUserHandler(userUC, &cfg.Upload)
+	postH := handler.NewPostHandler(postUC, &cfg.Upload)
+	imageH := handler.NewImageHandler(imageUC)
+	tagH := handler.NewTagHandler(tagUC)
+	commentH := handler.NewCommentHandler(commentUC)
+	reactionH := handler.NewReactionHandler(reactionUC)
+	followH := handler.NewFollowHandler(followUC)
+	bookmarkH := handler.NewBookmarkHandler(bookmarkUC)
+	revisionH := handler.NewRevisionHandler(revisionUC)
+	galleryH := handler.NewGalleryHandler(galleryUC, &cfg.Upload)
+	moderationH := handler.NewModerationHandler(moderationUC)
+	notificationH := handler.NewNotificationHandler(notificationUC)
+	accountH := handler.NewAccountHandler(accountUC)
+	uploadH := handler.NewUploadHandler(uploadUC)
 
-	authMiddleware := middleware.Auth(jwtService)
+	authMiddleware := middleware.Auth(jwtService, accountStatuses)
+
+	// ─── Background jobs ─────────────────────────────────────────────────────
+	trashPurger := jobs.NewTrashPurger(postRepo, &cfg.Trash)
+	blobSweeper := jobs.NewBlobSweeper(imageRepo, &cfg.Storage)
+	imageGC := jobs.NewImageCollector(imageRepo, &cfg.ImageGC)
+	uploadExpirer := jobs.NewUploadExpirer(uploadRepo)
 
 	// ─── Routes ──────────────────────────────────────────────────────────────
 	router.GET("/health", handler.HealthCheck)
@@ -64,8 +122,14 @@ func New(cfg *config.Config, db *gorm.DB) *Server {
 			auth.POST("/logout", authMiddleware, authH.Logout)
 		}
 
-		// Images — public (images are served by their UUID, not sensitive)
-		v1.GET("/images/:id", imageH.GetImage)
+		// Images — public, except those of posts restricted to some viewers,
+		// which need the token of a user allowed to see the post.
+		v1.GET("/images/:id", middleware.OptionalAuth(jwtService, accountStatuses), imageH.GetImage)
+		v1.HEAD("/images/:id", middleware.OptionalAuth(jwtService, accountStatuses), imageH.GetImage)
+
+		// Resumable uploads (tus protocol). OPTIONS only describes what the
+		// server supports, so it needs no token.
+		v1.OPTIONS("/uploads", uploadH.Options)
 
 		// Protected routes
 		protected := v1.Group("/", authMiddleware)
@@ -74,25 +138,109 @@ func New(cfg *config.Config, db *gorm.DB) *Server {
 			{
 				users.GET("/me", userH.GetMe)
 				users.PUT("/me", userH.UpdateMe)
+				users.PATCH("/me", userH.PatchMe)
 				users.POST("/me/avatar", userH.UploadAvatar)
+				users.GET("/me/storage", userH.GetStorage)
+				users.GET("/me/bookmarks", bookmarkH.List)
+				users.GET("/me/trash", postH.ListTrash)
+				users.GET("/me/bookmark-collections", bookmarkH.ListCollections)
+				users.POST("/me/bookmark-collections", bookmarkH.CreateCollection)
+				users.PUT("/me/bookmark-collections/:id", bookmarkH.RenameCollection)
+				users.DELETE("/me/bookmark-collections/:id", bookmarkH.DeleteCollection)
+				users.GET("/me/notifications", notificationH.List)
+				users.POST("/me/notifications/read", notificationH.MarkRead)
 				users.GET("/:id", userH.GetUser)
+				users.POST("/:id/follow", followH.Follow)
+				users.DELETE("/:id/follow", followH.Unfollow)
+				users.GET("/:id/followers", followH.Followers)
+				users.GET("/:id/following", followH.Following)
+			}
+
+			uploads := protected.Group("/uploads")
+			{
+				uploads.POST("", uploadH.Create)
+				uploads.HEAD("/:id", uploadH.Head)
+				uploads.PATCH("/:id", uploadH.Patch)
+				uploads.DELETE("/:id", uploadH.Terminate)
 			}
 
+			protected.GET("/feed", postH.Feed)
+
 			posts := protected.Group("/posts")
 			{
 				posts.POST("", postH.Create)
 				posts.GET("", postH.List)
 				posts.GET("/:id", postH.GetByID)
 				posts.PUT("/:id", postH.Update)
+				posts.PATCH("/:id", postH.Patch)
 				posts.DELETE("/:id", postH.Delete)
 				posts.POST("/:id/image", postH.AttachImage)
+				posts.GET("/:id/images", galleryH.List)
+				posts.POST("/:id/images", galleryH.Add)
+				posts.PUT("/:id/images/order", galleryH.Reorder)
+				posts.PATCH("/:id/images/:imageId", galleryH.Update)
+				posts.DELETE("/:id/images/:imageId", galleryH.Remove)
+				posts.POST("/:id/restore", postH.Restore)
+				posts.GET("/:id/comments", commentH.List)
+				posts.POST("/:id/comments", commentH.Create)
+				posts.PUT("/:id/reactions", reactionH.Set)
+				posts.DELETE("/:id/reactions", reactionH.Remove)
+				posts.PUT("/:id/bookmark", bookmarkH.Save)
+				posts.DELETE("/:id/bookmark", bookmarkH.Remove)
+				posts.GET("/:id/revisions", revisionH.List)
+				posts.GET("/:id/revisions/:rev", revisionH.Get)
+				posts.POST("/:id/revisions/:rev/restore", revisionH.Restore)
+				posts.POST("/:id/report", moderationH.ReportPost)
+			}
+
+			comments := protected.Group("/comments")
+			{
+				comments.PUT("/:id", commentH.Update)
+				comments.DELETE("/:id", commentH.Delete)
+				comments.POST("/:id/report", moderationH.ReportComment)
+			}
+
+			tags := protected.Group("/tags")
+			{
+				tags.GET("", tagH.List)
+				tags.GET("/:slug/posts", tagH.ListPosts)
+			}
+
+			// Staff — the use cases check the caller's role.
+			admin := protected.Group("/admin")
+			{
+				admin.GET("/reports", moderationH.ListReports)
+				admin.GET("/reports/:id", moderationH.GetReport)
+				admin.POST("/reports/:id/resolve", moderationH.Resolve)
+				admin.GET("/audit-log", moderationH.ListAudit)
+				admin.GET("/users/:id/status", accountH.GetStatus)
+				admin.PUT("/users/:id/status", accountH.SetStatus)
 			}
 		}
 	}
 
+	// Counters of the background jobs, among Go runtime statistics, are for
+	// operators only: they are served on their own listener, never the API's.
+	var debugServer *http.Server
+	if cfg.Server.DebugAddr != "" {
+		debug := http.NewServeMux()
+		debug.Handle("GET /debug/vars", expvar.Handler())
+		debugServer = &http.Server{
+			Addr:         cfg.Server.DebugAddr,
+			Handler:      debug,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		}
+	}
+
 	return &Server{
-		cfg:    cfg,
-		router: router,
+		cfg:           cfg,
+		debugServer:   debugServer,
+		router:        router,
+		trashPurger:   trashPurger,
+		blobSweeper:   blobSweeper,
+		imageGC:       imageGC,
+		uploadExpirer: uploadExpirer,
 		httpServer: &http.Server{
 			Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
 			Handler:      router,
@@ -103,8 +251,27 @@ func New(cfg *config.Config, db *gorm.DB) *Server {
 	}
 }
 
-// Start begins listening for incoming HTTP requests.
+// Start launches the background jobs and begins listening for incoming HTTP
+// requests. The jobs are stopped when the HTTP server stops.
 func (s *Server) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.trashPurger.Run(ctx)
+	go s.blobSweeper.Run(ctx)
+	go s.imageGC.Run(ctx)
+	go s.uploadExpirer.Run(ctx)
+
+	if s.debugServer != nil {
+		go func() {
+			log.Info().Msgf("Debug endpoints on http://%s/debug/vars", s.debugServer.Addr)
+			if err := s.debugServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("Debug listener stopped")
+			}
+		}()
+		defer s.debugServer.Close()
+	}
+
 	log.Info().Msgf("Server listening on http://localhost%s", s.httpServer.Addr)
 	return s.httpServer.ListenAndServe()
 }
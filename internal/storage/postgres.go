@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// postgresStore keeps blobs where images always kept them: in the bytea
+// column images.data, keyed by image ID. It can only hold the bytes of an
+// existing image row, so uploads to it write the column as they insert the
+// row; Put is for moving bytes in from another backend.
+type postgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) BlobStore {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Name() string { return BackendPostgres }
+
+func (s *postgresStore) Put(ctx context.Context, key string, r io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	res := s.db.WithContext(ctx).Exec("UPDATE images SET data = ? WHERE id = ?", data, key)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	var rows []struct{ Data []byte }
+	if err := s.db.WithContext(ctx).
+		Raw("SELECT data FROM images WHERE id = ? AND data IS NOT NULL", key).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(rows[0].Data)), nil
+}
+
+func (s *postgresStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Exec("UPDATE images SET data = NULL WHERE id = ?", key).Error
+}
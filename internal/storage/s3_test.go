@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-west-1"
+	testBucket    = "images"
+)
+
+// fakeS3 is an in-memory S3 bucket that checks the Signature V4 of every
+// request against testSecretKey.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	// fail, when set, answers every request with this status.
+	fail int
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+var authPattern = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([^,]+), Signature=([0-9a-f]{64})$`)
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.fail != 0 {
+		w.WriteHeader(f.fail)
+		io.WriteString(w, "<Error><Code>InternalError</Code></Error>")
+		return
+	}
+	if msg := f.checkSignature(r); msg != "" {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+msg+"</Message></Error>")
+		return
+	}
+
+	key := r.Host + r.URL.Path
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		w.Write(obj.data)
+	case http.MethodDelete:
+		// S3 answers 204 whether or not the object existed.
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// checkSignature verifies r as S3 would and returns what is wrong with it.
+func (f *fakeS3) checkSignature(r *http.Request) string {
+	m := authPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		return "malformed Authorization header"
+	}
+	accessKey, day, region, signedHeaders, signature := m[1], m[2], m[3], m[4], m[5]
+	amzDate := r.Header.Get("X-Amz-Date")
+	if accessKey != testAccessKey || region != testRegion || !strings.HasPrefix(amzDate, day) {
+		return "wrong credential scope"
+	}
+	if signedHeaders != "host;x-amz-content-sha256;x-amz-date" {
+		return "unexpected signed headers " + signedHeaders
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		"host:" + r.Host,
+		"x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256"),
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256(canonical)
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	if want := hmacSHA256(key, toSign); signature != hex.EncodeToString(want) {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+func (f *fakeS3) object(key string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+func newTestS3Store(t *testing.T, srv *httptest.Server, mutate func(*config.S3Config)) *s3Store {
+	t.Helper()
+	cfg := &config.S3Config{
+		Endpoint:  srv.URL,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+		Prefix:    "blobs/",
+		PathStyle: true,
+	}
+	if mutate != nil {
+		mutate(cfg)
+	}
+	store, err := NewS3Store(cfg)
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	s := store.(*s3Store)
+	// Send virtual-hosted requests, whatever their host, to the fake.
+	addr := srv.Listener.Addr().String()
+	s.client = &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+	return s
+}
+
+func readAll(t *testing.T, rc io.ReadCloser) string {
+	t.Helper()
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	return string(data)
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	for _, pathStyle := range []bool{true, false} {
+		t.Run("path style "+strconv.FormatBool(pathStyle), func(t *testing.T) {
+			fake, srv := newFakeS3(t)
+			store := newTestS3Store(t, srv, func(cfg *config.S3Config) { cfg.PathStyle = pathStyle })
+			ctx := context.Background()
+
+			wantKey := srv.Listener.Addr().String() + "/" + testBucket + "/blobs/abc"
+			if !pathStyle {
+				wantKey = testBucket + "." + srv.Listener.Addr().String() + "/blobs/abc"
+			}
+
+			if err := store.Put(ctx, "abc", strings.NewReader("hello"), 5, "image/png"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			obj, ok := fake.object(wantKey)
+			if !ok {
+				t.Fatalf("no object at %s", wantKey)
+			}
+			if string(obj.data) != "hello" || obj.contentType != "image/png" {
+				t.Errorf("stored %q as %q", obj.data, obj.contentType)
+			}
+
+			rc, err := store.Get(ctx, "abc")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got := readAll(t, rc); got != "hello" {
+				t.Errorf("Get = %q, want %q", got, "hello")
+			}
+
+			if err := store.Delete(ctx, "abc"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, ok := fake.object(wantKey); ok {
+				t.Error("object still there after Delete")
+			}
+		})
+	}
+}
+
+func TestS3StorePutUnknownSize(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	store := newTestS3Store(t, srv, nil)
+
+	body := strings.Repeat("x", 70_000)
+	// A streaming upload does not know its length.
+	if err := store.Put(context.Background(), "big", strings.NewReader(body), -1, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	obj, ok := fake.object(srv.Listener.Addr().String() + "/" + testBucket + "/blobs/big")
+	if !ok || string(obj.data) != body {
+		t.Fatalf("object not stored whole (found: %v, %d bytes)", ok, len(obj.data))
+	}
+}
+
+func TestS3StoreMissingKey(t *testing.T) {
+	_, srv := newFakeS3(t)
+	store := newTestS3Store(t, srv, nil)
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing key: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestS3StoreErrors(t *testing.T) {
+	t.Run("bad credentials", func(t *testing.T) {
+		_, srv := newFakeS3(t)
+		store := newTestS3Store(t, srv, func(cfg *config.S3Config) { cfg.SecretKey = "wrong" })
+
+		err := store.Put(context.Background(), "abc", strings.NewReader("x"), 1, "image/png")
+		if err == nil || errors.Is(err, ErrNotFound) {
+			t.Fatalf("Put with a wrong secret: err = %v", err)
+		}
+		for _, want := range []string{"403", "SignatureDoesNotMatch", "/blobs/abc"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not mention %q", err, want)
+			}
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		fake, srv := newFakeS3(t)
+		fake.fail = http.StatusInternalServerError
+		store := newTestS3Store(t, srv, nil)
+		ctx := context.Background()
+
+		if _, err := store.Get(ctx, "abc"); err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "500") {
+			t.Errorf("Get: err = %v, want a 500 error", err)
+		}
+		if err := store.Delete(ctx, "abc"); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete: err = %v, want a 500 error", err)
+		}
+	})
+
+	t.Run("invalid endpoint", func(t *testing.T) {
+		if _, err := NewS3Store(&config.S3Config{Endpoint: "not a url"}); err == nil {
+			t.Error("NewS3Store accepted an endpoint without a host")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// Package storage keeps the bytes of uploaded images. The images table holds
+// their metadata and the name of the backend that has the bytes, so several
+// backends can be in use at once while images are moved between them.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"gorm.io/gorm"
+)
+
+// Backend names, as recorded in images.backend.
+const (
+	BackendPostgres   = "postgres"
+	BackendFilesystem = "filesystem"
+	BackendS3         = "s3"
+)
+
+// ErrNotFound is returned by Get when the store has no blob under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore is a key/value store for image bytes. Keys are image IDs.
+type BlobStore interface {
+	// Name is the backend name recorded with the images it holds.
+	Name() string
+	// Put stores the bytes read from r under key, replacing any blob there.
+	// size is their number, or -1 if it is not known in advance.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key; the caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Stores is the set of configured backends. Uploads go to the primary one;
+// any of them can be read from.
+type Stores struct {
+	primary BlobStore
+	byName  map[string]BlobStore
+}
+
+// Open sets up every backend cfg configures.
+func Open(cfg *config.StorageConfig, db *gorm.DB) (*Stores, error) {
+	stores := []BlobStore{NewPostgresStore(db)}
+	if cfg.FSRoot != "" {
+		fs, err := NewFilesystemStore(cfg.FSRoot)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, fs)
+	}
+	if cfg.S3.Bucket != "" {
+		s3, err := NewS3Store(&cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, s3)
+	}
+	return NewStores(cfg.Backend, stores...)
+}
+
+// NewStores builds a set from already opened backends; primary names the one
+// that receives uploads.
+func NewStores(primary string, stores ...BlobStore) (*Stores, error) {
+	s := &Stores{byName: make(map[string]BlobStore, len(stores))}
+	for _, store := range stores {
+		s.byName[store.Name()] = store
+	}
+	p, ok := s.byName[primary]
+	if !ok {
+		return nil, fmt.Errorf("storage backend %q is not configured", primary)
+	}
+	s.primary = p
+	return s, nil
+}
+
+// Primary returns the backend that receives uploads.
+func (s *Stores) Primary() BlobStore {
+	return s.primary
+}
+
+// Get returns the named backend.
+func (s *Stores) Get(name string) (BlobStore, error) {
+	store, ok := s.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("storage backend %q is not configured", name)
+	}
+	return store, nil
+}
+
+// Names lists the configured backends.
+func (s *Stores) Names() []string {
+	names := make([]string, 0, len(s.byName))
+	for name := range s.byName {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+// SetAccountStatusInput is an admin's decision on an account. Until is
+// required for a suspension and ignored otherwise.
+type SetAccountStatusInput struct {
+	Status string     `json:"status" validate:"required,oneof=active suspended banned"`
+	Until  *time.Time `json:"until"  validate:"required_if=Status suspended"`
+	Reason string     `json:"reason" validate:"omitempty,max=1000"`
+}
+
+// AccountStatusView is an account's status as staff see it.
+type AccountStatusView struct {
+	UserID         uuid.UUID            `json:"user_id"`
+	Status         domain.AccountStatus `json:"status"`
+	SuspendedUntil *time.Time           `json:"suspended_until,omitempty"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+// AccountUseCase lets admins suspend, ban and reinstate accounts.
+type AccountUseCase struct {
+	userRepo         repository.UserRepository
+	notificationRepo repository.NotificationRepository
+	statuses         *AccountStatusCache
+}
+
+func NewAccountUseCase(
+	userRepo repository.UserRepository,
+	notificationRepo repository.NotificationRepository,
+	statuses *AccountStatusCache,
+) *AccountUseCase {
+	return &AccountUseCase{
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		statuses:         statuses,
+	}
+}
+
+// GetStatus returns the account's effective status. Admin only.
+func (uc *AccountUseCase) GetStatus(ctx context.Context, userID, actorID uuid.UUID) (*AccountStatusView, error) {
+	if err := uc.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return statusView(user), nil
+}
+
+// SetStatus changes the account's status. Suspending or banning revokes the
+// user's sessions at once; their access tokens stop working as soon as the
+// status cache notices. Admin only.
+func (uc *AccountUseCase) SetStatus(ctx context.Context, userID, actorID uuid.UUID, input SetAccountStatusInput) (*AccountStatusView, error) {
+	if err := uc.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if userID == actorID {
+		return nil, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "You cannot change your own account status")
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := domain.AccountStatus(input.Status)
+	var until *time.Time
+	audit := &domain.AuditEntry{
+		ActorID:    &actorID,
+		Action:     domain.AuditReinstateUser,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   userID,
+		Note:       input.Reason,
+	}
+	switch status {
+	case domain.AccountSuspended:
+		if !input.Until.After(time.Now()) {
+			return nil, apperror.ValidationError([]apperror.FieldError{
+				{Field: "until", Message: "Must be in the future"},
+			})
+		}
+		t := input.Until.UTC()
+		until = &t
+		audit.Action = domain.AuditSuspendUser
+	case domain.AccountBanned:
+		audit.Action = domain.AuditBanUser
+	}
+
+	if err := uc.userRepo.SetStatus(ctx, userID, status, until, audit); err != nil {
+		return nil, err
+	}
+	uc.statuses.Invalidate(userID)
+
+	wasActive := user.StatusAt(time.Now()) == domain.AccountActive
+	user.Status, user.SuspendedUntil = status, until
+	uc.notifyStatusChange(ctx, user, wasActive)
+
+	return statusView(user), nil
+}
+
+// notifyStatusChange tells the user about a suspension or its lifting. A
+// banned user cannot read notifications, so bans are not announced.
+func (uc *AccountUseCase) notifyStatusChange(ctx context.Context, user *domain.User, wasActive bool) {
+	var n domain.Notification
+	switch {
+	case user.Status == domain.AccountSuspended:
+		n = domain.Notification{
+			UserID:  user.ID,
+			Kind:    domain.NotifyAccountSuspended,
+			Message: "Your account is suspended until " + user.SuspendedUntil.Format(time.RFC1123) + ".",
+		}
+	case user.Status == domain.AccountActive && !wasActive:
+		n = domain.Notification{
+			UserID:  user.ID,
+			Kind:    domain.NotifyAccountReinstated,
+			Message: "Your account has been reinstated.",
+		}
+	default:
+		return
+	}
+	if err := uc.notificationRepo.Create(ctx, []domain.Notification{n}); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to deliver account notification")
+	}
+}
+
+func (uc *AccountUseCase) requireAdmin(ctx context.Context, userID uuid.UUID) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin() {
+		return apperror.Forbidden()
+	}
+	return nil
+}
+
+func statusView(user *domain.User) *AccountStatusView {
+	view := &AccountStatusView{UserID: user.ID, Status: user.StatusAt(time.Now())}
+	if view.Status == domain.AccountSuspended {
+		view.SuspendedUntil = user.SuspendedUntil
+	}
+	return view
+}
+
+// accountError returns the error refusing a user who may not use the API at
+// the given time, or nil when the account is active.
+func accountError(user *domain.User, now time.Time) error {
+	switch user.StatusAt(now) {
+	case domain.AccountSuspended:
+		return apperror.AccountSuspended(*user.SuspendedUntil)
+	case domain.AccountBanned:
+		return apperror.AccountBanned()
+	}
+	return nil
+}
+
+// ─── Status cache ────────────────────────────────────────────────────────────
+
+// AccountStatusCache answers "may this user still use the API?" for every
+// authenticated request without a database round trip each time. Entries
+// live for ttl; status changes made through this process invalidate them at
+// once, changes made elsewhere are picked up when the entry expires.
+type AccountStatusCache struct {
+	userRepo repository.UserRepository
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	entries   map[uuid.UUID]cachedStatus
+	fills     map[uuid.UUID]uint64 // token of the latest fill in flight per user
+	lastFill  uint64
+	nextSweep time.Time
+}
+
+type cachedStatus struct {
+	user    *domain.User // only Status and SuspendedUntil are set; nil if the user is gone
+	expires time.Time
+}
+
+// maxCachedStatuses bounds the cache. When it is full, expired entries are
+// swept, at most once per ttl, and an arbitrary entry is evicted if that was
+// not enough.
+const maxCachedStatuses = 100_000
+
+func NewAccountStatusCache(userRepo repository.UserRepository, ttl time.Duration) *AccountStatusCache {
+	return &AccountStatusCache{
+		userRepo: userRepo,
+		ttl:      ttl,
+		entries:  make(map[uuid.UUID]cachedStatus),
+		fills:    make(map[uuid.UUID]uint64),
+	}
+}
+
+// CheckAccount returns an error if the user may no longer use the API: the
+// account is suspended, banned or deleted.
+func (c *AccountStatusCache) CheckAccount(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	fresh := ok && !now.After(entry.expires)
+	var token uint64
+	if !fresh {
+		c.lastFill++
+		token = c.lastFill
+		c.fills[userID] = token
+	}
+	c.mu.Unlock()
+
+	if !fresh {
+		user, err := c.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			var appErr *apperror.AppError
+			if !errors.As(err, &appErr) || appErr.Code != apperror.ErrNotFound {
+				c.finishFill(userID, token, nil, now)
+				return err
+			}
+			user = nil
+		}
+		entry = cachedStatus{expires: now.Add(c.ttl)}
+		if user != nil {
+			entry.user = &domain.User{Status: user.Status, SuspendedUntil: user.SuspendedUntil}
+		}
+		c.finishFill(userID, token, &entry, now)
+	}
+
+	if entry.user == nil {
+		return apperror.Unauthorized("Account no longer exists")
+	}
+	return accountError(entry.user, now)
+}
+
+// Invalidate drops the cached status of the user. A fill in flight, which
+// may have read the status before the change, is not stored.
+func (c *AccountStatusCache) Invalidate(userID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	delete(c.fills, userID)
+	c.mu.Unlock()
+}
+
+// finishFill stores the entry read by the fill with the given token, unless
+// the user was invalidated or filled again since. A nil entry only ends the
+// fill.
+func (c *AccountStatusCache) finishFill(userID uuid.UUID, token uint64, entry *cachedStatus, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fills[userID] != token {
+		return
+	}
+	delete(c.fills, userID)
+	if entry == nil {
+		return
+	}
+
+	if _, ok := c.entries[userID]; !ok && len(c.entries) >= maxCachedStatuses {
+		if !now.Before(c.nextSweep) {
+			for id, e := range c.entries {
+				if now.After(e.expires) {
+					delete(c.entries, id)
+				}
+			}
+			c.nextSweep = now.Add(c.ttl)
+		}
+		for id := range c.entries {
+			if len(c.entries) < maxCachedStatuses {
+				break
+			}
+			delete(c.entries, id)
+		}
+	}
+	c.entries[userID] = *entry
+}
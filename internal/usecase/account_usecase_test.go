@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+// statusUserRepo serves every user with the same status and counts reads.
+// When reading is set, the next read signals it and waits for release.
+type statusUserRepo struct {
+	repository.UserRepository
+
+	mu      sync.Mutex
+	status  domain.AccountStatus
+	until   *time.Time
+	reads   int
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (r *statusUserRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	r.mu.Lock()
+	user := &domain.User{ID: id, Status: r.status, SuspendedUntil: r.until}
+	r.reads++
+	reading, release := r.reading, r.release
+	r.reading = nil
+	r.mu.Unlock()
+
+	if reading != nil {
+		close(reading)
+		<-release
+	}
+	return user, nil
+}
+
+func (r *statusUserRepo) suspend() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	until := time.Now().Add(time.Hour)
+	r.status, r.until = domain.AccountSuspended, &until
+}
+
+func isSuspended(err error) bool {
+	var appErr *apperror.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperror.ErrAccountSuspended
+}
+
+func TestAccountStatusCacheInvalidateDuringFill(t *testing.T) {
+	users := &statusUserRepo{
+		status:  domain.AccountActive,
+		reading: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	cache := NewAccountStatusCache(users, time.Hour)
+	userID := uuid.New()
+
+	reading := users.reading
+	done := make(chan error)
+	go func() { done <- cache.CheckAccount(context.Background(), userID) }()
+
+	// The fill has read the active status; the suspension lands before it
+	// stores it.
+	<-reading
+	users.suspend()
+	cache.Invalidate(userID)
+	close(users.release)
+	if err := <-done; err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+
+	if err := cache.CheckAccount(context.Background(), userID); !isSuspended(err) {
+		t.Errorf("check after the suspension = %v, want ACCOUNT_SUSPENDED", err)
+	}
+	// The suspension is cached in turn.
+	if err := cache.CheckAccount(context.Background(), userID); !isSuspended(err) {
+		t.Errorf("cached check = %v, want ACCOUNT_SUSPENDED", err)
+	}
+	if users.reads != 2 {
+		t.Errorf("%d reads, want 2", users.reads)
+	}
+}
+
+func TestAccountStatusCacheIsBounded(t *testing.T) {
+	users := &statusUserRepo{status: domain.AccountActive}
+	cache := NewAccountStatusCache(users, time.Hour)
+	ctx := context.Background()
+
+	for range maxCachedStatuses + 100 {
+		if err := cache.CheckAccount(ctx, uuid.New()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(cache.entries); n > maxCachedStatuses {
+		t.Errorf("%d entries cached, want at most %d", n, maxCachedStatuses)
+	}
+	if n := len(cache.fills); n != 0 {
+		t.Errorf("%d fills left in flight", n)
+	}
+
+	// A user already cached is still served from the cache.
+	userID := uuid.New()
+	cache.CheckAccount(ctx, userID)
+	reads := users.reads
+	cache.CheckAccount(ctx, userID)
+	if users.reads != reads {
+		t.Error("a cached user was read again")
+	}
+}
@@ -95,6 +95,10 @@ func (uc *AuthUseCase) Login(ctx context.Context, input LoginInput) (*TokenPair,
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
 		return nil, apperror.Unauthorized("Invalid email or password")
 	}
+	// Checked after the password so it does not reveal which accounts exist.
+	if err := accountError(user, time.Now()); err != nil {
+		return nil, err
+	}
 
 	return uc.issueTokenPair(ctx, user)
 }
@@ -121,6 +125,10 @@ func (uc *AuthUseCase) Refresh(ctx context.Context, refreshTokenStr string) (*To
 	// Delete old refresh token (rotation)
 	_ = uc.tokenRepo.DeleteByToken(ctx, refreshTokenStr)
 
+	if err := accountError(user, time.Now()); err != nil {
+		return nil, err
+	}
+
 	return uc.issueTokenPair(ctx, user)
 }
 
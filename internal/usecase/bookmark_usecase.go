@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type SaveBookmarkInput struct {
+	// CollectionID files the bookmark into one of the caller's collections;
+	// omit it (or send null) to keep the bookmark uncategorized.
+	CollectionID string `json:"collection_id" validate:"omitempty,uuid"`
+}
+
+type ListBookmarksInput struct {
+	Page         int    `form:"page"          validate:"omitempty,min=1"`
+	PerPage      int    `form:"per_page"      validate:"omitempty,min=1,max=100"`
+	CollectionID string `form:"collection_id" validate:"omitempty,uuid"`
+}
+
+type BookmarkCollectionInput struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+type BookmarkUseCase struct {
+	bookmarkRepo repository.BookmarkRepository
+	postRepo     repository.PostRepository
+	postUC       *PostUseCase
+}
+
+func NewBookmarkUseCase(
+	bookmarkRepo repository.BookmarkRepository,
+	postRepo repository.PostRepository,
+	postUC *PostUseCase,
+) *BookmarkUseCase {
+	return &BookmarkUseCase{bookmarkRepo: bookmarkRepo, postRepo: postRepo, postUC: postUC}
+}
+
+// Save bookmarks a post for the user, or moves the existing bookmark into
+// another collection.
+func (uc *BookmarkUseCase) Save(ctx context.Context, userID, postID uuid.UUID, input SaveBookmarkInput) (*domain.Bookmark, error) {
+	if _, err := uc.postRepo.GetByID(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	bookmark := &domain.Bookmark{UserID: userID, PostID: postID}
+	if input.CollectionID != "" {
+		collection, err := uc.bookmarkRepo.GetCollection(ctx, userID, uuid.MustParse(input.CollectionID))
+		if err != nil {
+			return nil, err
+		}
+		bookmark.CollectionID = &collection.ID
+	}
+
+	if err := uc.bookmarkRepo.Save(ctx, bookmark); err != nil {
+		return nil, err
+	}
+	return bookmark, nil
+}
+
+// Remove un-bookmarks a post. Removing a missing bookmark is not an error.
+func (uc *BookmarkUseCase) Remove(ctx context.Context, userID, postID uuid.UUID) error {
+	return uc.bookmarkRepo.Delete(ctx, userID, postID)
+}
+
+// List returns the user's saved posts, newest-saved first.
+func (uc *BookmarkUseCase) List(ctx context.Context, userID uuid.UUID, input ListBookmarksInput) ([]domain.Post, int64, error) {
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 10
+	}
+
+	var collectionID *uuid.UUID
+	if input.CollectionID != "" {
+		collection, err := uc.bookmarkRepo.GetCollection(ctx, userID, uuid.MustParse(input.CollectionID))
+		if err != nil {
+			return nil, 0, err
+		}
+		collectionID = &collection.ID
+	}
+
+	posts, total, err := uc.bookmarkRepo.ListPosts(ctx, userID, collectionID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := uc.postUC.Decorate(ctx, userID, posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// ListCollections returns all of the user's collections, sorted by name.
+func (uc *BookmarkUseCase) ListCollections(ctx context.Context, userID uuid.UUID) ([]domain.BookmarkCollection, error) {
+	return uc.bookmarkRepo.ListCollections(ctx, userID)
+}
+
+// CreateCollection creates a named collection. Names are unique per user.
+func (uc *BookmarkUseCase) CreateCollection(ctx context.Context, userID uuid.UUID, input BookmarkCollectionInput) (*domain.BookmarkCollection, error) {
+	collection := &domain.BookmarkCollection{UserID: userID, Name: input.Name}
+	if err := uc.bookmarkRepo.CreateCollection(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// RenameCollection renames one of the user's collections.
+func (uc *BookmarkUseCase) RenameCollection(ctx context.Context, userID, id uuid.UUID, input BookmarkCollectionInput) (*domain.BookmarkCollection, error) {
+	collection, err := uc.bookmarkRepo.GetCollection(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	collection.Name = input.Name
+	if err := uc.bookmarkRepo.UpdateCollection(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// DeleteCollection deletes one of the user's collections. The bookmarks in it
+// are kept and become uncategorized.
+func (uc *BookmarkUseCase) DeleteCollection(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := uc.bookmarkRepo.GetCollection(ctx, userID, id); err != nil {
+		return err
+	}
+	return uc.bookmarkRepo.DeleteCollection(ctx, userID, id)
+}
@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type CreateCommentInput struct {
+	Body     string `json:"body"      validate:"required,min=1,max=5000"`
+	ParentID string `json:"parent_id" validate:"omitempty,uuid"`
+}
+
+type UpdateCommentInput struct {
+	Body string `json:"body" validate:"required,min=1,max=5000"`
+}
+
+type ListCommentsInput struct {
+	Page     int    `form:"page"      validate:"omitempty,min=1"`
+	PerPage  int    `form:"per_page"  validate:"omitempty,min=1,max=100"`
+	ParentID string `form:"parent_id" validate:"omitempty,uuid"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+type CommentUseCase struct {
+	commentRepo repository.CommentRepository
+	postRepo    repository.PostRepository
+	urls        *imageurl.Signer
+}
+
+func NewCommentUseCase(commentRepo repository.CommentRepository, postRepo repository.PostRepository, urls *imageurl.Signer) *CommentUseCase {
+	return &CommentUseCase{commentRepo: commentRepo, postRepo: postRepo, urls: urls}
+}
+
+// Create adds a comment (or a reply when ParentID is set) to a post.
+func (uc *CommentUseCase) Create(ctx context.Context, postID, userID uuid.UUID, input CreateCommentInput) (*domain.Comment, error) {
+	if _, err := uc.postRepo.GetByID(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	comment := &domain.Comment{
+		PostID: postID,
+		UserID: userID,
+		Body:   input.Body,
+	}
+
+	if input.ParentID != "" {
+		parent, err := uc.commentRepo.GetByID(ctx, uuid.MustParse(input.ParentID))
+		if err != nil {
+			return nil, err
+		}
+		if parent.PostID != postID {
+			return nil, apperror.ValidationError([]apperror.FieldError{
+				{Field: "parent_id", Message: "Parent comment belongs to a different post"},
+			})
+		}
+		if parent.Depth >= domain.MaxCommentDepth {
+			return nil, apperror.ValidationError([]apperror.FieldError{
+				{Field: "parent_id", Message: "Replies cannot be nested deeper than " + strconv.Itoa(domain.MaxCommentDepth) + " levels"},
+			})
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
+	if err := uc.commentRepo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	comment, err := uc.commentRepo.GetByID(ctx, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	uc.urls.SignUser(comment.User)
+	return comment, nil
+}
+
+// List returns one page of a single thread level (see CommentRepository.ListByPost).
+func (uc *CommentUseCase) List(ctx context.Context, postID, viewerID uuid.UUID, input ListCommentsInput) ([]domain.Comment, int64, error) {
+	if _, err := uc.postRepo.GetByID(ctx, postID, viewerID); err != nil {
+		return nil, 0, err
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	var parentID *uuid.UUID
+	if input.ParentID != "" {
+		id := uuid.MustParse(input.ParentID)
+		parentID = &id
+	}
+
+	comments, total, err := uc.commentRepo.ListByPost(ctx, postID, parentID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	redactComments(comments, viewerID)
+	for i := range comments {
+		uc.urls.SignUser(comments[i].User)
+	}
+	return comments, total, nil
+}
+
+// Update edits a comment's body. Only the comment author may edit.
+func (uc *CommentUseCase) Update(ctx context.Context, commentID, userID uuid.UUID, input UpdateCommentInput) (*domain.Comment, error) {
+	comment, err := uc.commentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.UserID != userID {
+		return nil, apperror.Forbidden()
+	}
+	if comment.Moderation == domain.ModerationRemoved {
+		return nil, apperror.Conflict("The comment was removed by a moderator and can no longer be edited")
+	}
+
+	comment.Body = input.Body
+	if err := uc.commentRepo.UpdateBody(ctx, comment); err != nil {
+		return nil, err
+	}
+	uc.urls.SignUser(comment.User)
+	return comment, nil
+}
+
+// Delete removes a comment and its replies. Allowed for the comment author and
+// for the owner of the post it was written under.
+func (uc *CommentUseCase) Delete(ctx context.Context, commentID, userID uuid.UUID) error {
+	comment, err := uc.commentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	if comment.UserID != userID {
+		post, err := uc.postRepo.GetByID(ctx, comment.PostID, userID)
+		if err != nil {
+			return err
+		}
+		if post.UserID != userID {
+			return apperror.Forbidden()
+		}
+	}
+
+	return uc.commentRepo.Delete(ctx, comment)
+}
+
+// redactComments blanks the bodies of moderated comments the viewer may not
+// read: removed ones for everybody, hidden ones for all but their author.
+func redactComments(comments []domain.Comment, viewerID uuid.UUID) {
+	for i := range comments {
+		c := &comments[i]
+		if c.Moderation == domain.ModerationRemoved ||
+			(c.Moderation == domain.ModerationHidden && c.UserID != viewerID) {
+			c.Body = ""
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type ListFollowsInput struct {
+	Page    int `form:"page"     validate:"omitempty,min=1"`
+	PerPage int `form:"per_page" validate:"omitempty,min=1,max=100"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+type FollowUseCase struct {
+	followRepo repository.FollowRepository
+	userRepo   repository.UserRepository
+	urls       *imageurl.Signer
+}
+
+func NewFollowUseCase(followRepo repository.FollowRepository, userRepo repository.UserRepository, urls *imageurl.Signer) *FollowUseCase {
+	return &FollowUseCase{followRepo: followRepo, userRepo: userRepo, urls: urls}
+}
+
+// Follow makes followerID follow targetID. Following twice is a no-op.
+func (uc *FollowUseCase) Follow(ctx context.Context, followerID, targetID uuid.UUID) error {
+	if followerID == targetID {
+		return apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "You cannot follow yourself")
+	}
+	if _, err := uc.userRepo.GetByID(ctx, targetID); err != nil {
+		return err
+	}
+	return uc.followRepo.Follow(ctx, followerID, targetID)
+}
+
+// Unfollow removes the follow edge, if any.
+func (uc *FollowUseCase) Unfollow(ctx context.Context, followerID, targetID uuid.UUID) error {
+	if _, err := uc.userRepo.GetByID(ctx, targetID); err != nil {
+		return err
+	}
+	return uc.followRepo.Unfollow(ctx, followerID, targetID)
+}
+
+// Followers lists the users following userID.
+func (uc *FollowUseCase) Followers(ctx context.Context, userID uuid.UUID, input ListFollowsInput) ([]domain.UserPublic, int64, error) {
+	return uc.list(ctx, userID, input, uc.followRepo.ListFollowers)
+}
+
+// Following lists the users userID follows.
+func (uc *FollowUseCase) Following(ctx context.Context, userID uuid.UUID, input ListFollowsInput) ([]domain.UserPublic, int64, error) {
+	return uc.list(ctx, userID, input, uc.followRepo.ListFollowing)
+}
+
+func (uc *FollowUseCase) list(
+	ctx context.Context,
+	userID uuid.UUID,
+	input ListFollowsInput,
+	fetch func(context.Context, uuid.UUID, int, int) ([]domain.User, int64, error),
+) ([]domain.UserPublic, int64, error) {
+	if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	users, total, err := fetch(ctx, userID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]domain.UserPublic, len(users))
+	for i := range users {
+		out[i] = users[i].ToPublic()
+		uc.urls.SignProfile(&out[i])
+	}
+	return out, total, nil
+}
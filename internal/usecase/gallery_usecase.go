@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/patch"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+// GalleryUpload is one file of a multi-image upload.
+type GalleryUpload struct {
+	ImageUpload
+	Caption string
+	Alt     string
+}
+
+type ReorderImagesInput struct {
+	ImageIDs []string `json:"image_ids" validate:"required,min=1,dive,uuid"`
+}
+
+// PatchPostImageInput is a JSON Merge Patch document for a gallery entry.
+// A null caption or alt clears it.
+type PatchPostImageInput struct {
+	Caption patch.Field[string] `json:"caption" validate:"omitnil,max=300"`
+	Alt     patch.Field[string] `json:"alt"     validate:"omitnil,max=300"`
+}
+
+// maxImageTextLen is the length limit of captions and alt texts, in runes.
+const maxImageTextLen = 300
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+// GalleryUseCase manages the images of a post. Anyone who can see the post
+// may look at its gallery; only the author may change it.
+type GalleryUseCase struct {
+	galleryRepo repository.PostImageRepository
+	postRepo    repository.PostRepository
+	storage     *StorageUseCase
+	urls        *imageurl.Signer
+}
+
+func NewGalleryUseCase(
+	galleryRepo repository.PostImageRepository,
+	postRepo repository.PostRepository,
+	storage *StorageUseCase,
+	urls *imageurl.Signer,
+) *GalleryUseCase {
+	return &GalleryUseCase{galleryRepo: galleryRepo, postRepo: postRepo, storage: storage, urls: urls}
+}
+
+// List returns the post's gallery in display order.
+func (uc *GalleryUseCase) List(ctx context.Context, postID, viewerID uuid.UUID) ([]domain.PostImage, error) {
+	if _, err := uc.postRepo.GetByID(ctx, postID, viewerID); err != nil {
+		return nil, err
+	}
+	return uc.list(ctx, postID)
+}
+
+// Add stores the uploads and appends them to the gallery in the order given.
+// Either all of them are added or none is; together they must fit in the
+// user's storage quota.
+func (uc *GalleryUseCase) Add(ctx context.Context, postID, userID uuid.UUID, uploads []GalleryUpload) ([]domain.PostImage, error) {
+	if _, err := uc.ownedPost(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	if len(uploads) == 0 {
+		return nil, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+			"At least one file in field 'images' is required")
+	}
+	if len(uploads) > domain.MaxPostImages {
+		return nil, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest,
+			fmt.Sprintf("A post can have at most %d images", domain.MaxPostImages))
+	}
+
+	var details []apperror.FieldError
+	for i, u := range uploads {
+		if utf8.RuneCountInString(u.Caption) > maxImageTextLen {
+			details = append(details, apperror.FieldError{
+				Field:   fmt.Sprintf("captions[%d]", i),
+				Message: fmt.Sprintf("Must be at most %d characters", maxImageTextLen),
+			})
+		}
+		if utf8.RuneCountInString(u.Alt) > maxImageTextLen {
+			details = append(details, apperror.FieldError{
+				Field:   fmt.Sprintf("alts[%d]", i),
+				Message: fmt.Sprintf("Must be at most %d characters", maxImageTextLen),
+			})
+		}
+	}
+	if len(details) > 0 {
+		return nil, apperror.ValidationError(details)
+	}
+
+	entries := make([]domain.PostImage, len(uploads))
+	images := make([]*domain.Image, len(uploads))
+	finishers := make([]func(error) error, len(uploads))
+	for i, u := range uploads {
+		img, finish, err := newUploadedImage(u.ImageUpload)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = domain.PostImage{Caption: u.Caption, Alt: u.Alt, Image: img}
+		images[i] = img
+		finishers[i] = finish
+	}
+	check, err := uc.storage.charge(ctx, userID, images...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.galleryRepo.Add(ctx, postID, entries, false)
+	for _, finish := range finishers {
+		err = finish(err)
+	}
+	if err := check(err); err != nil {
+		return nil, err
+	}
+	return uc.list(ctx, postID)
+}
+
+// Reorder puts the gallery in the given order. The first image becomes the
+// post's cover.
+func (uc *GalleryUseCase) Reorder(ctx context.Context, postID, userID uuid.UUID, input ReorderImagesInput) ([]domain.PostImage, error) {
+	if _, err := uc.ownedPost(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(input.ImageIDs))
+	for i, raw := range input.ImageIDs {
+		ids[i] = uuid.MustParse(raw) // validated by the DTO
+	}
+
+	if err := uc.galleryRepo.Reorder(ctx, postID, ids); err != nil {
+		return nil, err
+	}
+	return uc.list(ctx, postID)
+}
+
+// Update applies a merge patch to the caption and alt text of a gallery entry.
+func (uc *GalleryUseCase) Update(ctx context.Context, postID, imageID, userID uuid.UUID, input PatchPostImageInput) (*domain.PostImage, error) {
+	if _, err := uc.ownedPost(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	image, err := uc.galleryRepo.Get(ctx, postID, imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Caption.Set {
+		image.Caption = input.Caption.Value // "" when null
+	}
+	if input.Alt.Set {
+		image.Alt = input.Alt.Value
+	}
+
+	if err := uc.galleryRepo.UpdateMeta(ctx, image); err != nil {
+		return nil, err
+	}
+	image.ImageURL = uc.urls.Image(image.ImageID)
+	return image, nil
+}
+
+// Remove takes an image out of the gallery. If it was the cover, the next
+// image takes its place.
+func (uc *GalleryUseCase) Remove(ctx context.Context, postID, imageID, userID uuid.UUID) error {
+	if _, err := uc.ownedPost(ctx, postID, userID); err != nil {
+		return err
+	}
+	return uc.galleryRepo.Remove(ctx, postID, imageID)
+}
+
+// list returns the gallery with the image URLs signed.
+func (uc *GalleryUseCase) list(ctx context.Context, postID uuid.UUID) ([]domain.PostImage, error) {
+	images, err := uc.galleryRepo.List(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	uc.urls.SignImages(images)
+	return images, nil
+}
+
+func (uc *GalleryUseCase) ownedPost(ctx context.Context, postID, userID uuid.UUID) (*domain.Post, error) {
+	post, err := uc.postRepo.GetByID(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != userID {
+		return nil, apperror.Forbidden()
+	}
+	return post, nil
+}
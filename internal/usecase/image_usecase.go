@@ -0,0 +1,278 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/imaging"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+// ImageVariantInput selects a resized variant of an image. Only the listed
+// sizes are generated, so the variant cache stays bounded per image. With
+// neither w nor h the original is served.
+type ImageVariantInput struct {
+	W   int    `form:"w"   validate:"omitempty,oneof=64 128 256 512 1024 2048"`
+	H   int    `form:"h"   validate:"omitempty,oneof=64 128 256 512 1024 2048"`
+	Fit string `form:"fit" validate:"omitempty,oneof=contain cover"`
+}
+
+// IsOriginal reports whether the input asks for the image as uploaded.
+func (in ImageVariantInput) IsOriginal() bool {
+	return in.W == 0 && in.H == 0
+}
+
+// ImageContent is what GET /images/:id serves: the original image or one of
+// its variants. The bytes are only read by Load, as conditional and HEAD
+// requests can be answered from the metadata.
+type ImageContent struct {
+	ContentType string
+	// Size is -1 for a variant Stat found not generated yet.
+	Size int64
+	// SHA256 is the hex digest of the bytes; empty for images uploaded
+	// before it was recorded, until Load has read them.
+	SHA256  string
+	ModTime time.Time
+	// Public is false when only some viewers may see the image, so shared
+	// caches must not keep it.
+	Public bool
+	// Expires is when the signed URL the image was requested with expires;
+	// zero for unsigned requests. Caches must not keep the image past it.
+	Expires time.Time
+
+	load func(ctx context.Context) (data []byte, sha256 string, err error)
+}
+
+// Load reads the bytes, updating Size and SHA256 to match them: a variant
+// whose cached bytes were lost is generated anew.
+func (ic *ImageContent) Load(ctx context.Context) ([]byte, error) {
+	data, sha256, err := ic.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ic.Size, ic.SHA256 = int64(len(data)), sha256
+	return data, nil
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+type ImageUseCase struct {
+	imageRepo   repository.ImageRepository
+	variantRepo repository.ImageVariantRepository
+	urls        *imageurl.Signer
+
+	// generating collapses concurrent requests for the same missing variant,
+	// e.g. a new avatar appearing in every row of a list, into one resize.
+	generating singleflight.Group
+	// resizing bounds how many images are decoded at once; decoded images
+	// are large and resizing is CPU-bound.
+	resizing chan struct{}
+}
+
+func NewImageUseCase(
+	imageRepo repository.ImageRepository,
+	variantRepo repository.ImageVariantRepository,
+	urls *imageurl.Signer,
+) *ImageUseCase {
+	return &ImageUseCase{
+		imageRepo:   imageRepo,
+		variantRepo: variantRepo,
+		urls:        urls,
+		resizing:    make(chan struct{}, runtime.GOMAXPROCS(0)),
+	}
+}
+
+// Open returns the image as uploaded, or the variant input asks for in a
+// format acceptable to accept (an HTTP Accept header). Variants are
+// generated on the first request and cached until the image is deleted.
+//
+// A request with a signed link is served to anyone until the link expires.
+// Without one, the viewer must be able to see the image; anonymous viewers
+// are only served avatars, and only while avatars are public.
+func (uc *ImageUseCase) Open(ctx context.Context, id, viewerID uuid.UUID, link imageurl.Link, input ImageVariantInput, accept string) (*ImageContent, error) {
+	return uc.open(ctx, id, viewerID, link, input, accept, true)
+}
+
+// Stat is Open for requests that only need the metadata, such as HEAD: it
+// never generates a variant. One not generated yet is described from the
+// original, with a Size of -1 and no SHA256 or ModTime, as they are only
+// known once it is made.
+func (uc *ImageUseCase) Stat(ctx context.Context, id, viewerID uuid.UUID, link imageurl.Link, input ImageVariantInput, accept string) (*ImageContent, error) {
+	return uc.open(ctx, id, viewerID, link, input, accept, false)
+}
+
+func (uc *ImageUseCase) open(ctx context.Context, id, viewerID uuid.UUID, link imageurl.Link, input ImageVariantInput, accept string, generate bool) (*ImageContent, error) {
+	img, err := uc.find(ctx, id, viewerID, link)
+	if err != nil {
+		return nil, err
+	}
+	var expires time.Time
+	if link.Signed() {
+		expires = time.Unix(link.Exp, 0)
+	}
+
+	if input.IsOriginal() {
+		return &ImageContent{
+			ContentType: img.ContentType,
+			Size:        img.Size,
+			SHA256:      img.SHA256,
+			ModTime:     img.CreatedAt,
+			Public:      img.Public,
+			Expires:     expires,
+			load: func(ctx context.Context) ([]byte, string, error) {
+				if err := uc.imageRepo.LoadData(ctx, img); err != nil {
+					return nil, "", err
+				}
+				if img.SHA256 == "" {
+					img.SHA256 = domain.ContentHash(img.Data)
+					// Best effort: it is recomputed next time otherwise.
+					_ = uc.imageRepo.SetSHA256(ctx, img.ID, img.SHA256)
+				}
+				return img.Data, img.SHA256, nil
+			},
+		}, nil
+	}
+
+	// Clients that accept neither output type (typically ones sending
+	// "Accept: application/json" on every request) get the default anyway,
+	// as they do for originals.
+	contentType, _ := imaging.Negotiate(accept, img.ContentType)
+	fit := input.Fit
+	if fit == "" {
+		fit = imaging.FitContain
+	}
+	name := fmt.Sprintf("%dx%d-%s-%s", input.W, input.H, fit, strings.TrimPrefix(contentType, "image/"))
+	regenerate := func() (*domain.ImageVariant, error) {
+		// The shared call must not be cancelled by whichever request started it.
+		genCtx := context.WithoutCancel(ctx)
+		v, err, _ := uc.generating.Do(id.String()+"_"+name, func() (any, error) {
+			return uc.generate(genCtx, img, name, input.W, input.H, fit, contentType)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return v.(*domain.ImageVariant), nil
+	}
+
+	variant, err := uc.variantRepo.Get(ctx, id, name)
+	if isNotFound(err) && !generate {
+		return &ImageContent{
+			ContentType: contentType,
+			Size:        -1,
+			Public:      img.Public,
+			Expires:     expires,
+			load: func(ctx context.Context) ([]byte, string, error) {
+				variant, err := regenerate()
+				if err != nil {
+					return nil, "", err
+				}
+				return variant.Data, variant.SHA256, nil
+			},
+		}, nil
+	}
+	if isNotFound(err) {
+		variant, err = regenerate()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImageContent{
+		ContentType: variant.ContentType,
+		Size:        variant.Size,
+		SHA256:      variant.SHA256,
+		ModTime:     variant.CreatedAt,
+		Public:      img.Public,
+		Expires:     expires,
+		load: func(ctx context.Context) ([]byte, string, error) {
+			if variant.Data == nil {
+				err := uc.variantRepo.LoadData(ctx, variant)
+				if isNotFound(err) {
+					variant, err = regenerate()
+				}
+				if err != nil {
+					return nil, "", err
+				}
+			}
+			if variant.SHA256 == "" {
+				variant.SHA256 = domain.ContentHash(variant.Data)
+			}
+			return variant.Data, variant.SHA256, nil
+		},
+	}, nil
+}
+
+// find returns the image's metadata if the link or, for unsigned requests,
+// the viewer allows it to be served. Without a viewer, an unsigned request
+// can only fetch an avatar, and only if avatars are public.
+func (uc *ImageUseCase) find(ctx context.Context, id, viewerID uuid.UUID, link imageurl.Link) (*domain.Image, error) {
+	if !link.Signed() {
+		if viewerID != uuid.Nil {
+			return uc.imageRepo.GetByID(ctx, id, viewerID)
+		}
+		if !uc.urls.PublicAvatars() {
+			return nil, apperror.NotFound("Image")
+		}
+		return uc.imageRepo.GetAvatar(ctx, id)
+	}
+
+	switch err := uc.urls.Verify(id, link); {
+	case errors.Is(err, imageurl.ErrExpired):
+		return nil, apperror.LinkExpired()
+	case err != nil:
+		return nil, apperror.NewWithCause(http.StatusForbidden, apperror.ErrForbidden, "Invalid image link", err)
+	}
+	return uc.imageRepo.GetForLink(ctx, id)
+}
+
+// isNotFound reports whether err is an AppError with code NOT_FOUND.
+func isNotFound(err error) bool {
+	var appErr *apperror.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperror.ErrNotFound
+}
+
+func (uc *ImageUseCase) generate(ctx context.Context, img *domain.Image, name string, w, h int, fit, contentType string) (*domain.ImageVariant, error) {
+	if err := uc.imageRepo.LoadData(ctx, img); err != nil {
+		return nil, err
+	}
+
+	uc.resizing <- struct{}{}
+	src, err := imaging.Decode(img.Data, img.ContentType)
+	var data []byte
+	if err == nil {
+		resized := imaging.Resize(src, w, h, fit)
+		w, h = resized.Bounds().Dx(), resized.Bounds().Dy()
+		data, err = imaging.Encode(resized, contentType)
+	}
+	<-uc.resizing
+	if err != nil {
+		return nil, apperror.NewWithCause(http.StatusUnsupportedMediaType, apperror.ErrUnsupportedMedia, "Image cannot be resized", err)
+	}
+
+	variant := &domain.ImageVariant{
+		ImageID:     img.ID,
+		Name:        name,
+		Data:        data,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       w,
+		Height:      h,
+		SHA256:      domain.ContentHash(data),
+	}
+	if err := uc.variantRepo.Save(ctx, variant); err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+// lookupImageRepo records which lookup served an image.
+type lookupImageRepo struct {
+	repository.ImageRepository
+	avatar bool // whether the image is an avatar
+	used   string
+}
+
+func (r *lookupImageRepo) GetByID(_ context.Context, id, _ uuid.UUID) (*domain.Image, error) {
+	r.used = "GetByID"
+	return &domain.Image{ID: id}, nil
+}
+
+func (r *lookupImageRepo) GetAvatar(_ context.Context, id uuid.UUID) (*domain.Image, error) {
+	r.used = "GetAvatar"
+	if !r.avatar {
+		return nil, apperror.NotFound("Image")
+	}
+	return &domain.Image{ID: id, Public: true}, nil
+}
+
+func (r *lookupImageRepo) GetForLink(_ context.Context, id uuid.UUID) (*domain.Image, error) {
+	r.used = "GetForLink"
+	return &domain.Image{ID: id}, nil
+}
+
+func signedLink(t *testing.T, signer *imageurl.Signer, id uuid.UUID) imageurl.Link {
+	t.Helper()
+	u, err := url.Parse(signer.Image(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	exp, _ := strconv.ParseInt(q.Get("exp"), 10, 64)
+	return imageurl.Link{Exp: exp, Kid: q.Get("kid"), Sig: q.Get("sig")}
+}
+
+func TestImageFind(t *testing.T) {
+	id, viewer := uuid.New(), uuid.New()
+	cfg := &config.ImageURLConfig{
+		Keys: []config.SigningKey{{ID: "k1", Secret: []byte("secret")}},
+		TTL:  time.Hour,
+	}
+	signer := imageurl.NewSigner(cfg)
+	link := signedLink(t, signer, id)
+
+	tests := []struct {
+		name          string
+		publicAvatars bool
+		avatar        bool
+		viewer        uuid.UUID
+		link          imageurl.Link
+		wantLookup    string
+		wantErr       bool
+	}{
+		{name: "anonymous avatar", publicAvatars: true, avatar: true, wantLookup: "GetAvatar"},
+		{name: "anonymous post image", publicAvatars: true, wantLookup: "GetAvatar", wantErr: true},
+		{name: "anonymous with private avatars", avatar: true, wantErr: true},
+		{name: "viewer without link", publicAvatars: true, viewer: viewer, wantLookup: "GetByID"},
+		{name: "anonymous with link", link: link, wantLookup: "GetForLink"},
+		{name: "anonymous with forged link", link: imageurl.Link{Exp: link.Exp, Kid: "k1", Sig: "forged"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.PublicAvatars = tt.publicAvatars
+			repo := &lookupImageRepo{avatar: tt.avatar}
+			uc := NewImageUseCase(repo, nil, signer)
+
+			img, err := uc.find(context.Background(), id, tt.viewer, tt.link)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			if err == nil && img.ID != id {
+				t.Errorf("got image %s, want %s", img.ID, id)
+			}
+			if repo.used != tt.wantLookup {
+				t.Errorf("looked up with %q, want %q", repo.used, tt.wantLookup)
+			}
+		})
+	}
+}
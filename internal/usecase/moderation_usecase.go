@@ -0,0 +1,326 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type ReportInput struct {
+	Reason  string `json:"reason"  validate:"required,oneof=spam harassment hate violence sexual misinformation other"`
+	Details string `json:"details" validate:"omitempty,max=1000"`
+}
+
+type ListReportsInput struct {
+	Status     string `form:"status"      validate:"omitempty,oneof=open dismissed actioned all"` // default open
+	TargetType string `form:"target_type" validate:"omitempty,oneof=post comment"`
+	Page       int    `form:"page"        validate:"omitempty,min=1"`
+	PerPage    int    `form:"per_page"    validate:"omitempty,min=1,max=100"`
+}
+
+// ResolveReportInput is a moderator's decision. "remove" takes the content
+// down; "suspend" also suspends its author for SuspendDays.
+type ResolveReportInput struct {
+	Action      string `json:"action"       validate:"required,oneof=dismiss remove suspend"`
+	Note        string `json:"note"         validate:"omitempty,max=1000"`
+	SuspendDays int    `json:"suspend_days" validate:"required_if=Action suspend,omitempty,min=1,max=3650"`
+}
+
+type ListAuditInput struct {
+	TargetID string `form:"target_id" validate:"omitempty,uuid"`
+	Page     int    `form:"page"      validate:"omitempty,min=1"`
+	PerPage  int    `form:"per_page"  validate:"omitempty,min=1,max=100"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+// ModerationUseCase handles abuse reports: any user can file them, staff
+// work through them in the moderation queue. Every moderation action is
+// audited and announced in the notification streams of those involved.
+type ModerationUseCase struct {
+	reportRepo       repository.ReportRepository
+	auditRepo        repository.AuditRepository
+	notificationRepo repository.NotificationRepository
+	userRepo         repository.UserRepository
+	postRepo         repository.PostRepository
+	commentRepo      repository.CommentRepository
+	statuses         *AccountStatusCache
+	cfg              *config.ModerationConfig
+}
+
+func NewModerationUseCase(
+	reportRepo repository.ReportRepository,
+	auditRepo repository.AuditRepository,
+	notificationRepo repository.NotificationRepository,
+	userRepo repository.UserRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	statuses *AccountStatusCache,
+	cfg *config.ModerationConfig,
+) *ModerationUseCase {
+	return &ModerationUseCase{
+		reportRepo:       reportRepo,
+		auditRepo:        auditRepo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		postRepo:         postRepo,
+		commentRepo:      commentRepo,
+		statuses:         statuses,
+		cfg:              cfg,
+	}
+}
+
+// ReportPost files a report about a post the reporter can see.
+func (uc *ModerationUseCase) ReportPost(ctx context.Context, postID, reporterID uuid.UUID, input ReportInput) (*domain.Report, error) {
+	post, err := uc.postRepo.GetByID(ctx, postID, reporterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.file(ctx, &domain.Report{
+		TargetType:     domain.ReportTargetPost,
+		TargetID:       post.ID,
+		ReporterID:     reporterID,
+		TargetAuthorID: post.UserID,
+		Reason:         domain.ReportReason(input.Reason),
+		Details:        input.Details,
+		Snapshot:       post.Title + "\n\n" + post.Body,
+	})
+}
+
+// ReportComment files a report about a comment under a post the reporter can see.
+func (uc *ModerationUseCase) ReportComment(ctx context.Context, commentID, reporterID uuid.UUID, input ReportInput) (*domain.Report, error) {
+	comment, err := uc.commentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.postRepo.GetByID(ctx, comment.PostID, reporterID); err != nil {
+		return nil, err
+	}
+	if comment.Moderation == domain.ModerationRemoved {
+		return nil, apperror.NotFound("Comment")
+	}
+
+	return uc.file(ctx, &domain.Report{
+		TargetType:     domain.ReportTargetComment,
+		TargetID:       comment.ID,
+		ReporterID:     reporterID,
+		TargetAuthorID: comment.UserID,
+		Reason:         domain.ReportReason(input.Reason),
+		Details:        input.Details,
+		Snapshot:       comment.Body,
+	})
+}
+
+func (uc *ModerationUseCase) file(ctx context.Context, report *domain.Report) (*domain.Report, error) {
+	if report.TargetAuthorID == report.ReporterID {
+		return nil, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "You cannot report your own content")
+	}
+
+	hidden, err := uc.reportRepo.Create(ctx, report, uc.cfg.AutoHideThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		uc.notify(ctx, domain.Notification{
+			UserID:      report.TargetAuthorID,
+			Kind:        domain.NotifyContentHidden,
+			SubjectType: string(report.TargetType),
+			SubjectID:   &report.TargetID,
+			Message:     fmt.Sprintf("Your %s was reported by several users and is hidden until a moderator reviews it.", report.TargetType),
+		})
+	}
+	return report, nil
+}
+
+// ListReports returns the moderation queue. Staff only.
+func (uc *ModerationUseCase) ListReports(ctx context.Context, actorID uuid.UUID, input ListReportsInput) ([]domain.Report, int64, error) {
+	if err := uc.requireModerator(ctx, actorID); err != nil {
+		return nil, 0, err
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	filter := repository.ReportFilter{
+		Status:     domain.ReportOpen,
+		TargetType: domain.ReportTargetType(input.TargetType),
+		Page:       page,
+		PerPage:    perPage,
+	}
+	switch input.Status {
+	case "":
+	case "all":
+		filter.Status = ""
+	default:
+		filter.Status = domain.ReportStatus(input.Status)
+	}
+	return uc.reportRepo.List(ctx, filter)
+}
+
+// GetReport returns a single report. Staff only.
+func (uc *ModerationUseCase) GetReport(ctx context.Context, reportID, actorID uuid.UUID) (*domain.Report, error) {
+	if err := uc.requireModerator(ctx, actorID); err != nil {
+		return nil, err
+	}
+	return uc.reportRepo.GetByID(ctx, reportID)
+}
+
+// Resolve applies a moderator's decision to the reported content. It closes
+// every open report on that content, not just this one. Staff only.
+func (uc *ModerationUseCase) Resolve(ctx context.Context, reportID, actorID uuid.UUID, input ResolveReportInput) (*domain.Report, error) {
+	if err := uc.requireModerator(ctx, actorID); err != nil {
+		return nil, err
+	}
+
+	report, err := uc.reportRepo.GetByID(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != domain.ReportOpen {
+		return nil, apperror.Conflict("The report has already been resolved")
+	}
+
+	res := &repository.ReportResolution{
+		Report:     report,
+		ResolvedBy: actorID,
+		Status:     domain.ReportActioned,
+		Moderation: domain.ModerationRemoved,
+		Audit: domain.AuditEntry{
+			ActorID:    &actorID,
+			TargetType: report.TargetType,
+			TargetID:   report.TargetID,
+			ReportID:   &report.ID,
+			Note:       input.Note,
+		},
+	}
+	switch input.Action {
+	case "dismiss":
+		res.Status = domain.ReportDismissed
+		res.Moderation = domain.ModerationVisible
+		res.Audit.Action = domain.AuditDismiss
+	case "remove":
+		res.Audit.Action = domain.AuditRemoveContent
+	case "suspend":
+		until := time.Now().AddDate(0, 0, input.SuspendDays)
+		res.SuspendUntil = &until
+		res.Audit.Action = domain.AuditSuspendAuthor
+		res.Audit.Note = strings.TrimSpace(fmt.Sprintf("Suspended for %d days. %s", input.SuspendDays, input.Note))
+	}
+
+	result, err := uc.reportRepo.Resolve(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+	if res.SuspendUntil != nil {
+		uc.statuses.Invalidate(report.TargetAuthorID)
+	}
+
+	uc.notify(ctx, resolutionNotifications(report, res, result)...)
+	return report, nil
+}
+
+// resolutionNotifications tells the reporters how their reports ended and the
+// author what happened to their content and account.
+func resolutionNotifications(report *domain.Report, res *repository.ReportResolution, result *repository.ResolveResult) []domain.Notification {
+	subjectType := string(report.TargetType)
+	subjectID := &report.TargetID
+
+	outcome := "A moderator reviewed the %s you reported and took action. Thank you."
+	if res.Status == domain.ReportDismissed {
+		outcome = "A moderator reviewed the %s you reported and found no violation."
+	}
+	notifications := make([]domain.Notification, 0, len(result.ReporterIDs)+2)
+	for _, reporterID := range result.ReporterIDs {
+		notifications = append(notifications, domain.Notification{
+			UserID:      reporterID,
+			Kind:        domain.NotifyReportResolved,
+			SubjectType: subjectType,
+			SubjectID:   subjectID,
+			Message:     fmt.Sprintf(outcome, report.TargetType),
+		})
+	}
+
+	if result.ContentChanged {
+		n := domain.Notification{
+			UserID:      report.TargetAuthorID,
+			Kind:        domain.NotifyContentRemoved,
+			SubjectType: subjectType,
+			SubjectID:   subjectID,
+			Message:     fmt.Sprintf("A moderator removed your %s for violating the rules (%s).", report.TargetType, report.Reason),
+		}
+		if res.Moderation == domain.ModerationVisible {
+			n.Kind = domain.NotifyContentRestored
+			n.Message = fmt.Sprintf("A moderator reviewed your %s and made it visible again.", report.TargetType)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if res.SuspendUntil != nil {
+		notifications = append(notifications, domain.Notification{
+			UserID:  report.TargetAuthorID,
+			Kind:    domain.NotifyAccountSuspended,
+			Message: "Your account is suspended until " + res.SuspendUntil.UTC().Format(time.RFC1123) + ".",
+		})
+	}
+	return notifications
+}
+
+// notify delivers notifications on a best-effort basis: the action they
+// announce has already been committed, so a failure is logged, not returned.
+func (uc *ModerationUseCase) notify(ctx context.Context, notifications ...domain.Notification) {
+	if err := uc.notificationRepo.Create(ctx, notifications); err != nil {
+		log.Error().Err(err).Int("count", len(notifications)).Msg("failed to deliver moderation notifications")
+	}
+}
+
+// ListAudit returns the moderation audit log, newest first. Staff only.
+func (uc *ModerationUseCase) ListAudit(ctx context.Context, actorID uuid.UUID, input ListAuditInput) ([]domain.AuditEntry, int64, error) {
+	if err := uc.requireModerator(ctx, actorID); err != nil {
+		return nil, 0, err
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 50
+	}
+
+	var targetID *uuid.UUID
+	if input.TargetID != "" {
+		id := uuid.MustParse(input.TargetID)
+		targetID = &id
+	}
+	return uc.auditRepo.List(ctx, targetID, page, perPage)
+}
+
+func (uc *ModerationUseCase) requireModerator(ctx context.Context, userID uuid.UUID) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsModerator() {
+		return apperror.Forbidden()
+	}
+	return nil
+}
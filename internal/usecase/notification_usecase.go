@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type ListNotificationsInput struct {
+	Unread  bool `form:"unread"`
+	Page    int  `form:"page"     validate:"omitempty,min=1"`
+	PerPage int  `form:"per_page" validate:"omitempty,min=1,max=100"`
+}
+
+// MarkNotificationsReadInput lists the notifications to mark as read; an
+// empty list marks all of them.
+type MarkNotificationsReadInput struct {
+	IDs []string `json:"ids" validate:"omitempty,max=100,dive,uuid"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+// NotificationUseCase serves a user's own notification stream.
+type NotificationUseCase struct {
+	notificationRepo repository.NotificationRepository
+}
+
+func NewNotificationUseCase(notificationRepo repository.NotificationRepository) *NotificationUseCase {
+	return &NotificationUseCase{notificationRepo: notificationRepo}
+}
+
+// List returns the user's notifications, newest first, and how many are unread.
+func (uc *NotificationUseCase) List(ctx context.Context, userID uuid.UUID, input ListNotificationsInput) ([]domain.Notification, int64, int64, error) {
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+	return uc.notificationRepo.List(ctx, userID, input.Unread, page, perPage)
+}
+
+// MarkRead marks notifications as read and returns how many changed.
+func (uc *NotificationUseCase) MarkRead(ctx context.Context, userID uuid.UUID, input MarkNotificationsReadInput) (int64, error) {
+	ids := make([]uuid.UUID, len(input.IDs))
+	for i, raw := range input.IDs {
+		ids[i] = uuid.MustParse(raw) // validated by the DTO
+	}
+	return uc.notificationRepo.MarkRead(ctx, userID, ids)
+}
@@ -2,68 +2,155 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
 
-	"github.com/acidsoft/gorestteach/internal/config"
 	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
 	"github.com/acidsoft/gorestteach/internal/repository"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/markdown"
+	"github.com/acidsoft/gorestteach/pkg/patch"
 	"github.com/google/uuid"
 )
 
 // ─── DTOs ────────────────────────────────────────────────────────────────────
 
 type CreatePostInput struct {
-	Title string `json:"title" validate:"required,min=3,max=255"`
-	Body  string `json:"body"  validate:"required,min=10"`
+	Title      string   `json:"title"       validate:"required,min=3,max=255"`
+	Body       string   `json:"body"        validate:"required,min=10"`
+	BodyFormat string   `json:"body_format" validate:"omitempty,oneof=plain markdown"`                    // default plain
+	Visibility string   `json:"visibility"  validate:"omitempty,oneof=public followers unlisted private"` // default public
+	Tags       []string `json:"tags"        validate:"omitempty,max=10,dive,required,max=50"`
 }
 
 type UpdatePostInput struct {
-	Title string `json:"title" validate:"omitempty,min=3,max=255"`
-	Body  string `json:"body"  validate:"omitempty,min=10"`
+	Title      string `json:"title"       validate:"omitempty,min=3,max=255"`
+	Body       string `json:"body"        validate:"omitempty,min=10"`
+	BodyFormat string `json:"body_format" validate:"omitempty,oneof=plain markdown"`
+	Visibility string `json:"visibility"  validate:"omitempty,oneof=public followers unlisted private"`
+	// Tags replaces the whole tag set when present; omit it to keep the
+	// current tags, send [] to clear them.
+	Tags *[]string `json:"tags" validate:"omitempty,max=10,dive,required,max=50"`
+}
+
+// PatchPostInput is a JSON Merge Patch document for a post. Title and body
+// cannot be null; a null body_format or visibility resets it to the default,
+// a null image_id removes the whole gallery and null tags clear them.
+type PatchPostInput struct {
+	Title      patch.Field[string]    `json:"title"       validate:"omitnil,required,min=3,max=255"`
+	Body       patch.Field[string]    `json:"body"        validate:"omitnil,required,min=10"`
+	BodyFormat patch.Field[string]    `json:"body_format" validate:"omitnil,oneof=plain markdown"`
+	Visibility patch.Field[string]    `json:"visibility"  validate:"omitnil,oneof=public followers unlisted private"`
+	Tags       patch.Field[[]string]  `json:"tags"        validate:"omitnil,max=10,dive,required,max=50"`
+	ImageID    patch.Field[uuid.UUID] `json:"image_id"`
 }
 
 type ListPostsInput struct {
-	Page    int    `form:"page"     validate:"omitempty,min=1"`
-	PerPage int    `form:"per_page" validate:"omitempty,min=1,max=100"`
-	Search  string `form:"search"`
+	Page        int        `form:"page"         validate:"omitempty,min=1"`
+	PerPage     int        `form:"per_page"     validate:"omitempty,min=1,max=100"`
+	Search      string     `form:"search"`
+	UserID      string     `form:"user_id"      validate:"omitempty,uuid"`
+	CreatedFrom *time.Time `form:"created_from"`
+	CreatedTo   *time.Time `form:"created_to"`
+	UpdatedFrom *time.Time `form:"updated_from"`
+	UpdatedTo   *time.Time `form:"updated_to"`
+	HasImage    *bool      `form:"has_image"`
+	Tag         string     `form:"tag"          validate:"omitempty,max=50"`
+	Sort        string     `form:"sort"         validate:"omitempty,max=200"`
+}
+
+type ListTrashInput struct {
+	Page    int `form:"page"     validate:"omitempty,min=1"`
+	PerPage int `form:"per_page" validate:"omitempty,min=1,max=100"`
+}
+
+type FeedInput struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"  validate:"omitempty,min=1,max=100"`
+}
+
+// postSortColumns is the allowlist of fields accepted by ?sort=.
+// Keys are the public field names, values the underlying column.
+var postSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
 }
 
 // ─── Use Case ────────────────────────────────────────────────────────────────
 
 type PostUseCase struct {
-	postRepo  repository.PostRepository
-	imageRepo repository.ImageRepository
-	uploadCfg *config.UploadConfig
+	postRepo     repository.PostRepository
+	galleryRepo  repository.PostImageRepository
+	reactionRepo repository.ReactionRepository
+	bookmarkRepo repository.BookmarkRepository
+	uploads      *UploadUseCase
+	storage      *StorageUseCase
+	urls         *imageurl.Signer
 }
 
 func NewPostUseCase(
 	postRepo repository.PostRepository,
-	imageRepo repository.ImageRepository,
-	uploadCfg *config.UploadConfig,
+	galleryRepo repository.PostImageRepository,
+	reactionRepo repository.ReactionRepository,
+	bookmarkRepo repository.BookmarkRepository,
+	uploads *UploadUseCase,
+	storage *StorageUseCase,
+	urls *imageurl.Signer,
 ) *PostUseCase {
-	return &PostUseCase{postRepo: postRepo, imageRepo: imageRepo, uploadCfg: uploadCfg}
+	return &PostUseCase{
+		postRepo:     postRepo,
+		galleryRepo:  galleryRepo,
+		reactionRepo: reactionRepo,
+		bookmarkRepo: bookmarkRepo,
+		uploads:      uploads,
+		storage:      storage,
+		urls:         urls,
+	}
 }
 
 // Create creates a new post owned by userID.
 func (uc *PostUseCase) Create(ctx context.Context, userID uuid.UUID, input CreatePostInput) (*domain.Post, error) {
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	post := &domain.Post{
-		UserID: userID,
-		Title:  input.Title,
-		Body:   input.Body,
+		UserID:     userID,
+		Title:      input.Title,
+		Body:       input.Body,
+		BodyFormat: domain.BodyFormatPlain,
+		Visibility: domain.VisibilityPublic,
+		Tags:       tags,
+	}
+	if input.BodyFormat != "" {
+		post.BodyFormat = domain.BodyFormat(input.BodyFormat)
 	}
+	if input.Visibility != "" {
+		post.Visibility = domain.Visibility(input.Visibility)
+	}
+	renderBody(post)
 	if err := uc.postRepo.Create(ctx, post); err != nil {
 		return nil, err
 	}
 	return post, nil
 }
 
-// GetByID returns a single post with author info.
-func (uc *PostUseCase) GetByID(ctx context.Context, id uuid.UUID) (*domain.Post, error) {
-	return uc.postRepo.GetByID(ctx, id)
+// GetByID returns a single post with author info and viewer-specific fields.
+func (uc *PostUseCase) GetByID(ctx context.Context, id, viewerID uuid.UUID) (*domain.Post, error) {
+	post, err := uc.postRepo.GetByID(ctx, id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.decorateOne(ctx, viewerID, post)
 }
 
-// List returns a paginated list of posts with optional search.
-func (uc *PostUseCase) List(ctx context.Context, input ListPostsInput) ([]domain.Post, int64, error) {
+// List returns a paginated, filtered and sorted list of posts.
+func (uc *PostUseCase) List(ctx context.Context, viewerID uuid.UUID, input ListPostsInput) ([]domain.Post, int64, error) {
 	page := input.Page
 	if page < 1 {
 		page = 1
@@ -72,12 +159,206 @@ func (uc *PostUseCase) List(ctx context.Context, input ListPostsInput) ([]domain
 	if perPage < 1 {
 		perPage = 10
 	}
-	return uc.postRepo.List(ctx, page, perPage, input.Search)
+
+	filter := repository.PostFilter{
+		ViewerID:    viewerID,
+		Page:        page,
+		PerPage:     perPage,
+		Search:      input.Search,
+		CreatedFrom: input.CreatedFrom,
+		CreatedTo:   input.CreatedTo,
+		UpdatedFrom: input.UpdatedFrom,
+		UpdatedTo:   input.UpdatedTo,
+		HasImage:    input.HasImage,
+		Tag:         domain.NormalizeTagSlug(input.Tag),
+	}
+
+	var details []apperror.FieldError
+	if input.UserID != "" {
+		// Already validated by the `uuid` tag.
+		id := uuid.MustParse(input.UserID)
+		filter.UserID = &id
+	}
+	if input.Tag != "" && filter.Tag == "" {
+		details = append(details, apperror.FieldError{
+			Field:   "tag",
+			Message: "Tag '" + input.Tag + "' must contain at least one letter or digit",
+		})
+	}
+	if input.CreatedFrom != nil && input.CreatedTo != nil && input.CreatedFrom.After(*input.CreatedTo) {
+		details = append(details, apperror.FieldError{Field: "created_from", Message: "Must not be after created_to"})
+	}
+	if input.UpdatedFrom != nil && input.UpdatedTo != nil && input.UpdatedFrom.After(*input.UpdatedTo) {
+		details = append(details, apperror.FieldError{Field: "updated_from", Message: "Must not be after updated_to"})
+	}
+
+	sort, sortErrs := parsePostSort(input.Sort)
+	details = append(details, sortErrs...)
+	if len(details) > 0 {
+		return nil, 0, apperror.ValidationError(details)
+	}
+	filter.Sort = sort
+
+	posts, total, err := uc.postRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := uc.Decorate(ctx, viewerID, posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// Feed returns the newest posts from users the viewer follows. The returned
+// cursor is passed back as ?cursor= to get the next page; it is empty when
+// there are no more posts.
+func (uc *PostUseCase) Feed(ctx context.Context, viewerID uuid.UUID, input FeedInput) ([]domain.Post, string, error) {
+	limit := input.Limit
+	if limit < 1 {
+		limit = 20
+	}
+
+	var after *repository.FeedCursor
+	if input.Cursor != "" {
+		cur, err := decodeFeedCursor(input.Cursor)
+		if err != nil {
+			return nil, "", apperror.ValidationError([]apperror.FieldError{
+				{Field: "cursor", Message: "Invalid or corrupted cursor"},
+			})
+		}
+		after = cur
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	posts, err := uc.postRepo.Feed(ctx, viewerID, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		next = encodeFeedCursor(repository.FeedCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	if err := uc.Decorate(ctx, viewerID, posts); err != nil {
+		return nil, "", err
+	}
+	return posts, next, nil
 }
 
-// Update updates a post, enforcing that only the owner can edit it.
-func (uc *PostUseCase) Update(ctx context.Context, postID, userID uuid.UUID, input UpdatePostInput) (*domain.Post, error) {
-	post, err := uc.postRepo.GetByID(ctx, postID)
+// encodeFeedCursor produces an opaque token; clients must not parse it.
+func encodeFeedCursor(c repository.FeedCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(s string) (*repository.FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.FeedCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// Decorate fills the non-column, per-viewer fields of a page of posts using a
+// fixed number of batched queries regardless of page size, and signs their
+// image URLs. Other use cases
+// that return posts call it too, so every post payload looks the same.
+func (uc *PostUseCase) Decorate(ctx context.Context, viewerID uuid.UUID, posts []domain.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	summaries, err := uc.reactionRepo.Summaries(ctx, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	bookmarked, err := uc.bookmarkRepo.BookmarkedPostIDs(ctx, viewerID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		s := summaries[posts[i].ID]
+		posts[i].Reactions = s.Counts
+		posts[i].MyReaction = s.Mine
+		posts[i].IsBookmarked = bookmarked[posts[i].ID]
+	}
+	uc.urls.SignPosts(posts)
+	return nil
+}
+
+// decorateOne is Decorate for a single post.
+func (uc *PostUseCase) decorateOne(ctx context.Context, viewerID uuid.UUID, post *domain.Post) (*domain.Post, error) {
+	posts := []domain.Post{*post}
+	if err := uc.Decorate(ctx, viewerID, posts); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
+}
+
+// parsePostSort parses a comma-separated sort spec such as "-created_at,title".
+// A leading "-" means descending. Unknown or repeated fields are reported as
+// field errors rather than silently ignored.
+func parsePostSort(spec string) ([]repository.SortField, []apperror.FieldError) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	var fields []repository.SortField
+	var details []apperror.FieldError
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+		column, ok := postSortColumns[name]
+		switch {
+		case !ok:
+			details = append(details, apperror.FieldError{
+				Field:   "sort",
+				Message: "Unknown sort field '" + name + "'; allowed: created_at, updated_at, title",
+			})
+		case seen[name]:
+			details = append(details, apperror.FieldError{
+				Field:   "sort",
+				Message: "Sort field '" + name + "' is listed more than once",
+			})
+		default:
+			seen[name] = true
+			fields = append(fields, repository.SortField{Column: column, Desc: desc})
+		}
+	}
+
+	return fields, details
+}
+
+// Update updates a post, enforcing that only the owner can edit it. When
+// ifMatch is set (from the If-Match header) the post must still be at that
+// version.
+func (uc *PostUseCase) Update(ctx context.Context, postID, userID uuid.UUID, input UpdatePostInput, ifMatch *int64) (*domain.Post, error) {
+	post, err := uc.postRepo.GetByID(ctx, postID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +366,9 @@ func (uc *PostUseCase) Update(ctx context.Context, postID, userID uuid.UUID, inp
 	if post.UserID != userID {
 		return nil, apperror.Forbidden()
 	}
+	if err := checkPostVersion(post, ifMatch); err != nil {
+		return nil, err
+	}
 
 	if input.Title != "" {
 		post.Title = input.Title
@@ -92,16 +376,107 @@ func (uc *PostUseCase) Update(ctx context.Context, postID, userID uuid.UUID, inp
 	if input.Body != "" {
 		post.Body = input.Body
 	}
+	if input.BodyFormat != "" {
+		post.BodyFormat = domain.BodyFormat(input.BodyFormat)
+	}
+	if input.Visibility != "" {
+		post.Visibility = domain.Visibility(input.Visibility)
+	}
+	renderBody(post)
+
+	if input.Tags == nil {
+		if err := uc.postRepo.Update(ctx, post, userID); err != nil {
+			return nil, err
+		}
+		return uc.decorateOne(ctx, userID, post)
+	}
 
-	if err := uc.postRepo.Update(ctx, post); err != nil {
+	tags, err := normalizeTags(*input.Tags)
+	if err != nil {
 		return nil, err
 	}
-	return post, nil
+	post.Tags = tags
+	if err := uc.postRepo.UpdateWithTags(ctx, post, userID); err != nil {
+		return nil, err
+	}
+	return uc.decorateOne(ctx, userID, post)
 }
 
-// Delete deletes a post, enforcing ownership.
-func (uc *PostUseCase) Delete(ctx context.Context, postID, userID uuid.UUID) error {
-	post, err := uc.postRepo.GetByID(ctx, postID)
+// Patch applies a merge patch to a post, enforcing ownership and, when ifMatch
+// is set, the expected version.
+func (uc *PostUseCase) Patch(ctx context.Context, postID, userID uuid.UUID, input PatchPostInput, ifMatch *int64) (*domain.Post, error) {
+	var details []apperror.FieldError
+	if input.Title.Null {
+		details = append(details, nullNotAllowed("title"))
+	}
+	if input.Body.Null {
+		details = append(details, nullNotAllowed("body"))
+	}
+	if input.ImageID.HasValue() {
+		details = append(details, apperror.FieldError{
+			Field:   "image_id",
+			Message: "Can only be set to null; manage images via /posts/{id}/images",
+		})
+	}
+	if len(details) > 0 {
+		return nil, apperror.ValidationError(details)
+	}
+
+	post, err := uc.postRepo.GetByID(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.UserID != userID {
+		return nil, apperror.Forbidden()
+	}
+	if err := checkPostVersion(post, ifMatch); err != nil {
+		return nil, err
+	}
+
+	if input.Title.HasValue() {
+		post.Title = input.Title.Value
+	}
+	if input.Body.HasValue() {
+		post.Body = input.Body.Value
+	}
+	if input.BodyFormat.Null {
+		post.BodyFormat = domain.BodyFormatPlain
+	} else if input.BodyFormat.HasValue() {
+		post.BodyFormat = domain.BodyFormat(input.BodyFormat.Value)
+	}
+	renderBody(post)
+	if input.Visibility.Null {
+		post.Visibility = domain.VisibilityPublic
+	} else if input.Visibility.HasValue() {
+		post.Visibility = domain.Visibility(input.Visibility.Value)
+	}
+	if input.ImageID.Null {
+		post.ImageID = nil
+	}
+
+	if !input.Tags.Set {
+		if err := uc.postRepo.Update(ctx, post, userID); err != nil {
+			return nil, err
+		}
+		return uc.decorateOne(ctx, userID, post)
+	}
+
+	tags, err := normalizeTags(input.Tags.Value) // null clears: Value is nil
+	if err != nil {
+		return nil, err
+	}
+	post.Tags = tags
+	if err := uc.postRepo.UpdateWithTags(ctx, post, userID); err != nil {
+		return nil, err
+	}
+	return uc.decorateOne(ctx, userID, post)
+}
+
+// Delete moves a post to the trash, enforcing ownership and, when ifMatch is
+// set, the expected version.
+func (uc *PostUseCase) Delete(ctx context.Context, postID, userID uuid.UUID, ifMatch *int64) error {
+	post, err := uc.postRepo.GetByID(ctx, postID, userID)
 	if err != nil {
 		return err
 	}
@@ -109,14 +484,63 @@ func (uc *PostUseCase) Delete(ctx context.Context, postID, userID uuid.UUID) err
 	if post.UserID != userID {
 		return apperror.Forbidden()
 	}
+	if err := checkPostVersion(post, ifMatch); err != nil {
+		return err
+	}
+
+	return uc.postRepo.Delete(ctx, postID, ifMatch)
+}
+
+// checkPostVersion fails fast when the client's If-Match version is already
+// stale. The repository re-checks it atomically on write.
+func checkPostVersion(post *domain.Post, ifMatch *int64) error {
+	if ifMatch != nil && post.Version != *ifMatch {
+		return apperror.PreconditionFailed("The post has been modified since it was read")
+	}
+	return nil
+}
 
-	return uc.postRepo.Delete(ctx, postID)
+// ListTrash returns the user's soft-deleted posts that can still be restored.
+func (uc *PostUseCase) ListTrash(ctx context.Context, userID uuid.UUID, input ListTrashInput) ([]domain.Post, int64, error) {
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 10
+	}
+	posts, total, err := uc.postRepo.ListTrash(ctx, userID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	uc.urls.SignPosts(posts)
+	return posts, total, nil
 }
 
-// AttachImage validates and stores an image blob, then links it to the post.
-func (uc *PostUseCase) AttachImage(ctx context.Context, postID, userID uuid.UUID, data []byte, contentType string) (*domain.Post, error) {
+// Restore moves a post out of the trash, enforcing ownership.
+func (uc *PostUseCase) Restore(ctx context.Context, postID, userID uuid.UUID) (*domain.Post, error) {
+	post, err := uc.postRepo.GetDeletedByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.UserID != userID {
+		return nil, apperror.Forbidden()
+	}
+
+	if err := uc.postRepo.Restore(ctx, postID); err != nil {
+		return nil, err
+	}
+	return uc.GetByID(ctx, postID, userID)
+}
+
+// AttachImage validates and stores an image blob within the user's storage
+// quota, then puts it at the front of the post's gallery, making it the
+// cover. Earlier images are kept.
+func (uc *PostUseCase) AttachImage(ctx context.Context, postID, userID uuid.UUID, input ImageUpload) (*domain.Post, error) {
 	// Verify post exists and caller is the owner
-	post, err := uc.postRepo.GetByID(ctx, postID)
+	post, err := uc.postRepo.GetByID(ctx, postID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -125,22 +549,81 @@ func (uc *PostUseCase) AttachImage(ctx context.Context, postID, userID uuid.UUID
 		return nil, apperror.Forbidden()
 	}
 
-	if err := validateImageUpload(data, contentType, uc.uploadCfg.MaxSizeMB); err != nil {
+	img, finish, err := newUploadedImage(input)
+	if err != nil {
+		return nil, err
+	}
+	check, err := uc.storage.charge(ctx, userID, img)
+	if err != nil {
 		return nil, err
 	}
 
-	img := &domain.Image{
-		Data:        data,
-		ContentType: contentType,
-		Size:        int64(len(data)),
+	entry := domain.PostImage{Image: img}
+	if err := check(finish(uc.galleryRepo.Add(ctx, postID, []domain.PostImage{entry}, true))); err != nil {
+		return nil, err
 	}
-	if err := uc.imageRepo.Save(ctx, img); err != nil {
+
+	post, err = uc.postRepo.GetByID(ctx, postID, userID)
+	if err != nil {
 		return nil, err
 	}
+	return uc.decorateOne(ctx, userID, post)
+}
 
-	if err := uc.postRepo.UpdateImage(ctx, postID, img.ID); err != nil {
+// AttachUpload attaches a complete resumable upload to the post, with the
+// same checks as AttachImage.
+func (uc *PostUseCase) AttachUpload(ctx context.Context, postID, userID, uploadID uuid.UUID) (*domain.Post, error) {
+	var post *domain.Post
+	err := uc.uploads.attach(ctx, userID, uploadID, func(input ImageUpload) error {
+		var err error
+		post, err = uc.AttachImage(ctx, postID, userID, input)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
+	return post, nil
+}
+
+// renderBody refreshes the cached HTML rendering of the post body.
+func renderBody(post *domain.Post) {
+	if post.BodyFormat == domain.BodyFormatMarkdown {
+		post.BodyHTML = markdown.ToHTML(post.Body)
+	} else {
+		post.BodyHTML = markdown.PlainToHTML(post.Body)
+	}
+}
 
-	return uc.postRepo.GetByID(ctx, postID)
+// normalizeTags turns user-supplied tag names into de-duplicated tags keyed by
+// slug. Names that normalize to an empty slug are rejected.
+func normalizeTags(raw []string) ([]domain.Tag, error) {
+	tags := make([]domain.Tag, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	var details []apperror.FieldError
+
+	for _, name := range raw {
+		slug := domain.NormalizeTagSlug(name)
+		if slug == "" {
+			details = append(details, apperror.FieldError{
+				Field:   "tags",
+				Message: "Tag '" + name + "' must contain at least one letter or digit",
+			})
+			continue
+		}
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		display := strings.TrimSpace(name)
+		if runes := []rune(display); len(runes) > domain.MaxTagSlugLength {
+			display = string(runes[:domain.MaxTagSlugLength])
+		}
+		tags = append(tags, domain.Tag{Slug: slug, Name: display})
+	}
+
+	if len(details) > 0 {
+		return nil, apperror.ValidationError(details)
+	}
+	return tags, nil
 }
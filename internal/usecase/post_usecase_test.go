@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+// filterPostRepo records the filter of the last List call and finds nothing.
+type filterPostRepo struct {
+	repository.PostRepository
+	filter *repository.PostFilter
+}
+
+func (r *filterPostRepo) List(_ context.Context, filter repository.PostFilter) ([]domain.Post, int64, error) {
+	r.filter = &filter
+	return nil, 0, nil
+}
+
+func TestListTagFilter(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		invalid bool
+	}{
+		{tag: "", want: ""},
+		{tag: "Go Lang", want: "go-lang"},
+		{tag: "%%%", invalid: true},
+		{tag: "  ", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			posts := &filterPostRepo{}
+			uc := NewPostUseCase(posts, nil, nil, nil, nil, nil, nil)
+
+			_, _, err := uc.List(context.Background(), uuid.Nil, ListPostsInput{Tag: tt.tag})
+			if tt.invalid {
+				var appErr *apperror.AppError
+				if !errors.As(err, &appErr) || appErr.Code != apperror.ErrValidation {
+					t.Fatalf("err = %v, want a validation error", err)
+				}
+				if posts.filter != nil {
+					t.Error("posts were listed without the tag filter")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if posts.filter.Tag != tt.want {
+				t.Errorf("filter tag = %q, want %q", posts.filter.Tag, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type SetReactionInput struct {
+	Reaction domain.ReactionKind `json:"reaction" validate:"required,oneof=like love laugh wow sad angry"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+type ReactionUseCase struct {
+	reactionRepo repository.ReactionRepository
+	postRepo     repository.PostRepository
+}
+
+func NewReactionUseCase(reactionRepo repository.ReactionRepository, postRepo repository.PostRepository) *ReactionUseCase {
+	return &ReactionUseCase{reactionRepo: reactionRepo, postRepo: postRepo}
+}
+
+// Set records (or changes) the user's reaction and returns the post's new totals.
+func (uc *ReactionUseCase) Set(ctx context.Context, postID, userID uuid.UUID, input SetReactionInput) (*domain.ReactionSummary, error) {
+	if _, err := uc.postRepo.GetByID(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	if err := uc.reactionRepo.Set(ctx, postID, userID, input.Reaction); err != nil {
+		return nil, err
+	}
+	return uc.summary(ctx, postID, userID)
+}
+
+// Remove clears the user's reaction (no-op if there was none) and returns the new totals.
+func (uc *ReactionUseCase) Remove(ctx context.Context, postID, userID uuid.UUID) (*domain.ReactionSummary, error) {
+	if _, err := uc.postRepo.GetByID(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	if err := uc.reactionRepo.Remove(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	return uc.summary(ctx, postID, userID)
+}
+
+func (uc *ReactionUseCase) summary(ctx context.Context, postID, userID uuid.UUID) (*domain.ReactionSummary, error) {
+	summaries, err := uc.reactionRepo.Summaries(ctx, []uuid.UUID{postID}, userID)
+	if err != nil {
+		return nil, err
+	}
+	s := summaries[postID]
+	return &s, nil
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/textdiff"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type ListRevisionsInput struct {
+	Page    int `form:"page"     validate:"omitempty,min=1"`
+	PerPage int `form:"per_page" validate:"omitempty,min=1,max=100"`
+}
+
+// RevisionDiff is a stored revision together with the line diff that turns it
+// into the current version of the post.
+type RevisionDiff struct {
+	Revision        *domain.PostRevision `json:"revision"`
+	CurrentRevision int                  `json:"current_revision"`
+	TitleDiff       []textdiff.Line      `json:"title_diff"`
+	BodyDiff        []textdiff.Line      `json:"body_diff"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+// RevisionUseCase exposes a post's edit history to its author.
+type RevisionUseCase struct {
+	revisionRepo repository.PostRevisionRepository
+	postRepo     repository.PostRepository
+	postUC       *PostUseCase
+}
+
+func NewRevisionUseCase(
+	revisionRepo repository.PostRevisionRepository,
+	postRepo repository.PostRepository,
+	postUC *PostUseCase,
+) *RevisionUseCase {
+	return &RevisionUseCase{revisionRepo: revisionRepo, postRepo: postRepo, postUC: postUC}
+}
+
+// List returns the post's revisions, newest first. Only the author may see them.
+func (uc *RevisionUseCase) List(ctx context.Context, postID, userID uuid.UUID, input ListRevisionsInput) ([]domain.PostRevision, int64, error) {
+	if _, err := uc.ownedPost(ctx, postID, userID); err != nil {
+		return nil, 0, err
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 20
+	}
+	return uc.revisionRepo.List(ctx, postID, page, perPage)
+}
+
+// Get returns one revision with a line-level diff against the current version.
+func (uc *RevisionUseCase) Get(ctx context.Context, postID, userID uuid.UUID, revision int) (*RevisionDiff, error) {
+	post, err := uc.ownedPost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := uc.revisionRepo.Get(ctx, postID, revision)
+	if err != nil {
+		return nil, err
+	}
+	current, err := uc.revisionRepo.LatestNumber(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevisionDiff{
+		Revision:        rev,
+		CurrentRevision: current,
+		TitleDiff:       textdiff.Lines(rev.Title, post.Title),
+		BodyDiff:        textdiff.Lines(rev.Body, post.Body),
+	}, nil
+}
+
+// Restore rolls the post's title and body back to the given revision. The
+// rollback is itself recorded as a new revision, so it can be undone too.
+func (uc *RevisionUseCase) Restore(ctx context.Context, postID, userID uuid.UUID, revision int) (*domain.Post, error) {
+	post, err := uc.ownedPost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := uc.revisionRepo.Get(ctx, postID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Title = rev.Title
+	post.Body = rev.Body
+	renderBody(post)
+	if err := uc.postRepo.Update(ctx, post, userID); err != nil {
+		return nil, err
+	}
+	return uc.postUC.GetByID(ctx, postID, userID)
+}
+
+func (uc *RevisionUseCase) ownedPost(ctx context.Context, postID, userID uuid.UUID) (*domain.Post, error) {
+	post, err := uc.postRepo.GetByID(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != userID {
+		return nil, apperror.Forbidden()
+	}
+	return post, nil
+}
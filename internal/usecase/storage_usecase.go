@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+// StorageUsage is how much of their storage quota a user has used.
+// QuotaBytes and RemainingBytes are nil when there is no quota. Images are
+// counted for the user who first uploaded their bytes only: uploading a
+// duplicate is free.
+type StorageUsage struct {
+	Images         int64  `json:"images"`
+	UsedBytes      int64  `json:"used_bytes"`
+	QuotaBytes     *int64 `json:"quota_bytes"`
+	RemainingBytes *int64 `json:"remaining_bytes"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+// StorageUseCase accounts for the images users upload and keeps each user
+// within the storage quota. An image is charged to the user who uploaded it
+// until it is deleted: an image no longer used is freed when the orphan
+// collector deletes it.
+//
+// The quota is enforced exactly when the images are saved: the saves of one
+// user are serialized, and each fails if it would take the user's total
+// past the quota. Uploads are also cut off while they are read, as soon as
+// they no longer fit in what was left when they started, so that a file too
+// large is not stored in full first.
+type StorageUseCase struct {
+	imageRepo repository.ImageRepository
+	cfg       *config.UploadConfig
+}
+
+func NewStorageUseCase(imageRepo repository.ImageRepository, cfg *config.UploadConfig) *StorageUseCase {
+	return &StorageUseCase{imageRepo: imageRepo, cfg: cfg}
+}
+
+// Usage returns how much of their quota userID has used.
+func (uc *StorageUseCase) Usage(ctx context.Context, userID uuid.UUID) (*StorageUsage, error) {
+	usage, err := uc.imageRepo.Usage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := &StorageUsage{Images: usage.Images, UsedBytes: usage.Bytes}
+	if quota := uc.quota(); quota > 0 {
+		remaining := max(quota-usage.Bytes, 0)
+		out.QuotaBytes, out.RemainingBytes = &quota, &remaining
+	}
+	return out, nil
+}
+
+// quota is the quota in bytes, 0 if there is none.
+func (uc *StorageUseCase) quota() int64 {
+	return uc.cfg.QuotaMB << 20
+}
+
+// fits checks that a file of size bytes fits in what is left of userID's
+// quota, before it is sent.
+func (uc *StorageUseCase) fits(ctx context.Context, userID uuid.UUID, size int64) error {
+	if uc.quota() == 0 {
+		return nil
+	}
+	usage, err := uc.imageRepo.Usage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usage.Bytes+size > uc.quota() {
+		return apperror.QuotaExceeded(uc.cfg.QuotaMB)
+	}
+	return nil
+}
+
+// charge makes userID the uploader of new images and limits their bytes,
+// together, to what is left of the user's quota. The caller must pass the
+// result of saving the images to the returned check, which reports a save
+// stopped by the quota as QuotaExceeded.
+func (uc *StorageUseCase) charge(ctx context.Context, userID uuid.UUID, images ...*domain.Image) (check func(saveErr error) error, err error) {
+	for _, img := range images {
+		img.UserID = &userID
+		img.Quota = uc.quota()
+	}
+	if uc.quota() == 0 {
+		return func(saveErr error) error { return saveErr }, nil
+	}
+
+	usage, err := uc.imageRepo.Usage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if usage.Bytes >= uc.quota() {
+		return nil, apperror.QuotaExceeded(uc.cfg.QuotaMB)
+	}
+	budget := &quotaBudget{left: uc.quota() - usage.Bytes}
+	for _, img := range images {
+		img.Body = &quotaReader{r: img.Body, budget: budget}
+	}
+	return func(saveErr error) error {
+		if saveErr != nil && (budget.exceeded || errors.Is(saveErr, repository.ErrQuotaExceeded)) {
+			return apperror.QuotaExceeded(uc.cfg.QuotaMB)
+		}
+		return saveErr
+	}, nil
+}
+
+// errOverQuota stops the storing of an image that does not fit in the quota.
+var errOverQuota = errors.New("storage quota exceeded")
+
+// quotaBudget is the number of bytes a set of uploads may still take up.
+type quotaBudget struct {
+	left     int64
+	exceeded bool
+}
+
+// quotaReader fails once the bytes read through it exceed the budget.
+type quotaReader struct {
+	r      io.Reader
+	budget *quotaBudget
+}
+
+func (qr *quotaReader) Read(p []byte) (int, error) {
+	n, err := qr.r.Read(p)
+	qr.budget.left -= int64(n)
+	if qr.budget.left < 0 {
+		qr.budget.exceeded = true
+		return 0, errOverQuota
+	}
+	return n, err
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+// usageImageRepo reports a fixed usage.
+type usageImageRepo struct {
+	repository.ImageRepository
+	used int64
+}
+
+func (r *usageImageRepo) Usage(context.Context, uuid.UUID) (repository.ImageUsage, error) {
+	return repository.ImageUsage{Images: 1, Bytes: r.used}, nil
+}
+
+func isQuotaExceeded(err error) bool {
+	var appErr *apperror.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperror.ErrQuotaExceeded
+}
+
+func TestStorageCharge(t *testing.T) {
+	const quotaMB = 1
+	userID := uuid.New()
+	newUC := func(used int64, quotaMB int64) *StorageUseCase {
+		return NewStorageUseCase(&usageImageRepo{used: used}, &config.UploadConfig{QuotaMB: quotaMB})
+	}
+	newImage := func(size int) *domain.Image {
+		return &domain.Image{Body: strings.NewReader(strings.Repeat("x", size))}
+	}
+
+	t.Run("sets the uploader and quota", func(t *testing.T) {
+		images := []*domain.Image{newImage(1), newImage(1)}
+		if _, err := newUC(0, quotaMB).charge(context.Background(), userID, images...); err != nil {
+			t.Fatal(err)
+		}
+		for _, img := range images {
+			if img.UserID == nil || *img.UserID != userID || img.Quota != quotaMB<<20 {
+				t.Errorf("image charged to %v with quota %d", img.UserID, img.Quota)
+			}
+		}
+	})
+
+	t.Run("without a quota", func(t *testing.T) {
+		img := newImage(10 << 20)
+		check, err := newUC(1<<40, 0).charge(context.Background(), userID, img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, img.Body); err != nil {
+			t.Fatalf("reading the body: %v", err)
+		}
+		if img.Quota != 0 {
+			t.Errorf("quota = %d, want none", img.Quota)
+		}
+		saveErr := errors.New("disk full")
+		if err := check(saveErr); err != saveErr {
+			t.Errorf("check = %v, want the save error", err)
+		}
+	})
+
+	t.Run("already full", func(t *testing.T) {
+		_, err := newUC(quotaMB<<20, quotaMB).charge(context.Background(), userID, newImage(1))
+		if !isQuotaExceeded(err) {
+			t.Errorf("err = %v, want QUOTA_EXCEEDED", err)
+		}
+	})
+
+	t.Run("uploads together over what is left", func(t *testing.T) {
+		left := 1000
+		images := []*domain.Image{newImage(600), newImage(600)}
+		check, err := newUC(quotaMB<<20-int64(left), quotaMB).charge(context.Background(), userID, images...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, images[0].Body); err != nil {
+			t.Fatalf("first image: %v", err)
+		}
+		_, err = io.Copy(io.Discard, images[1].Body)
+		if err == nil {
+			t.Fatal("second image read past the quota")
+		}
+		if err := check(apperror.Internal(err)); !isQuotaExceeded(err) {
+			t.Errorf("check = %v, want QUOTA_EXCEEDED", err)
+		}
+	})
+
+	t.Run("refused when saved", func(t *testing.T) {
+		// Another upload of the same user got in first.
+		check, err := newUC(0, quotaMB).charge(context.Background(), userID, newImage(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := check(apperror.Internal(repository.ErrQuotaExceeded)); !isQuotaExceeded(err) {
+			t.Errorf("check = %v, want QUOTA_EXCEEDED", err)
+		}
+	})
+
+	t.Run("other failures", func(t *testing.T) {
+		check, err := newUC(0, quotaMB).charge(context.Background(), userID, newImage(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		saveErr := apperror.Internal(errors.New("connection reset"))
+		if err := check(saveErr); err != saveErr {
+			t.Errorf("check = %v, want the save error", err)
+		}
+		if err := check(nil); err != nil {
+			t.Errorf("check(nil) = %v", err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+type ListTagsInput struct {
+	Page    int    `form:"page"     validate:"omitempty,min=1"`
+	PerPage int    `form:"per_page" validate:"omitempty,min=1,max=100"`
+	Prefix  string `form:"prefix"   validate:"omitempty,max=50"`
+}
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+type TagUseCase struct {
+	tagRepo repository.TagRepository
+	postUC  *PostUseCase
+}
+
+func NewTagUseCase(tagRepo repository.TagRepository, postUC *PostUseCase) *TagUseCase {
+	return &TagUseCase{tagRepo: tagRepo, postUC: postUC}
+}
+
+// List returns tags with their usage counts, optionally filtered by a slug
+// prefix for autocomplete.
+func (uc *TagUseCase) List(ctx context.Context, input ListTagsInput) ([]domain.TagWithCount, int64, error) {
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := input.PerPage
+	if perPage < 1 {
+		perPage = 10
+	}
+	return uc.tagRepo.ListWithCounts(ctx, domain.NormalizeTagSlug(input.Prefix), page, perPage)
+}
+
+// ListPosts returns posts carrying the given tag. Unknown tags are a 404 rather
+// than an empty list so clients can tell a typo from an unused tag.
+func (uc *TagUseCase) ListPosts(ctx context.Context, viewerID uuid.UUID, slug string, input ListPostsInput) ([]domain.Post, int64, error) {
+	tag, err := uc.tagRepo.GetBySlug(ctx, domain.NormalizeTagSlug(slug))
+	if err != nil {
+		return nil, 0, err
+	}
+	input.Tag = tag.Slug
+	return uc.postUC.List(ctx, viewerID, input)
+}
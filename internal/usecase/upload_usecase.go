@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/acidsoft/gorestteach/internal/config"
+	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/repository"
+	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ─── DTOs ────────────────────────────────────────────────────────────────────
+
+// CreateUploadInput starts a resumable upload of a file of Length bytes.
+// Metadata is the client's Upload-Metadata header, returned as sent.
+type CreateUploadInput struct {
+	Length   int64
+	Metadata string
+}
+
+// FromUploadInput names a complete resumable upload to use as an image,
+// instead of sending the file in the request.
+type FromUploadInput struct {
+	UploadID uuid.UUID `json:"upload_id" validate:"required"`
+}
+
+// maxUploadMetadata is the longest Upload-Metadata header kept.
+const maxUploadMetadata = 1024
+
+// ─── Use Case ────────────────────────────────────────────────────────────────
+
+// UploadUseCase runs resumable uploads: a file is sent in as many requests
+// as the connection needs, then attached to the sender's profile or one of
+// their posts, where it goes through the same checks as a file sent whole.
+type UploadUseCase struct {
+	uploadRepo repository.UploadRepository
+	storage    *StorageUseCase
+	cfg        *config.UploadConfig
+}
+
+func NewUploadUseCase(uploadRepo repository.UploadRepository, storage *StorageUseCase, cfg *config.UploadConfig) *UploadUseCase {
+	return &UploadUseCase{uploadRepo: uploadRepo, storage: storage, cfg: cfg}
+}
+
+// MaxLength is the size of the largest file that can be uploaded.
+func (uc *UploadUseCase) MaxLength() int64 {
+	return uc.cfg.MaxSizeMB << 20
+}
+
+// Create starts an upload for userID. A file that would not fit in what is
+// left of their storage quota is refused before it is sent.
+func (uc *UploadUseCase) Create(ctx context.Context, userID uuid.UUID, input CreateUploadInput) (*domain.Upload, error) {
+	if input.Length <= 0 {
+		return nil, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Upload-Length must be positive")
+	}
+	if input.Length > uc.MaxLength() {
+		return nil, apperror.FileTooLarge(uc.cfg.MaxSizeMB)
+	}
+	if len(input.Metadata) > maxUploadMetadata {
+		return nil, apperror.New(http.StatusBadRequest, apperror.ErrBadRequest, "Upload-Metadata is too long")
+	}
+	if err := uc.storage.fits(ctx, userID, input.Length); err != nil {
+		return nil, err
+	}
+
+	upload := &domain.Upload{
+		UserID:    userID,
+		Length:    input.Length,
+		Metadata:  input.Metadata,
+		ExpiresAt: time.Now().Add(uc.cfg.ResumableExpiry),
+	}
+	if err := uc.uploadRepo.Create(ctx, upload); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Get returns the progress of one of userID's uploads. Other users' uploads
+// and expired ones are reported as not found.
+func (uc *UploadUseCase) Get(ctx context.Context, userID, id uuid.UUID) (*domain.Upload, error) {
+	upload, err := uc.uploadRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserID != userID || upload.ExpiresAt.Before(time.Now()) {
+		return nil, apperror.NotFound("Upload")
+	}
+	return upload, nil
+}
+
+// Append stores the bytes of body as the continuation of an upload at
+// offset, which must be how much of it has been received. size is the
+// number of bytes in body, or -1 if it is not known; bytes past the end of
+// the file are not read.
+func (uc *UploadUseCase) Append(ctx context.Context, userID, id uuid.UUID, offset int64, body io.Reader, size int64) (*domain.Upload, error) {
+	upload, err := uc.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return nil, apperror.Conflict("Upload-Offset does not match the upload's offset")
+	}
+	remaining := upload.Length - upload.Offset
+	if size > remaining {
+		return nil, apperror.New(http.StatusRequestEntityTooLarge, apperror.ErrFileTooLarge,
+			"The chunk goes past the end of the upload")
+	}
+
+	if err := uc.uploadRepo.Append(ctx, upload, io.LimitReader(body, remaining)); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Terminate deletes one of userID's uploads, complete or not.
+func (uc *UploadUseCase) Terminate(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := uc.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	return uc.uploadRepo.Delete(ctx, id)
+}
+
+// attach passes a complete upload of userID to use as an image, and deletes
+// the upload once use has succeeded: the image keeps a copy of the bytes.
+// The content type is sniffed from the first bytes, as for a file sent
+// whole.
+func (uc *UploadUseCase) attach(ctx context.Context, userID, id uuid.UUID, use func(ImageUpload) error) error {
+	upload, err := uc.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !upload.Complete() {
+		return apperror.Conflict("The upload is not complete")
+	}
+
+	rc, err := uc.uploadRepo.Open(ctx, upload)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	src := &uploadSource{r: rc}
+	br := bufio.NewReaderSize(src, 512) // all http.DetectContentType looks at
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return apperror.Internal(err)
+	}
+
+	if err := use(ImageUpload{Body: br, ContentType: http.DetectContentType(head)}); err != nil {
+		if src.err != nil {
+			return apperror.Internal(src.err) // not the client's file at fault
+		}
+		return err
+	}
+	// The image is in place; an upload left behind is deleted when it expires.
+	if err := uc.uploadRepo.Delete(ctx, id); err != nil && !isNotFound(err) {
+		log.Error().Err(err).Str("upload_id", id.String()).Msg("failed to delete attached upload")
+	}
+	return nil
+}
+
+// uploadSource remembers why reading the stored bytes of an upload failed.
+type uploadSource struct {
+	r   io.Reader
+	err error
+}
+
+func (s *uploadSource) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+	return n, err
+}
@@ -3,12 +3,16 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
-	"github.com/acidsoft/gorestteach/internal/config"
 	"github.com/acidsoft/gorestteach/internal/domain"
+	"github.com/acidsoft/gorestteach/internal/imageurl"
 	"github.com/acidsoft/gorestteach/internal/repository"
 	"github.com/acidsoft/gorestteach/pkg/apperror"
+	"github.com/acidsoft/gorestteach/pkg/imaging"
+	"github.com/acidsoft/gorestteach/pkg/patch"
 	"github.com/google/uuid"
 )
 
@@ -16,32 +20,74 @@ import (
 
 type UpdateUserInput struct {
 	Name string `json:"name" validate:"omitempty,min=2,max=100"`
-	Bio  string `json:"bio"  validate:"omitempty,max=500"`
+	// Bio is left untouched when omitted; send "" to clear it.
+	Bio *string `json:"bio" validate:"omitempty,max=500"`
+}
+
+// PatchUserInput is a JSON Merge Patch document for the caller's profile.
+// Name cannot be null; a null bio clears it and a null avatar_id removes the avatar.
+type PatchUserInput struct {
+	Name     patch.Field[string]    `json:"name"      validate:"omitnil,required,min=2,max=100"`
+	Bio      patch.Field[string]    `json:"bio"       validate:"omitnil,max=500"`
+	AvatarID patch.Field[uuid.UUID] `json:"avatar_id"`
+}
+
+// ImageUpload is an uploaded image file, read as it is stored. ContentType
+// is sniffed from the first bytes; the size limit is enforced by the reader.
+type ImageUpload struct {
+	Body        io.Reader
+	ContentType string
 }
 
 // ─── Use Case ────────────────────────────────────────────────────────────────
 
 type UserUseCase struct {
-	userRepo  repository.UserRepository
-	imageRepo repository.ImageRepository
-	uploadCfg *config.UploadConfig
+	userRepo   repository.UserRepository
+	imageRepo  repository.ImageRepository
+	followRepo repository.FollowRepository
+	uploads    *UploadUseCase
+	storage    *StorageUseCase
+	urls       *imageurl.Signer
 }
 
 func NewUserUseCase(
 	userRepo repository.UserRepository,
 	imageRepo repository.ImageRepository,
-	uploadCfg *config.UploadConfig,
+	followRepo repository.FollowRepository,
+	uploads *UploadUseCase,
+	storage *StorageUseCase,
+	urls *imageurl.Signer,
 ) *UserUseCase {
-	return &UserUseCase{userRepo: userRepo, imageRepo: imageRepo, uploadCfg: uploadCfg}
+	return &UserUseCase{
+		userRepo:   userRepo,
+		imageRepo:  imageRepo,
+		followRepo: followRepo,
+		uploads:    uploads,
+		storage:    storage,
+		urls:       urls,
+	}
 }
 
-// GetProfile returns the full profile of any user by ID.
+// GetProfile returns the full profile of any user by ID, including follow counts.
 func (uc *UserUseCase) GetProfile(ctx context.Context, id uuid.UUID) (*domain.UserPublic, error) {
 	user, err := uc.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	return uc.toProfile(ctx, user)
+}
+
+// toProfile converts a user to its public form with the follow counts and
+// avatar URL filled in.
+func (uc *UserUseCase) toProfile(ctx context.Context, user *domain.User) (*domain.UserPublic, error) {
 	pub := user.ToPublic()
+	uc.urls.SignProfile(&pub)
+	followers, following, err := uc.followRepo.Counts(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	pub.FollowersCount = followers
+	pub.FollowingCount = following
 	return &pub, nil
 }
 
@@ -55,28 +101,67 @@ func (uc *UserUseCase) UpdateProfile(ctx context.Context, userID uuid.UUID, inpu
 	if input.Name != "" {
 		user.Name = input.Name
 	}
-	user.Bio = input.Bio
+	if input.Bio != nil {
+		user.Bio = *input.Bio
+	}
 
 	if err := uc.userRepo.Update(ctx, user); err != nil {
 		return nil, err
 	}
 
-	pub := user.ToPublic()
-	return &pub, nil
+	return uc.toProfile(ctx, user)
 }
 
-// UploadAvatar validates and stores avatar image data as a blob in the DB.
-func (uc *UserUseCase) UploadAvatar(ctx context.Context, userID uuid.UUID, data []byte, contentType string) (*domain.UserPublic, error) {
-	if err := validateImageUpload(data, contentType, uc.uploadCfg.MaxSizeMB); err != nil {
+// PatchProfile applies a merge patch to the authenticated user's profile.
+func (uc *UserUseCase) PatchProfile(ctx context.Context, userID uuid.UUID, input PatchUserInput) (*domain.UserPublic, error) {
+	var details []apperror.FieldError
+	if input.Name.Null {
+		details = append(details, nullNotAllowed("name"))
+	}
+	if input.AvatarID.HasValue() {
+		details = append(details, apperror.FieldError{
+			Field:   "avatar_id",
+			Message: "Can only be set to null; upload a new avatar via POST /users/me/avatar",
+		})
+	}
+	if len(details) > 0 {
+		return nil, apperror.ValidationError(details)
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
 		return nil, err
 	}
 
-	img := &domain.Image{
-		Data:        data,
-		ContentType: contentType,
-		Size:        int64(len(data)),
+	if input.Name.HasValue() {
+		user.Name = input.Name.Value
+	}
+	if input.Bio.Set {
+		user.Bio = input.Bio.Value // "" when null
+	}
+	if input.AvatarID.Null {
+		user.AvatarID = nil
 	}
-	if err := uc.imageRepo.Save(ctx, img); err != nil {
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return uc.toProfile(ctx, user)
+}
+
+// UploadAvatar validates, sanitizes and stores an avatar image, within the
+// user's storage quota.
+func (uc *UserUseCase) UploadAvatar(ctx context.Context, userID uuid.UUID, input ImageUpload) (*domain.UserPublic, error) {
+	img, finish, err := newUploadedImage(input)
+	if err != nil {
+		return nil, err
+	}
+	check, err := uc.storage.charge(ctx, userID, img)
+	if err != nil {
+		return nil, err
+	}
+	if err := check(finish(uc.imageRepo.Save(ctx, img))); err != nil {
 		return nil, err
 	}
 
@@ -87,6 +172,26 @@ func (uc *UserUseCase) UploadAvatar(ctx context.Context, userID uuid.UUID, data
 	return uc.GetProfile(ctx, userID)
 }
 
+// Storage returns how much of their storage quota the user has used.
+func (uc *UserUseCase) Storage(ctx context.Context, userID uuid.UUID) (*StorageUsage, error) {
+	return uc.storage.Usage(ctx, userID)
+}
+
+// AvatarFromUpload makes a complete resumable upload the avatar, with the
+// same checks as UploadAvatar.
+func (uc *UserUseCase) AvatarFromUpload(ctx context.Context, userID, uploadID uuid.UUID) (*domain.UserPublic, error) {
+	var profile *domain.UserPublic
+	err := uc.uploads.attach(ctx, userID, uploadID, func(input ImageUpload) error {
+		var err error
+		profile, err = uc.UploadAvatar(ctx, userID, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
 // ─── Shared validation ────────────────────────────────────────────────────────
 
 var allowedImageTypes = map[string]bool{
@@ -96,29 +201,83 @@ var allowedImageTypes = map[string]bool{
 	"image/gif":  true,
 }
 
-func validateImageUpload(data []byte, contentType string, maxMB int64) error {
-	_ = errors.New("") // keep import used
+// nullNotAllowed reports a merge-patch member that was null but cannot be removed.
+func nullNotAllowed(field string) apperror.FieldError {
+	return apperror.FieldError{Field: field, Message: "Cannot be null"}
+}
 
-	// Validate content type
-	if !allowedImageTypes[contentType] {
-		return apperror.UnsupportedMedia("Only JPEG, PNG, WebP and GIF images are allowed")
+// newUploadedImage checks the header of an upload and prepares it to be
+// streamed into storage. JPEG and PNG files are re-encoded upright and
+// without their metadata, which often includes the GPS position a photo was
+// taken at. Only the header is read here: the image is decoded when the
+// repository reads its Body, so a request uploading several images holds one
+// at a time in memory.
+//
+// The caller must pass the result of saving the image to the returned
+// finish, which stops the encoding if it did not complete and reports why
+// the upload could not be stored.
+func newUploadedImage(input ImageUpload) (img *domain.Image, finish func(saveErr error) error, err error) {
+	if !allowedImageTypes[input.ContentType] {
+		return nil, nil, apperror.UnsupportedMedia("Only JPEG, PNG, WebP and GIF images are allowed")
 	}
 
-	// Validate size
-	maxBytes := maxMB * 1024 * 1024
-	if int64(len(data)) > maxBytes {
-		return apperror.FileTooLarge(maxMB)
+	upload, err := imaging.Sanitize(input.Body, input.ContentType)
+	if err != nil {
+		return nil, nil, uploadError(err)
+	}
+	body := &sanitizedBody{upload: upload}
+	img = &domain.Image{
+		Body:        body,
+		ContentType: upload.ContentType,
+		Width:       upload.Width,
+		Height:      upload.Height,
 	}
+	return img, body.finish, nil
+}
 
-	// Additional sniff-check: validate actual bytes match claimed MIME
-	sniffed := http.DetectContentType(data)
-	if !allowedImageTypes[sniffed] {
-		return &apperror.AppError{
-			HTTPStatus: http.StatusUnsupportedMediaType,
-			Code:       apperror.ErrUnsupportedMedia,
-			Message:    "File content does not match an allowed image type",
-		}
+// uploadError maps a failure to decode an upload to the error reported to
+// the client.
+func uploadError(err error) error {
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return apperror.UnsupportedMedia(
+			fmt.Sprintf("Images may have at most %d megapixels", imaging.MaxPixels/1_000_000))
 	}
+	return apperror.NewWithCause(http.StatusUnsupportedMediaType, apperror.ErrUnsupportedMedia,
+		"File is not a valid image", err)
+}
 
-	return nil
+// sanitizedBody is the Body of an uploaded image: the output of its
+// imaging.Upload, through a pipe. The encoding only starts on the first Read.
+type sanitizedBody struct {
+	upload *imaging.Upload
+	pr     *io.PipeReader
+	done   chan struct{}
+	err    error // of WriteTo, once done is closed
+}
+
+func (b *sanitizedBody) Read(p []byte) (int, error) {
+	if b.pr == nil {
+		pr, pw := io.Pipe()
+		b.pr, b.done = pr, make(chan struct{})
+		go func() {
+			defer close(b.done)
+			_, b.err = b.upload.WriteTo(pw)
+			pw.CloseWithError(b.err)
+		}()
+	}
+	return b.pr.Read(p)
+}
+
+// finish returns saveErr, or the reason the image could not be encoded if
+// that is why it was not saved.
+func (b *sanitizedBody) finish(saveErr error) error {
+	if b.pr == nil {
+		return saveErr
+	}
+	b.pr.Close() // unblocks the encoder if the body was not read to the end
+	<-b.done
+	if b.err != nil && !errors.Is(b.err, io.ErrClosedPipe) {
+		return uploadError(b.err)
+	}
+	return saveErr
 }
@@ -3,6 +3,7 @@ package apperror
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // ErrorCode is a machine-readable string identifying the error type.
@@ -19,6 +20,11 @@ const (
 	ErrConflict         ErrorCode = "CONFLICT"
 	ErrFileTooLarge     ErrorCode = "FILE_TOO_LARGE"
 	ErrUnsupportedMedia ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrPrecondition     ErrorCode = "PRECONDITION_FAILED"
+	ErrAccountSuspended ErrorCode = "ACCOUNT_SUSPENDED"
+	ErrAccountBanned    ErrorCode = "ACCOUNT_BANNED"
+	ErrLinkExpired      ErrorCode = "LINK_EXPIRED"
+	ErrQuotaExceeded    ErrorCode = "QUOTA_EXCEEDED"
 
 	// 5xx
 	ErrInternal ErrorCode = "INTERNAL_ERROR"
@@ -94,10 +100,37 @@ func FileTooLarge(maxMB int64) *AppError {
 		fmt.Sprintf("File exceeds maximum allowed size of %dMB", maxMB))
 }
 
+// QuotaExceeded rejects an upload that does not fit in the user's storage
+// quota.
+func QuotaExceeded(quotaMB int64) *AppError {
+	return New(http.StatusRequestEntityTooLarge, ErrQuotaExceeded,
+		fmt.Sprintf("Upload exceeds your storage quota of %dMB; delete some images first", quotaMB))
+}
+
 func UnsupportedMedia(msg string) *AppError {
 	return New(http.StatusUnsupportedMediaType, ErrUnsupportedMedia, msg)
 }
 
+func PreconditionFailed(msg string) *AppError {
+	return New(http.StatusPreconditionFailed, ErrPrecondition, msg)
+}
+
+// AccountSuspended rejects a user who is suspended until the given time.
+func AccountSuspended(until time.Time) *AppError {
+	return New(http.StatusForbidden, ErrAccountSuspended,
+		"Account is suspended until "+until.UTC().Format(time.RFC3339))
+}
+
+func AccountBanned() *AppError {
+	return New(http.StatusForbidden, ErrAccountBanned, "Account has been banned")
+}
+
+// LinkExpired rejects a signed URL past its expiry; the API hands out a
+// fresh one with the resource.
+func LinkExpired() *AppError {
+	return New(http.StatusForbidden, ErrLinkExpired, "The link has expired; fetch a new one")
+}
+
 func Internal(cause error) *AppError {
 	return NewWithCause(http.StatusInternalServerError, ErrInternal,
 		"An unexpected error occurred. Please try again later.", cause)
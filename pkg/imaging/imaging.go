@@ -0,0 +1,363 @@
+// Package imaging resizes uploaded images and picks the format to send them
+// in. It only uses pure-Go codecs: JPEG, PNG, GIF and WebP can be read, JPEG
+// and PNG written.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Fit modes for Resize.
+const (
+	// FitContain scales the image to fit inside the box, keeping its aspect
+	// ratio; the result may be smaller than the box in one dimension.
+	FitContain = "contain"
+	// FitCover scales the image to fill the box and crops the overflow,
+	// keeping the centre.
+	FitCover = "cover"
+)
+
+// Content types Encode can produce.
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+)
+
+// MaxPixels bounds the size of images Decode accepts, so a small file that
+// declares huge dimensions cannot exhaust memory.
+const MaxPixels = 50_000_000
+
+// JPEG qualities: a good trade-off for thumbnails, and a high one for
+// re-encoded uploads, which are served as originals.
+const (
+	jpegQuality       = 85
+	jpegUploadQuality = 92
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported image format")
+	ErrTooLarge    = errors.New("image dimensions are too large")
+)
+
+// Decode reads an image in any supported format, turned upright according
+// to its EXIF orientation. Only the first frame of an animated GIF is
+// returned.
+func Decode(data []byte, contentType string) (image.Image, error) {
+	cfg, err := decodeConfig(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var decode func(r io.Reader) (image.Image, error)
+	switch contentType {
+	case TypeJPEG:
+		decode = jpeg.Decode
+	case TypePNG:
+		decode = png.Decode
+	case "image/gif":
+		decode = gif.Decode
+	case "image/webp":
+		decode = webp.Decode
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return orient(img, orientation(data, contentType)), nil
+}
+
+// decodeConfig reads the dimensions of an image in any supported format.
+func decodeConfig(data []byte, contentType string) (image.Config, error) {
+	return decodeConfigFrom(bytes.NewReader(data), contentType)
+}
+
+func decodeConfigFrom(r io.Reader, contentType string) (image.Config, error) {
+	var cfg image.Config
+	var err error
+	switch contentType {
+	case TypeJPEG:
+		cfg, err = jpeg.DecodeConfig(r)
+	case TypePNG:
+		cfg, err = png.DecodeConfig(r)
+	case "image/gif":
+		cfg, err = gif.DecodeConfig(r)
+	case "image/webp":
+		cfg, err = webp.DecodeConfig(r)
+	default:
+		return cfg, ErrUnsupported
+	}
+	if err == nil && (cfg.Width <= 0 || cfg.Height <= 0) {
+		err = ErrUnsupported
+	}
+	return cfg, err
+}
+
+// Upload is an uploaded image being sanitized on its way to storage: JPEG
+// and PNG files are re-encoded so that they keep none of the source's
+// metadata — EXIF with its GPS position, XMP, comments — apart from the
+// colour profile, with the EXIF orientation applied to the pixels. Other
+// formats are passed through unchanged.
+//
+// Only the header has been read when Sanitize returns; WriteTo reads,
+// decodes and re-encodes the rest, so one image at a time is held in memory
+// however many are uploaded together.
+type Upload struct {
+	ContentType string
+	// Width and Height are those of the image WriteTo writes.
+	Width, Height int
+
+	head        []byte    // the bytes read so far
+	rest        io.Reader // the bytes after head
+	orientation int
+}
+
+// Sanitize reads the header of an image of the given type from r and
+// returns the upload to write out. It fails with ErrTooLarge if the image
+// has more than MaxPixels.
+func Sanitize(r io.Reader, contentType string) (*Upload, error) {
+	var head bytes.Buffer
+	cfg, err := decodeConfigFrom(io.TeeReader(r, &head), contentType)
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	u := &Upload{
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		head:        head.Bytes(),
+		rest:        r,
+		orientation: orientNormal,
+	}
+	if contentType == TypePNG {
+		// DecodeConfig stops after IHDR, but eXIf and the colour chunks may
+		// come anywhere before the image data.
+		if err := readPNGHeader(r, &head); err != nil {
+			return nil, err
+		}
+		u.head = head.Bytes()
+	}
+	if contentType == TypeJPEG || contentType == TypePNG {
+		// The EXIF data of a JPEG comes before the pixel dimensions in
+		// practice.
+		u.orientation = orientation(u.head, contentType)
+		if u.orientation >= orientTranspose {
+			u.Width, u.Height = u.Height, u.Width
+		}
+	}
+	return u, nil
+}
+
+// WriteTo reads the rest of the upload and writes the sanitized image to w.
+func (u *Upload) WriteTo(w io.Writer) (int64, error) {
+	src := io.MultiReader(bytes.NewReader(u.head), u.rest)
+	cw := &countingWriter{w: w}
+	if u.ContentType != TypeJPEG && u.ContentType != TypePNG {
+		_, err := io.Copy(cw, src)
+		return cw.n, err
+	}
+
+	var img image.Image
+	var err error
+	if u.ContentType == TypeJPEG {
+		img, err = jpeg.Decode(src)
+	} else {
+		img, err = png.Decode(src)
+	}
+	if err != nil {
+		return 0, err
+	}
+	// Read past the end of the image too, so the whole upload is consumed
+	// (and the caller's limits apply to it) before anything is written.
+	if _, err := io.Copy(io.Discard, src); err != nil {
+		return 0, err
+	}
+	img = orient(img, u.orientation)
+
+	var out io.Writer = cw
+	if at, profile := colorProfile(u.head, u.ContentType); len(profile) > 0 {
+		out = &insertingWriter{w: cw, at: at, insert: profile}
+	}
+	if u.ContentType == TypeJPEG {
+		err = jpeg.Encode(out, img, &jpeg.Options{Quality: jpegUploadQuality})
+	} else {
+		err = png.Encode(out, img)
+	}
+	return cw.n, err
+}
+
+// Resize scales src into a box of w×h pixels according to fit. A zero w or h
+// leaves that dimension to follow the aspect ratio. Images are never scaled
+// up: a box larger than the source yields the source size (cropped to the
+// box's aspect ratio for FitCover).
+func Resize(src image.Image, w, h int, fit string) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if w <= 0 && h <= 0 {
+		return src
+	}
+	if w <= 0 {
+		w = scaled(sw, float64(h)/float64(sh))
+		fit = FitContain
+	}
+	if h <= 0 {
+		h = scaled(sh, float64(w)/float64(sw))
+		fit = FitContain
+	}
+
+	crop := b
+	var dw, dh int
+	if fit == FitCover {
+		// Largest centred region of the source with the box's aspect ratio.
+		cw, ch := sw, scaled(sw, float64(h)/float64(w))
+		if ch > sh {
+			cw, ch = scaled(sh, float64(w)/float64(h)), sh
+		}
+		x0 := b.Min.X + (sw-cw)/2
+		y0 := b.Min.Y + (sh-ch)/2
+		crop = image.Rect(x0, y0, x0+cw, y0+ch)
+		dw, dh = w, h
+		if cw < w {
+			dw, dh = cw, ch
+		}
+	} else {
+		scale := math.Min(1, math.Min(float64(w)/float64(sw), float64(h)/float64(sh)))
+		dw, dh = scaled(sw, scale), scaled(sh, scale)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, xdraw.Src, nil)
+	return dst
+}
+
+func scaled(n int, factor float64) int {
+	return max(1, int(math.Round(float64(n)*factor)))
+}
+
+// Encode writes img as contentType, which must be TypeJPEG or TypePNG.
+// Transparent areas are flattened onto white for JPEG.
+func Encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch contentType {
+	case TypeJPEG:
+		if !isOpaque(img) {
+			flat := image.NewRGBA(img.Bounds())
+			draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+			draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+			img = flat
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+	case TypePNG:
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+	return buf.Bytes(), nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// Negotiate picks the output type for an image stored as sourceType from an
+// HTTP Accept header. The source's own type is preferred when it can be
+// encoded; other sources prefer PNG, which keeps transparency. An empty
+// header accepts anything. If the client accepts neither output type, ok is
+// false and contentType is the preferred type regardless.
+func Negotiate(accept, sourceType string) (contentType string, ok bool) {
+	candidates := []string{TypePNG, TypeJPEG}
+	if sourceType == TypeJPEG {
+		candidates = []string{TypeJPEG, TypePNG}
+	}
+	if strings.TrimSpace(accept) == "" {
+		return candidates[0], true
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, candidate := range candidates {
+		if q := quality(ranges, candidate); q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+	if best == "" {
+		return candidates[0], false
+	}
+	return best, true
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, found := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !found {
+			continue
+		}
+		r := mediaRange{typ: strings.TrimSpace(typ), subtype: strings.TrimSpace(subtype), q: 1}
+		for _, p := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(strings.TrimSpace(name), "q") {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q >= 0 && q <= 1 {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality returns the q-value the most specific matching range gives to
+// contentType, or 0 if no range matches.
+func quality(ranges []mediaRange, contentType string) float64 {
+	typ, subtype, _ := strings.Cut(contentType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
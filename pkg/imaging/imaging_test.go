@@ -0,0 +1,193 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"testing"
+)
+
+// pngChunk encodes a PNG chunk with its length and CRC.
+func pngChunk(typ string, payload []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	out = append(out, typ...)
+	out = append(out, payload...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[4:]))
+}
+
+// exifOrientation is the TIFF structure of an eXIf chunk holding only an
+// orientation tag.
+func exifOrientation(o uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1) // one IFD entry
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, o)
+	tiff = append(tiff, 0, 0)
+	return binary.BigEndian.AppendUint32(tiff, 0) // no next IFD
+}
+
+// taggedPNG returns a 2×1 PNG, red then blue, with the given chunks after
+// IHDR.
+func taggedPNG(t *testing.T, chunks ...[]byte) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	img.Set(1, 0, color.NRGBA{B: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	ihdrEnd := len(pngSignature) + 12 + 13
+	out := append([]byte{}, buf.Bytes()[:ihdrEnd]...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return append(out, buf.Bytes()[ihdrEnd:]...)
+}
+
+func chunkTypes(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	types := map[string][]byte{}
+	pngChunks(data, func(typ string, payload, _ []byte) bool {
+		types[typ] = payload
+		return true
+	})
+	if _, ok := types["IEND"]; !ok {
+		t.Fatalf("output is not a complete PNG: %v", types)
+	}
+	return types
+}
+
+func TestSanitizePNGKeepsProfileAndAppliesOrientation(t *testing.T) {
+	icc := append([]byte("test profile\x00\x00"), 0x78, 0x9c, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01)
+	src := taggedPNG(t,
+		pngChunk("tEXt", []byte("Comment\x00taken at home")),
+		pngChunk("iCCP", icc),
+		pngChunk("eXIf", exifOrientation(orientRotate90)),
+	)
+
+	u, err := Sanitize(bytes.NewReader(src), TypePNG)
+	if err != nil {
+		t.Fatalf("Sanitize: %v", err)
+	}
+	if u.Width != 1 || u.Height != 2 {
+		t.Errorf("size = %d×%d, want 1×2", u.Width, u.Height)
+	}
+	var out bytes.Buffer
+	n, err := u.WriteTo(&out)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if n != int64(out.Len()) {
+		t.Errorf("WriteTo returned %d, wrote %d bytes", n, out.Len())
+	}
+
+	types := chunkTypes(t, out.Bytes())
+	if !bytes.Equal(types["iCCP"], icc) {
+		t.Errorf("iCCP = %q, want %q", types["iCCP"], icc)
+	}
+	for _, typ := range []string{"eXIf", "tEXt"} {
+		if _, ok := types[typ]; ok {
+			t.Errorf("%s chunk was kept", typ)
+		}
+	}
+
+	img, err := png.Decode(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 1 || b.Dy() != 2 {
+		t.Fatalf("decoded size = %v, want 1×2", b.Size())
+	}
+	if r, _, b, _ := img.At(0, 0).RGBA(); r == 0 || b != 0 {
+		t.Errorf("top pixel is not red: %v", img.At(0, 0))
+	}
+	if r, _, b, _ := img.At(0, 1).RGBA(); r != 0 || b == 0 {
+		t.Errorf("bottom pixel is not blue: %v", img.At(0, 1))
+	}
+}
+
+func TestSanitizePNGWithoutMetadata(t *testing.T) {
+	src := taggedPNG(t)
+	u, err := Sanitize(bytes.NewReader(src), TypePNG)
+	if err != nil {
+		t.Fatalf("Sanitize: %v", err)
+	}
+	if u.Width != 2 || u.Height != 1 {
+		t.Errorf("size = %d×%d, want 2×1", u.Width, u.Height)
+	}
+	var out bytes.Buffer
+	if _, err := u.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	types := chunkTypes(t, out.Bytes())
+	for typ := range pngColorChunks {
+		if _, ok := types[typ]; ok {
+			t.Errorf("%s chunk was added", typ)
+		}
+	}
+}
+
+func TestSanitizeTruncatedPNG(t *testing.T) {
+	src := taggedPNG(t, pngChunk("iCCP", bytes.Repeat([]byte{1}, 64)))
+	// Cut the file inside the iCCP chunk, after IHDR.
+	src = src[:len(pngSignature)+12+13+20]
+	if _, err := Sanitize(bytes.NewReader(src), TypePNG); err == nil {
+		t.Fatal("Sanitize accepted a truncated file")
+	}
+}
+
+// noisePhoto returns a w×h image of random pixels, which compresses about as
+// badly as a detailed photo.
+func noisePhoto(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewPCG(1, 2))
+	for y := range h {
+		for x := range w {
+			v := uint8(rng.IntN(256))
+			img.SetRGBA(x, y, color.RGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+	return img
+}
+
+// BenchmarkSanitize measures the re-encoding of a 12-megapixel photo, from
+// the upload read to the clean file written:
+//
+//	go test -run '^$' -bench Sanitize -benchmem ./pkg/imaging/
+func BenchmarkSanitize(b *testing.B) {
+	photo := noisePhoto(4000, 3000)
+	for _, contentType := range []string{TypeJPEG, TypePNG} {
+		var src bytes.Buffer
+		var err error
+		if contentType == TypeJPEG {
+			err = jpeg.Encode(&src, photo, &jpeg.Options{Quality: 92})
+		} else {
+			err = png.Encode(&src, photo)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(contentType, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(src.Len()))
+			for b.Loop() {
+				u, err := Sanitize(bytes.NewReader(src.Bytes()), contentType)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := u.WriteTo(io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+)
+
+// EXIF orientation values (tag 0x0112), describing how the stored pixels
+// must be transformed for display.
+const (
+	orientNormal     = 1
+	orientFlipH      = 2
+	orientRotate180  = 3
+	orientFlipV      = 4
+	orientTranspose  = 5
+	orientRotate90   = 6 // clockwise
+	orientTransverse = 7
+	orientRotate270  = 8 // clockwise
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngColorChunks are the PNG chunks kept when an upload is re-encoded: they
+// describe the colour space, not the picture's origin.
+var pngColorChunks = map[string]bool{"iCCP": true, "sRGB": true, "gAMA": true, "cHRM": true}
+
+// jpegSegments calls fn with the marker and payload of every segment before
+// the image data of a JPEG file, until fn returns false.
+func jpegSegments(data []byte, fn func(marker byte, payload []byte) bool) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // start of scan, end of image
+			return
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return
+		}
+		if !fn(marker, data[i+4:i+2+n]) {
+			return
+		}
+		i += 2 + n
+	}
+}
+
+// pngChunks calls fn with the type, payload and raw bytes (length, type,
+// payload and CRC) of every chunk of a PNG file, until fn returns false.
+func pngChunks(data []byte, fn func(typ string, payload, raw []byte) bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return
+	}
+	for i := len(pngSignature); i+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		if n < 0 || n > len(data)-i-12 {
+			return
+		}
+		typ := string(data[i+4 : i+8])
+		if !fn(typ, data[i+8:i+8+n], data[i:i+12+n]) {
+			return
+		}
+		i += 12 + n
+	}
+}
+
+// readPNGHeader reads the chunks of a PNG file from r into head, which holds
+// the file up to a chunk boundary, until it has read the type of the first
+// IDAT chunk: all the metadata that may precede the image data.
+func readPNGHeader(r io.Reader, head *bytes.Buffer) error {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return noEOF(err)
+		}
+		head.Write(hdr[:])
+		if typ := string(hdr[4:]); typ == "IDAT" || typ == "IEND" {
+			return nil
+		}
+		n := int64(binary.BigEndian.Uint32(hdr[:]))
+		if n > math.MaxInt32 {
+			return ErrUnsupported
+		}
+		if _, err := io.CopyN(head, r, n+4); err != nil { // payload and CRC
+			return noEOF(err)
+		}
+	}
+}
+
+// noEOF reports a file that ends early as the error it is.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// orientation returns the EXIF orientation of a JPEG or PNG file, or
+// orientNormal if it has none.
+func orientation(data []byte, contentType string) int {
+	var tiff []byte
+	switch contentType {
+	case TypeJPEG:
+		jpegSegments(data, func(marker byte, payload []byte) bool {
+			if marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				tiff = payload[6:]
+				return false
+			}
+			return true
+		})
+	case TypePNG:
+		pngChunks(data, func(typ string, payload, _ []byte) bool {
+			if typ == "eXIf" {
+				tiff = payload
+				return false
+			}
+			return typ != "IDAT"
+		})
+	}
+	if o := tiffOrientation(tiff); o >= orientNormal && o <= orientRotate270 {
+		return o
+	}
+	return orientNormal
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of a TIFF
+// structure, as embedded in EXIF data. It returns 0 if there is none.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd > len(tiff)-2 {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		const tagOrientation, typeShort = 0x0112, 3
+		if order.Uint16(tiff[entry:]) == tagOrientation && order.Uint16(tiff[entry+2:]) == typeShort {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// orient transforms img as an EXIF orientation prescribes, so that it
+// displays upright without the tag.
+func orient(img image.Image, o int) image.Image {
+	if o <= orientNormal || o > orientRotate270 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if o >= orientTranspose {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch o {
+			case orientFlipH:
+				sx, sy = w-1-x, y
+			case orientRotate180:
+				sx, sy = w-1-x, h-1-y
+			case orientFlipV:
+				sx, sy = x, h-1-y
+			case orientTranspose:
+				sx, sy = y, x
+			case orientRotate90:
+				sx, sy = y, h-1-x
+			case orientTransverse:
+				sx, sy = w-1-y, h-1-x
+			case orientRotate270:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// colorProfile returns the colour-space metadata found in the header of a
+// source file, and the offset in an encoding of the same format at which to
+// insert it: the ICC profile of a JPEG, right after the SOI marker, and the
+// colour chunks of a PNG, right after IHDR. Without it, photos taken in a
+// wide-gamut space would render with washed-out colours.
+func colorProfile(head []byte, contentType string) (at int, profile []byte) {
+	switch contentType {
+	case TypeJPEG:
+		jpegSegments(head, func(marker byte, payload []byte) bool {
+			if marker == 0xE2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")) {
+				seg := []byte{0xFF, 0xE2, 0, 0}
+				binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+				profile = append(append(profile, seg...), payload...)
+			}
+			return true
+		})
+		return 2, profile
+	case TypePNG:
+		pngChunks(head, func(typ string, _, raw []byte) bool {
+			if pngColorChunks[typ] {
+				profile = append(profile, raw...)
+			}
+			return typ != "IDAT"
+		})
+		// IHDR is always 13 bytes; colour chunks must precede PLTE and IDAT.
+		return len(pngSignature) + 12 + 13, profile
+	}
+	return 0, nil
+}
+
+// insertingWriter writes insert into the stream after the first at bytes.
+type insertingWriter struct {
+	w       io.Writer
+	at      int
+	insert  []byte
+	written int
+}
+
+func (iw *insertingWriter) Write(p []byte) (int, error) {
+	if iw.insert == nil || iw.written+len(p) < iw.at {
+		iw.written += len(p)
+		return iw.w.Write(p)
+	}
+	split := iw.at - iw.written
+	if _, err := iw.w.Write(p[:split]); err != nil {
+		return 0, err
+	}
+	if _, err := iw.w.Write(iw.insert); err != nil {
+		return split, err
+	}
+	iw.insert = nil
+	n, err := iw.w.Write(p[split:])
+	iw.written += split + n
+	return split + n, err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,442 @@
+package markdown
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// renderBlocks renders a sequence of block-level lines. In tight mode (items
+// of a tight list) paragraphs are not wrapped in <p>.
+func renderBlocks(b *strings.Builder, lines []string, tight bool, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			i++
+			continue
+		}
+
+		indent := indentOf(line)
+		if indent >= 4 {
+			i = renderIndentedCode(b, lines, i)
+			continue
+		}
+
+		rest := line[indent:]
+		if _, _, _, ok := parseFence(rest); ok {
+			i = renderFencedCode(b, lines, i)
+			continue
+		}
+		if level, text := parseATXHeading(rest); level > 0 {
+			writeHeading(b, level, text, depth)
+			i++
+			continue
+		}
+		if isThematicBreak(rest) {
+			b.WriteString("<hr>\n")
+			i++
+			continue
+		}
+		if depth < maxNesting {
+			if rest[0] == '>' {
+				i = renderBlockquote(b, lines, i, depth)
+				continue
+			}
+			if _, _, ok := parseListMarker(line); ok {
+				i = renderList(b, lines, i, depth)
+				continue
+			}
+		}
+		i = renderParagraph(b, lines, i, tight, depth)
+	}
+}
+
+// startsBlock reports whether line begins a block that interrupts a paragraph.
+func startsBlock(line string) bool {
+	indent := indentOf(line)
+	if indent >= 4 || indent == len(line) {
+		return false
+	}
+	rest := line[indent:]
+
+	if _, _, _, ok := parseFence(rest); ok {
+		return true
+	}
+	if level, _ := parseATXHeading(rest); level > 0 {
+		return true
+	}
+	if isThematicBreak(rest) || rest[0] == '>' {
+		return true
+	}
+	// Only a non-empty bullet or a list starting at 1 interrupts a paragraph,
+	// so "2019. A good year" stays text.
+	if m, content, ok := parseListMarker(line); ok && !isBlank(content) {
+		return !m.ordered || m.start == 1
+	}
+	return false
+}
+
+// ─── Headings, breaks, paragraphs ───────────────────────────────────────────
+
+func parseATXHeading(rest string) (level int, text string) {
+	n := 0
+	for n < len(rest) && n < 7 && rest[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (n < len(rest) && rest[n] != ' ') {
+		return 0, ""
+	}
+
+	text = strings.TrimSpace(rest[n:])
+	// Optional closing sequence: trailing #s preceded by a space.
+	if trimmed := strings.TrimRight(text, "#"); trimmed == "" {
+		text = ""
+	} else if strings.HasSuffix(trimmed, " ") {
+		text = strings.TrimSpace(trimmed)
+	}
+	return n, text
+}
+
+func writeHeading(b *strings.Builder, level int, text string, depth int) {
+	tag := "h" + strconv.Itoa(level)
+	b.WriteString("<" + tag + ">")
+	renderInline(b, text, false, depth)
+	b.WriteString("</" + tag + ">\n")
+}
+
+func isThematicBreak(rest string) bool {
+	var marker byte
+	count := 0
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; c {
+		case ' ':
+		case '-', '*', '_':
+			if marker == 0 {
+				marker = c
+			} else if c != marker {
+				return false
+			}
+			count++
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// setextLevel returns 1 for a "===" underline, 2 for "---", 0 otherwise.
+func setextLevel(line string) int {
+	indent := indentOf(line)
+	if indent >= 4 {
+		return 0
+	}
+	rest := strings.TrimRight(line[indent:], " ")
+	if rest == "" {
+		return 0
+	}
+	switch c := rest[0]; {
+	case c == '=' && strings.Trim(rest, "=") == "":
+		return 1
+	case c == '-' && strings.Trim(rest, "-") == "":
+		return 2
+	}
+	return 0
+}
+
+func renderParagraph(b *strings.Builder, lines []string, i int, tight bool, depth int) int {
+	var para []string
+	for i < len(lines) {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if len(para) > 0 {
+			if level := setextLevel(line); level > 0 {
+				writeHeading(b, level, strings.Join(para, "\n"), depth)
+				return i + 1
+			}
+			if startsBlock(line) {
+				break
+			}
+		}
+		para = append(para, strings.TrimLeft(line, " "))
+		i++
+	}
+
+	text := strings.TrimRight(strings.Join(para, "\n"), " ")
+	if tight {
+		renderInline(b, text, false, depth)
+		b.WriteString("\n")
+		return i
+	}
+	b.WriteString("<p>")
+	renderInline(b, text, false, depth)
+	b.WriteString("</p>\n")
+	return i
+}
+
+// ─── Code blocks ────────────────────────────────────────────────────────────
+
+// parseFence recognizes an opening code fence: three or more backticks or
+// tildes, optionally followed by an info string.
+func parseFence(rest string) (marker byte, length int, info string, ok bool) {
+	if len(rest) < 3 || (rest[0] != '`' && rest[0] != '~') {
+		return 0, 0, "", false
+	}
+	marker = rest[0]
+	for length < len(rest) && rest[length] == marker {
+		length++
+	}
+	if length < 3 {
+		return 0, 0, "", false
+	}
+	info = strings.TrimSpace(rest[length:])
+	if marker == '`' && strings.Contains(info, "`") {
+		return 0, 0, "", false
+	}
+	return marker, length, info, true
+}
+
+func renderFencedCode(b *strings.Builder, lines []string, i int) int {
+	indent := indentOf(lines[i])
+	marker, length, info, _ := parseFence(lines[i][indent:])
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if n := indentOf(line); n < 4 {
+			rest := strings.TrimRight(line[n:], " ")
+			if len(rest) >= length && strings.Trim(rest, string(marker)) == "" {
+				i++
+				break
+			}
+		}
+		// Drop up to the opening fence's indentation from content lines.
+		strip := min(indent, indentOf(line))
+		code = append(code, line[strip:])
+	}
+
+	writeCode(b, code, codeLanguage(info))
+	return i
+}
+
+func renderIndentedCode(b *strings.Builder, lines []string, i int) int {
+	var code []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			code = append(code, "")
+		} else if indentOf(line) >= 4 {
+			code = append(code, line[4:])
+		} else {
+			break
+		}
+	}
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+	writeCode(b, code, "")
+	return i
+}
+
+func writeCode(b *strings.Builder, code []string, lang string) {
+	b.WriteString("<pre><code")
+	if lang != "" {
+		b.WriteString(` class="language-` + lang + `"`)
+	}
+	b.WriteString(">")
+	for _, line := range code {
+		b.WriteString(html.EscapeString(line))
+		b.WriteString("\n")
+	}
+	b.WriteString("</code></pre>\n")
+}
+
+// codeLanguage extracts the language from a fence info string. Anything but a
+// short identifier is dropped rather than escaped — it ends up in a class.
+func codeLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 || len(fields[0]) > 32 {
+		return ""
+	}
+	lang := fields[0]
+	for i := 0; i < len(lang); i++ {
+		c := lang[i]
+		if !isAlnum(c) && !strings.ContainsRune("_+#.-", rune(c)) {
+			return ""
+		}
+	}
+	return lang
+}
+
+// ─── Block quotes and lists ─────────────────────────────────────────────────
+
+func renderBlockquote(b *strings.Builder, lines []string, i int, depth int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		indent := indentOf(line)
+		if indent < 4 && indent < len(line) && line[indent] == '>' {
+			rest := line[indent+1:]
+			rest = strings.TrimPrefix(rest, " ")
+			inner = append(inner, rest)
+			continue
+		}
+		// Lazy continuation of a quoted paragraph.
+		if len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !isBlank(line) && !startsBlock(line) {
+			inner = append(inner, line)
+			continue
+		}
+		break
+	}
+
+	b.WriteString("<blockquote>\n")
+	renderBlocks(b, inner, false, depth+1)
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+type listMarker struct {
+	ordered bool
+	char    byte // '-', '*', '+' for bullets; '.' or ')' for ordered lists
+	start   int
+	width   int // column where the item's content starts
+}
+
+// parseListMarker recognizes a list item line and returns its marker and the
+// first line of its content.
+func parseListMarker(line string) (listMarker, string, bool) {
+	indent := indentOf(line)
+	if indent >= 4 || indent == len(line) {
+		return listMarker{}, "", false
+	}
+	rest := line[indent:]
+
+	var m listMarker
+	markerLen := 0
+	switch c := rest[0]; {
+	case c == '-' || c == '*' || c == '+':
+		m.char = c
+		markerLen = 1
+	case c >= '0' && c <= '9':
+		n := 0
+		for n < len(rest) && n < 10 && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		if n > 9 || n == len(rest) || (rest[n] != '.' && rest[n] != ')') {
+			return listMarker{}, "", false
+		}
+		m.ordered = true
+		m.char = rest[n]
+		m.start, _ = strconv.Atoi(rest[:n])
+		markerLen = n + 1
+	default:
+		return listMarker{}, "", false
+	}
+
+	after := rest[markerLen:]
+	if after != "" && after[0] != ' ' {
+		return listMarker{}, "", false
+	}
+	spaces := indentOf(after)
+	if spaces == 0 || spaces > 4 || spaces == len(after) {
+		// Empty item, or content that is itself an indented code block.
+		spaces = 1
+	}
+	m.width = indent + markerLen + spaces
+	if m.width > len(line) {
+		return m, "", true
+	}
+	return m, line[m.width:], true
+}
+
+func renderList(b *strings.Builder, lines []string, i int, depth int) int {
+	first, _, _ := parseListMarker(lines[i])
+
+	var items [][]string
+	loose := false
+	for i < len(lines) {
+		m, content, ok := parseListMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.char != first.char {
+			break
+		}
+
+		item := []string{content}
+		sawBlank := false
+	collect:
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			switch {
+			case isBlank(line):
+				item = append(item, "")
+				sawBlank = true
+			case indentOf(line) >= m.width:
+				item = append(item, line[m.width:])
+				if sawBlank {
+					loose = true
+					sawBlank = false
+				}
+			case sawBlank || startsBlock(line) || isListItem(line):
+				break collect
+			default:
+				item = append(item, strings.TrimLeft(line, " ")) // lazy continuation
+			}
+		}
+
+		// Trailing blank lines separate items; they are not part of this one.
+		trailing := 0
+		for len(item) > 1 && item[len(item)-1] == "" {
+			item = item[:len(item)-1]
+			trailing++
+		}
+		items = append(items, item)
+
+		if trailing > 0 {
+			next, _, ok := parseListMarker(lineAt(lines, i))
+			if !ok || next.ordered != first.ordered || next.char != first.char {
+				break
+			}
+			loose = true
+		}
+	}
+
+	if first.ordered {
+		if first.start != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(first.start) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	for _, item := range items {
+		var inner strings.Builder
+		renderBlocks(&inner, item, !loose, depth+1)
+		content := inner.String()
+		if loose {
+			b.WriteString("<li>\n" + content + "</li>\n")
+		} else {
+			b.WriteString("<li>" + strings.TrimSuffix(content, "\n") + "</li>\n")
+		}
+	}
+
+	if first.ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+func isListItem(line string) bool {
+	_, _, ok := parseListMarker(line)
+	return ok
+}
+
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
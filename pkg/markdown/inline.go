@@ -0,0 +1,507 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// maxLabelLen bounds how far a "[" looks for its "]" (and a link for its
+// title), keeping unmatched brackets from making rendering quadratic.
+const maxLabelLen = 1000
+
+// inlineParser renders one run of inline content.
+type inlineParser struct {
+	s      string
+	inLink bool // links cannot nest
+	depth  int
+
+	// noCloser[d] is the smallest position from which a search for the
+	// delimiter d is known to fail. Whether a position closes d does not
+	// depend on where the search started, so later searches can stop early.
+	noCloser map[string]int
+}
+
+func renderInline(b *strings.Builder, s string, inLink bool, depth int) {
+	if depth >= maxNesting {
+		writeText(b, s)
+		return
+	}
+	p := &inlineParser{s: s, inLink: inLink, depth: depth, noCloser: map[string]int{}}
+	p.render(b)
+}
+
+func (p *inlineParser) render(b *strings.Builder) {
+	s := p.s
+	text := 0 // start of pending literal text
+	for i := 0; i < len(s); {
+		var out string
+		next, ok := -1, false
+
+		switch c := s[i]; c {
+		case '\\':
+			out, next, ok = p.escape(i)
+		case '`':
+			out, next, ok = p.codeSpan(i)
+		case '!':
+			if i+1 < len(s) && s[i+1] == '[' {
+				out, next, ok = p.link(i+1, true)
+			}
+		case '[':
+			if !p.inLink {
+				out, next, ok = p.link(i, false)
+			}
+		case '<':
+			if !p.inLink {
+				out, next, ok = p.autolink(i)
+			}
+		case '*', '_', '~':
+			out, next, ok = p.emphasis(i)
+		case '\n':
+			// Two or more trailing spaces make a hard line break; otherwise
+			// trailing spaces are dropped.
+			end := i
+			for end > text && s[end-1] == ' ' {
+				end--
+			}
+			writeText(b, s[text:end])
+			if i-end >= 2 {
+				b.WriteString("<br>\n")
+			} else {
+				b.WriteString("\n")
+			}
+			i++
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			text = i
+			continue
+		case 'h', 'H':
+			if !p.inLink && (i == 0 || isURLBoundary(s[i-1])) {
+				out, next, ok = p.bareURL(i)
+			}
+		}
+
+		if !ok {
+			i++
+			continue
+		}
+		writeText(b, s[text:i])
+		b.WriteString(out)
+		i, text = next, next
+	}
+	writeText(b, s[text:])
+}
+
+// ─── Escapes and code spans ─────────────────────────────────────────────────
+
+func (p *inlineParser) escape(i int) (string, int, bool) {
+	s := p.s
+	if i+1 >= len(s) {
+		return "", 0, false
+	}
+	switch c := s[i+1]; {
+	case c == '\n':
+		return "<br>\n", i + 2, true
+	case isASCIIPunct(c):
+		return html.EscapeString(string(c)), i + 2, true
+	}
+	return "", 0, false
+}
+
+// codeSpan renders `code`. An unmatched backtick run is emitted literally as
+// a whole, so it cannot pair up with a later run of a different length.
+func (p *inlineParser) codeSpan(i int) (string, int, bool) {
+	n := runLength(p.s, i)
+	open := p.s[i : i+n]
+
+	end := p.findCodeClose(i+n, n)
+	if end < 0 {
+		return html.EscapeString(open), i + n, true
+	}
+
+	code := strings.ReplaceAll(p.s[i+n:end], "\n", " ")
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		code = code[1 : len(code)-1]
+	}
+	return "<code>" + html.EscapeString(code) + "</code>", end + n, true
+}
+
+// findCodeClose returns the start of the next backtick run of exactly n.
+func (p *inlineParser) findCodeClose(from, n int) int {
+	key := strings.Repeat("`", n)
+	if stop, ok := p.noCloser[key]; ok && from >= stop {
+		return -1
+	}
+	s := p.s
+	for j := from; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j)
+		if m == n {
+			return j
+		}
+		j += m
+	}
+	p.markNoCloser(key, from)
+	return -1
+}
+
+// ─── Emphasis ───────────────────────────────────────────────────────────────
+
+// emphasis renders *em*, **strong**, ***both*** (or with _) and ~~del~~.
+// Closers must be a run of exactly the opener's length; a run that does not
+// open anything is emitted literally as a whole.
+func (p *inlineParser) emphasis(i int) (string, int, bool) {
+	s := p.s
+	c := s[i]
+	n := runLength(s, i)
+	literal := html.EscapeString(s[i : i+n])
+
+	canOpen := i+n < len(s) && !isSpace(s[i+n])
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		canOpen = false // no intraword emphasis with underscores
+	}
+	if !canOpen || (c == '~' && n != 2) || n > 3 {
+		return literal, i + n, true
+	}
+
+	delim := s[i : i+n]
+	end := p.findEmphasisClose(i+n, delim)
+	if end < 0 {
+		return literal, i + n, true
+	}
+
+	var inner strings.Builder
+	renderInline(&inner, s[i+n:end], p.inLink, p.depth+1)
+
+	var open, close string
+	switch {
+	case c == '~':
+		open, close = "<del>", "</del>"
+	case n == 1:
+		open, close = "<em>", "</em>"
+	case n == 2:
+		open, close = "<strong>", "</strong>"
+	default:
+		open, close = "<em><strong>", "</strong></em>"
+	}
+	return open + inner.String() + close, end + n, true
+}
+
+// findEmphasisClose returns the start of the run closing delim, skipping
+// escapes and code spans.
+func (p *inlineParser) findEmphasisClose(from int, delim string) int {
+	if stop, ok := p.noCloser[delim]; ok && from >= stop {
+		return -1
+	}
+	s := p.s
+	c := delim[0]
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			n := runLength(s, j)
+			if end := p.findCodeClose(j+n, n); end >= 0 {
+				j = end + n
+			} else {
+				j += n
+			}
+			continue
+		}
+		if s[j] != c {
+			j++
+			continue
+		}
+
+		m := runLength(s, j)
+		closes := m == len(delim) && j > from && !isSpace(s[j-1])
+		if c == '_' && j+m < len(s) && isAlnum(s[j+m]) {
+			closes = false
+		}
+		if closes {
+			return j
+		}
+		j += m
+	}
+	p.markNoCloser(delim, from)
+	return -1
+}
+
+func (p *inlineParser) markNoCloser(key string, from int) {
+	if stop, ok := p.noCloser[key]; !ok || from < stop {
+		p.noCloser[key] = from
+	}
+}
+
+// ─── Links and images ───────────────────────────────────────────────────────
+
+// link renders [label](dest "title") starting at the "[" at i, or an image
+// when image is set. A link whose URL fails the allowlist keeps its text.
+func (p *inlineParser) link(i int, image bool) (string, int, bool) {
+	s := p.s
+	end := p.matchBracket(i)
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", 0, false
+	}
+	dest, title, next, ok := parseLinkTail(s, end+2)
+	if !ok {
+		return "", 0, false
+	}
+	label := s[i+1 : end]
+
+	if image {
+		alt := html.EscapeString(unescapePunct(label))
+		src, ok := safeURL(dest, true)
+		if !ok {
+			return alt, next, true
+		}
+		return `<img src="` + src + `" alt="` + alt + `"` + titleAttr(title) + `>`, next, true
+	}
+
+	var text strings.Builder
+	renderInline(&text, label, true, p.depth+1)
+	href, ok := safeURL(dest, false)
+	if !ok {
+		return text.String(), next, true
+	}
+	return anchor(href, title, text.String()), next, true
+}
+
+// matchBracket returns the index of the "]" matching the "[" at i.
+func (p *inlineParser) matchBracket(i int) int {
+	s := p.s
+	depth := 0
+	limit := min(len(s), i+maxLabelLen)
+	for j := i; j < limit; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			n := runLength(s, j)
+			if end := p.findCodeClose(j+n, n); end >= 0 {
+				j = end + n - 1
+			} else {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// parseLinkTail parses `dest "title")` following "](" at i.
+func parseLinkTail(s string, i int) (dest, title string, next int, ok bool) {
+	i = skipSpaces(s, i)
+
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexAny(s[i+1:], ">\n")
+		if end < 0 || s[i+1+end] != '>' {
+			return "", "", 0, false
+		}
+		dest = s[i+1 : i+1+end]
+		i += end + 2
+	} else {
+		start, parens := i, 0
+	scan:
+		for ; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '(':
+				parens++
+				if parens > 32 {
+					return "", "", 0, false
+				}
+			case ')':
+				if parens == 0 {
+					break scan
+				}
+				parens--
+			case ' ', '\n':
+				break scan
+			}
+		}
+		if i > len(s) {
+			i = len(s)
+		}
+		dest = s[start:i]
+	}
+
+	i = skipSpaces(s, i)
+	if i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		closer := s[i]
+		if closer == '(' {
+			closer = ')'
+		}
+		limit := min(len(s), i+1+maxLabelLen)
+		end := strings.IndexByte(s[i+1:limit], closer)
+		if end < 0 {
+			return "", "", 0, false
+		}
+		title = unescapePunct(s[i+1 : i+1+end])
+		i = skipSpaces(s, i+end+2)
+	}
+
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return unescapePunct(dest), title, i + 1, true
+}
+
+var emailPattern = regexp.MustCompile(`^[A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+
+// autolink renders <https://example.com> and <user@example.com>.
+func (p *inlineParser) autolink(i int) (string, int, bool) {
+	s := p.s
+	limit := min(len(s), i+2048)
+	end := strings.IndexAny(s[i+1:limit], "<> \n")
+	if end < 0 || s[i+1+end] != '>' {
+		return "", 0, false
+	}
+	target := s[i+1 : i+1+end]
+	next := i + end + 2
+
+	if emailPattern.MatchString(target) {
+		href, _ := safeURL("mailto:"+target, false)
+		return anchor(href, "", html.EscapeString(target)), next, true
+	}
+	if !strings.Contains(target, ":") {
+		return "", 0, false
+	}
+	href, ok := safeURL(target, false)
+	if !ok {
+		return "", 0, false
+	}
+	return anchor(href, "", html.EscapeString(target)), next, true
+}
+
+// bareURL links a plain http(s) URL in running text (GFM autolink extension).
+func (p *inlineParser) bareURL(i int) (string, int, bool) {
+	s := p.s
+	rest := strings.ToLower(s[i:min(len(s), i+8)])
+	if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+		return "", 0, false
+	}
+
+	end := i
+	for end < len(s) && !isSpace(s[end]) && s[end] != '<' {
+		end++
+	}
+	// Trailing punctuation belongs to the sentence, and a closing paren only
+	// to the URL when it balances an opening one.
+	for end > i {
+		c := s[end-1]
+		if strings.IndexByte(`?!.,:;*_~'"`, c) >= 0 {
+			end--
+			continue
+		}
+		if c == ')' && strings.Count(s[i:end], "(") < strings.Count(s[i:end], ")") {
+			end--
+			continue
+		}
+		break
+	}
+
+	target := s[i:end]
+	if len(target) <= len("https://") {
+		return "", 0, false
+	}
+	href, ok := safeURL(target, false)
+	if !ok {
+		return "", 0, false
+	}
+	return anchor(href, "", html.EscapeString(target)), end, true
+}
+
+// anchor builds a link; href must already be sanitized and escaped.
+func anchor(href, title, text string) string {
+	return `<a href="` + href + `"` + titleAttr(title) + ` rel="nofollow noopener noreferrer">` + text + `</a>`
+}
+
+func titleAttr(title string) string {
+	if title == "" {
+		return ""
+	}
+	return ` title="` + html.EscapeString(title) + `"`
+}
+
+// ─── Text ───────────────────────────────────────────────────────────────────
+
+var entityPattern = regexp.MustCompile(`^&(?:[A-Za-z][A-Za-z0-9]{1,31}|#[0-9]{1,7}|#[xX][0-9A-Fa-f]{1,6});`)
+
+// writeText escapes literal text. Well-formed character references are kept
+// as they are — in text content they can only ever produce a character.
+func writeText(b *strings.Builder, t string) {
+	for {
+		amp := strings.IndexByte(t, '&')
+		if amp < 0 {
+			b.WriteString(html.EscapeString(t))
+			return
+		}
+		b.WriteString(html.EscapeString(t[:amp]))
+		if ref := entityPattern.FindString(t[amp:]); ref != "" {
+			b.WriteString(ref)
+			t = t[amp+len(ref):]
+		} else {
+			b.WriteString("&amp;")
+			t = t[amp+1:]
+		}
+	}
+}
+
+// unescapePunct resolves backslash escapes of ASCII punctuation.
+func unescapePunct(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isASCIIPunct(c byte) bool {
+	return c >= '!' && c <= '/' || c >= ':' && c <= '@' || c >= '[' && c <= '`' || c >= '{' && c <= '~'
+}
+
+func isURLBoundary(c byte) bool {
+	return isSpace(c) || c == '(' || c == '*' || c == '_' || c == '~'
+}
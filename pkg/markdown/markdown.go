@@ -0,0 +1,112 @@
+// Package markdown renders post bodies to HTML that is safe to embed as-is.
+//
+// It implements the commonly used subset of CommonMark — headings, paragraphs,
+// emphasis, code, block quotes, lists, links, images and thematic breaks —
+// plus GFM strikethrough and bare-URL autolinks.
+//
+// Safety comes from construction rather than from filtering: raw HTML in the
+// source is always escaped, the renderer only ever emits the tags listed in
+// this package, every attribute value is escaped, and link and image URLs
+// must pass a scheme allowlist. Links carry rel="nofollow".
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// maxNesting bounds block quote / list and inline nesting. Deeper markers are
+// rendered as text, which keeps pathological input from blowing up the
+// recursion.
+const maxNesting = 16
+
+// ToHTML renders Markdown source to sanitized HTML.
+func ToHTML(src string) string {
+	var b strings.Builder
+	renderBlocks(&b, splitLines(src), false, 0)
+	return b.String()
+}
+
+// PlainToHTML renders plain text to sanitized HTML: blank lines separate
+// paragraphs and single line breaks are kept.
+func PlainToHTML(src string) string {
+	var b strings.Builder
+	var para []string
+
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		for i, line := range para {
+			if i > 0 {
+				b.WriteString("<br>\n")
+			}
+			b.WriteString(html.EscapeString(line))
+		}
+		b.WriteString("</p>\n")
+		para = para[:0]
+	}
+
+	for _, line := range splitLines(src) {
+		if isBlank(line) {
+			flush()
+			continue
+		}
+		para = append(para, line)
+	}
+	flush()
+
+	return b.String()
+}
+
+// splitLines normalizes line endings, replaces NUL and expands leading tabs.
+func splitLines(src string) []string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = strings.ReplaceAll(src, "\x00", "\uFFFD")
+
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+	return lines
+}
+
+// expandTabs turns tabs in the leading whitespace into spaces (tab stop 4), so
+// indentation can be measured in spaces.
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\t':
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case ' ':
+			b.WriteByte(' ')
+			col++
+		default:
+			b.WriteString(line[i:])
+			return b.String()
+		}
+	}
+	return b.String()
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,173 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// allowedTags are the tags the renderer emits, with the attributes each may
+// carry.
+var allowedTags = map[string][]string{
+	"a": {"href", "title", "rel"}, "img": {"src", "alt", "title"},
+	"p": nil, "br": nil, "hr": nil, "blockquote": nil, "pre": nil, "code": {"class"},
+	"em": nil, "strong": nil, "del": nil, "ul": nil, "ol": {"start"}, "li": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+}
+
+var (
+	tagPattern  = regexp.MustCompile(`^<(/?)([a-z0-9]+)((?: [a-z]+="[^"<>]*")*)>`)
+	attrPattern = regexp.MustCompile(` ([a-z]+)="([^"]*)"`)
+)
+
+// assertSafe checks that out contains only allowlisted tags and attributes,
+// and that every URL in it has an allowed scheme once unescaped.
+func assertSafe(t *testing.T, out string) {
+	t.Helper()
+	for i := strings.IndexByte(out, '<'); i >= 0; i = strings.IndexByte(out, '<') {
+		m := tagPattern.FindStringSubmatch(out[i:])
+		if m == nil {
+			t.Fatalf("unexpected markup at %q", out[i:])
+		}
+		attrs, ok := allowedTags[m[2]]
+		if !ok {
+			t.Fatalf("unexpected tag <%s> in %q", m[2], out)
+		}
+		for _, a := range attrPattern.FindAllStringSubmatch(m[3], -1) {
+			if !contains(attrs, a[1]) {
+				t.Fatalf("unexpected attribute %s on <%s> in %q", a[1], m[2], out)
+			}
+			if a[1] == "href" || a[1] == "src" {
+				assertSafeURL(t, html.UnescapeString(a[2]))
+			}
+		}
+		out = out[i+len(m[0]):]
+	}
+}
+
+func assertSafeURL(t *testing.T, u string) {
+	t.Helper()
+	for i := 0; i < len(u); i++ {
+		if c := u[i]; c <= ' ' || c == 0x7f {
+			t.Fatalf("URL %q contains a control character", u)
+		}
+	}
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 {
+		return
+	}
+	if cut := strings.IndexAny(u, "/?#"); cut >= 0 && cut < colon {
+		return
+	}
+	if scheme := strings.ToLower(u[:colon]); !allowedSchemes[scheme] {
+		t.Fatalf("URL %q has scheme %q", u, scheme)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestToHTMLEscapesUnsafeInput(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		// Dangerous schemes.
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mixed-case scheme", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+		{"vbscript link", "[x](vbscript:msgbox(1))", "<p>x</p>\n"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>\n"},
+		{"data image", "![x](data:image/svg+xml;base64,PHN2Zz4=)", "<p>x</p>\n"},
+		{"javascript image", "![x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mailto image", "![x](mailto:a@example.com)", "<p>x</p>\n"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+
+		// Scheme obfuscation. Entities are not decoded in destinations, so
+		// they stay literal: "&#106;avascript" is a relative URL.
+		{"entity-encoded letter", "[x](&#106;avascript:alert(1))",
+			`<p><a href="&amp;#106;avascript:alert(1)" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"entity-encoded colon", "[x](javascript&#58;alert(1))",
+			`<p><a href="javascript&amp;#58;alert(1)" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"entity-encoded tab", "[x](java&#x09;script:alert(1))",
+			`<p><a href="java&amp;#x09;script:alert(1)" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"tab in scheme", "[x](<java\tscript:alert(1)>)", "<p>x</p>\n"},
+		{"control character in scheme", "[x](java\x01script:alert(1))", "<p>x</p>\n"},
+		{"leading space", "[x]( javascript:alert(1))", "<p>x</p>\n"},
+		{"NUL in scheme", "[x](java\x00script:alert(1))", "<p>x</p>\n"},
+
+		// Raw HTML.
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"img onerror", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"raw anchor", `<a href="javascript:alert(1)">x</a>`,
+			"<p>&lt;a href=&#34;javascript:alert(1)&#34;&gt;x&lt;/a&gt;</p>\n"},
+		{"HTML block", "<div>\n<iframe src=\"//evil\"></iframe>\n</div>",
+			"<p>&lt;div&gt;\n&lt;iframe src=&#34;//evil&#34;&gt;&lt;/iframe&gt;\n&lt;/div&gt;</p>\n"},
+		{"script in code span", "`<script>`", "<p><code>&lt;script&gt;</code></p>\n"},
+		{"fence info breakout", "```\"><script>\n<b>\n```", "<pre><code>&lt;b&gt;\n</code></pre>\n"},
+
+		// Attribute breakouts.
+		{"quote in title", `[x](http://example.com '"><script>alert(1)</script>')`,
+			`<p><a href="http://example.com" title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"quote in image alt", `![" onerror="alert(1)](http://example.com/a.png)`,
+			`<p><img src="http://example.com/a.png" alt="&#34; onerror=&#34;alert(1)"></p>` + "\n"},
+		{"tag in image alt", "![<script>](http://example.com/a.png)",
+			`<p><img src="http://example.com/a.png" alt="&lt;script&gt;"></p>` + "\n"},
+		{"quote in image title", `![a](http://example.com/a.png '" onload="alert(1)')`,
+			`<p><img src="http://example.com/a.png" alt="a" title="&#34; onload=&#34;alert(1)"></p>` + "\n"},
+		{"quote in destination", `[x](http://example.com/"onmouseover="alert(1))`,
+			`<p><a href="http://example.com/&#34;onmouseover=&#34;alert(1)" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"quote in bare URL", `http://example.com/"onmouseover=alert(1)`,
+			`<p><a href="http://example.com/&#34;onmouseover=alert(1)" rel="nofollow noopener noreferrer">http://example.com/&#34;onmouseover=alert(1)</a></p>` + "\n"},
+
+		// Nesting.
+		{"link in emphasis", "***[x](javascript:alert(1))***", "<p><em><strong>x</strong></em></p>\n"},
+		{"emphasis in link", "**[*x*](http://example.com)**",
+			`<p><strong><a href="http://example.com" rel="nofollow noopener noreferrer"><em>x</em></a></strong></p>` + "\n"},
+		{"link in link", "[[x](javascript:alert(1))](http://example.com)",
+			`<p><a href="http://example.com" rel="nofollow noopener noreferrer">[x](javascript:alert(1))</a></p>` + "\n"},
+		{"image in link", "[![x](javascript:alert(1))](javascript:alert(2))", "<p>x</p>\n"},
+		{"safe image in unsafe link", "[![x](http://example.com/a.png)](javascript:alert(1))",
+			`<p><img src="http://example.com/a.png" alt="x"></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToHTML(tt.src)
+			if got != tt.want {
+				t.Errorf("ToHTML(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+			assertSafe(t, got)
+		})
+	}
+}
+
+func TestToHTMLDeepNesting(t *testing.T) {
+	for _, src := range []string{
+		strings.Repeat(">", 1000) + " <script>",
+		strings.Repeat("- ", 1000) + "<script>",
+		strings.Repeat("*", 1000) + "<script>" + strings.Repeat("*", 1000),
+		strings.Repeat("[", 1000) + "x" + strings.Repeat("](javascript:alert(1))", 1000),
+	} {
+		out := ToHTML(src)
+		if strings.Contains(out, "<script") {
+			t.Errorf("raw tag in output of %.20q…", src)
+		}
+		assertSafe(t, out)
+	}
+}
+
+func TestPlainToHTMLEscapes(t *testing.T) {
+	got := PlainToHTML("<script>alert(1)</script>\n[x](javascript:alert(1))")
+	want := "<p>&lt;script&gt;alert(1)&lt;/script&gt;<br>\n[x](javascript:alert(1))</p>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	assertSafe(t, got)
+}
@@ -0,0 +1,44 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// allowedSchemes are the URL schemes links may use; images are limited to
+// http and https. Relative URLs are always allowed.
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// safeURL checks a link or image destination against the scheme allowlist
+// and returns it escaped for use in an attribute.
+//
+// Anything a browser could read as a scheme is checked: the scheme is
+// whatever precedes the first ":" that comes before any "/", "?" or "#".
+// Control characters and whitespace are rejected outright, since browsers
+// strip them and "java\tscript:" would otherwise slip through.
+func safeURL(raw string, image bool) (string, bool) {
+	u := strings.TrimSpace(raw)
+	if u == "" {
+		return "", false
+	}
+	for i := 0; i < len(u); i++ {
+		if c := u[i]; c <= ' ' || c == 0x7f {
+			return "", false
+		}
+	}
+
+	if colon := strings.IndexByte(u, ':'); colon >= 0 {
+		if cut := strings.IndexAny(u, "/?#"); cut < 0 || colon < cut {
+			scheme := strings.ToLower(u[:colon])
+			if !allowedSchemes[scheme] || (image && scheme == "mailto") {
+				return "", false
+			}
+		}
+	}
+
+	return html.EscapeString(u), true
+}
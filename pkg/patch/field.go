@@ -0,0 +1,50 @@
+// Package patch provides the building blocks for JSON Merge Patch (RFC 7386)
+// request bodies, where a member can be absent (leave as is), null (remove /
+// reset) or set to a new value.
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Field is a merge-patch member. The zero value means "absent".
+type Field[T any] struct {
+	Set   bool // the member was present in the document
+	Null  bool // the member was present and null
+	Value T    // the new value when Set && !Null
+}
+
+// UnmarshalJSON is only called for members present in the document, which is
+// what tells absent and null apart.
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if string(data) == "null" {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+// HasValue reports whether the member carries a new, non-null value.
+func (f Field[T]) HasValue() bool {
+	return f.Set && !f.Null
+}
+
+func (f Field[T]) validationValue() any {
+	if f.HasValue() {
+		return f.Value
+	}
+	return (*T)(nil) // typed, so omitnil recognizes it
+}
+
+// ValidationValue is a validator.CustomTypeFunc: it lets the existing struct
+// tags validate the value of a Field. Absent and null members yield nil, so
+// rules guarded by omitnil are skipped for them while an empty value is still
+// checked.
+func ValidationValue(v reflect.Value) any {
+	if f, ok := v.Interface().(interface{ validationValue() any }); ok {
+		return f.validationValue()
+	}
+	return nil
+}
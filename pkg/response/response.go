@@ -34,6 +34,20 @@ type PaginationMeta struct {
 	Total   int64 `json:"total"`
 }
 
+// CursorMeta is the metadata for keyset-paginated lists. NextCursor is empty
+// on the last page.
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// InboxMeta is the metadata for notification lists: pagination plus the
+// number of unread notifications overall.
+type InboxMeta struct {
+	PaginationMeta
+	Unread int64 `json:"unread"`
+}
+
 // ─── Success responses ────────────────────────────────────────────────────────
 
 // OK sends HTTP 200 with data.
@@ -51,6 +65,16 @@ func OKWithMeta(c *gin.Context, data any, meta PaginationMeta) {
 	c.JSON(http.StatusOK, successEnvelope{Success: true, Data: data, Meta: meta})
 }
 
+// OKWithCursor sends HTTP 200 with data and keyset pagination meta.
+func OKWithCursor(c *gin.Context, data any, meta CursorMeta) {
+	c.JSON(http.StatusOK, successEnvelope{Success: true, Data: data, Meta: meta})
+}
+
+// OKWithInbox sends HTTP 200 with data and notification list meta.
+func OKWithInbox(c *gin.Context, data any, meta InboxMeta) {
+	c.JSON(http.StatusOK, successEnvelope{Success: true, Data: data, Meta: meta})
+}
+
 // NoContent sends HTTP 204 (no body).
 func NoContent(c *gin.Context) {
 	c.Status(http.StatusNoContent)
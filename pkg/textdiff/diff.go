@@ -0,0 +1,119 @@
+// Package textdiff computes line-level diffs between two texts.
+package textdiff
+
+import "strings"
+
+// Op is the kind of change a Line represents.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line is one line of a diff. Delete lines come from the old text, Insert
+// lines from the new one, Equal lines from both.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the LCS table (old lines × new lines). Beyond it the diff
+// degrades to "delete everything, insert everything", which is still correct,
+// just not minimal.
+const maxCells = 4_000_000
+
+// Lines returns the line diff that turns oldText into newText, based on the
+// longest common subsequence of lines.
+func Lines(oldText, newText string) []Line {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// Trim the common prefix and suffix — edits are usually local, so this
+	// keeps the quadratic part small.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	out := make([]Line, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		out = append(out, Line{Op: Equal, Text: l})
+	}
+	out = append(out, lcsDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		out = append(out, Line{Op: Equal, Text: l})
+	}
+	return out
+}
+
+func lcsDiff(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	if n*m > maxCells {
+		out := make([]Line, 0, n+m)
+		for _, l := range a {
+			out = append(out, Line{Op: Delete, Text: l})
+		}
+		for _, l := range b {
+			out = append(out, Line{Op: Insert, Text: l})
+		}
+		return out
+	}
+
+	// lcs[i][j] = length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			out = append(out, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		out = append(out, Line{Op: Insert, Text: b[j]})
+	}
+	return out
+}
+
+// splitLines splits on \n (tolerating \r\n). An empty text has no lines.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(s, "\n")
+}
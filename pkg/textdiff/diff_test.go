@@ -0,0 +1,81 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func eq(s string) Line  { return Line{Op: Equal, Text: s} }
+func ins(s string) Line { return Line{Op: Insert, Text: s} }
+func del(s string) Line { return Line{Op: Delete, Text: s} }
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []Line
+	}{
+		{"both empty", "", "", []Line{}},
+		{"empty old", "", "a\nb", []Line{ins("a"), ins("b")}},
+		{"empty new", "a\nb", "", []Line{del("a"), del("b")}},
+		{"identical", "a\nb\nc", "a\nb\nc", []Line{eq("a"), eq("b"), eq("c")}},
+		{"insert", "a\nc", "a\nb\nc", []Line{eq("a"), ins("b"), eq("c")}},
+		{"delete", "a\nb\nc", "a\nc", []Line{eq("a"), del("b"), eq("c")}},
+		{"replace in the middle", "a\nb\nc\nd", "a\nx\ny\nd",
+			[]Line{eq("a"), del("b"), del("c"), ins("x"), ins("y"), eq("d")}},
+		{"replace keeping a common line", "a\nb\nc\nd\ne", "a\nx\nc\ny\ne",
+			[]Line{eq("a"), del("b"), ins("x"), eq("c"), del("d"), ins("y"), eq("e")}},
+		// The text after the last newline is a line of its own, empty when
+		// the text ends with a newline.
+		{"trailing newline removed", "a\nb\n", "a\nb", []Line{eq("a"), eq("b"), del("")}},
+		{"trailing newline added", "a\nb", "a\nb\n", []Line{eq("a"), eq("b"), ins("")}},
+		{"last line changed without newline", "a\nb", "a\nc", []Line{eq("a"), del("b"), ins("c")}},
+		{"CRLF", "a\r\nb\r\n", "a\nb\n", []Line{eq("a"), eq("b"), eq("")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.old, tt.new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q)\n got %v\nwant %v", tt.old, tt.new, got, tt.want)
+			}
+			checkApplies(t, tt.old, tt.new, got)
+		})
+	}
+}
+
+// Past maxCells the diff is not minimal, but still turns old into new.
+func TestLinesLargeFallback(t *testing.T) {
+	var a, b []string
+	for i := range 2_100 {
+		a = append(a, "old "+strings.Repeat("x", i%7))
+		b = append(b, "new "+strings.Repeat("y", i%5))
+	}
+	oldText, newText := "head\n"+strings.Join(a, "\n"), "head\n"+strings.Join(b, "\n")
+	got := Lines(oldText, newText)
+	if got[0] != eq("head") {
+		t.Errorf("common prefix not kept: %v", got[0])
+	}
+	checkApplies(t, oldText, newText, got)
+}
+
+// checkApplies verifies that the Equal and Delete lines of diff spell out
+// oldText, and the Equal and Insert lines newText.
+func checkApplies(t *testing.T, oldText, newText string, diff []Line) {
+	t.Helper()
+	var a, b []string
+	for _, l := range diff {
+		if l.Op != Insert {
+			a = append(a, l.Text)
+		}
+		if l.Op != Delete {
+			b = append(b, l.Text)
+		}
+	}
+	if got, want := strings.Join(a, "\n"), strings.ReplaceAll(oldText, "\r\n", "\n"); got != want {
+		t.Errorf("old side = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(b, "\n"), strings.ReplaceAll(newText, "\r\n", "\n"); got != want {
+		t.Errorf("new side = %q, want %q", got, want)
+	}
+}